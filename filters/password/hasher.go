@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	argon2idID = "argon2id"
+	scryptID   = "scrypt"
+	bcryptID   = "2a"
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// 验证时对哈希值中各参数的限制，防止通过构造的哈希值耗尽资源。
+const (
+	minSaltSize = 8
+	minKeySize  = 16
+	maxKeySize  = 64
+
+	maxMemory  = 1 << 30 // 最大的内存用量，单位为字节。
+	maxTime    = 1 << 10 // argon2id 最大的迭代次数
+	maxScryptP = 1 << 4  // scrypt 最大的并行度
+)
+
+type argon2idHasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+type scryptHasher struct {
+	ln   uint8 // N 的对数值，N=1<<ln
+	r, p int
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+// NewArgon2id 声明 argon2id 算法的 [Hasher]
+//
+// time 迭代次数；
+// memory 内存用量，单位为 KiB；
+// threads 线程数量；
+//
+// 以上参数的推荐值可参考 [RFC9106]。
+//
+// [RFC9106]: https://www.rfc-editor.org/rfc/rfc9106.html#name-parameter-choice
+func NewArgon2id(time, memory uint32, threads uint8) Hasher {
+	if time == 0 || memory == 0 || threads == 0 {
+		panic("参数 time、memory 和 threads 都必须大于 0")
+	}
+	return &argon2idHasher{time: time, memory: memory, threads: threads}
+}
+
+func (h *argon2idHasher) ID() string { return argon2idID }
+
+func (h *argon2idHasher) Hash(password []byte) (string, error) {
+	salt, err := newSalt(saltSize)
+	if err != nil {
+		return "", err
+	}
+
+	p := &phc{
+		id:      argon2idID,
+		version: strconv.Itoa(argon2.Version),
+		params: map[string]string{
+			"m": strconv.FormatUint(uint64(h.memory), 10),
+			"t": strconv.FormatUint(uint64(h.time), 10),
+			"p": strconv.FormatUint(uint64(h.threads), 10),
+		},
+		salt: salt,
+		hash: argon2.IDKey(password, salt, h.time, h.memory, h.threads, keySize),
+	}
+	return p.String(), nil
+}
+
+func (h *argon2idHasher) Verify(password []byte, hash string) (ok, rehash bool, err error) {
+	p, err := parsePHC(hash)
+	if err != nil {
+		return false, false, err
+	}
+	if p.id != argon2idID || p.version != strconv.Itoa(argon2.Version) {
+		return false, false, ErrInvalidHash()
+	}
+
+	if err := p.checkSize(); err != nil {
+		return false, false, err
+	}
+
+	threads, err := p.uintParam("p", 1, 1<<8-1)
+	if err != nil {
+		return false, false, err
+	}
+	m, err := p.uintParam("m", 8*threads, maxMemory>>10)
+	if err != nil {
+		return false, false, err
+	}
+	t, err := p.uintParam("t", 1, maxTime)
+	if err != nil {
+		return false, false, err
+	}
+
+	key := argon2.IDKey(password, p.salt, uint32(t), uint32(m), uint8(threads), uint32(len(p.hash)))
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return false, false, nil
+	}
+
+	rehash = uint32(m) != h.memory || uint32(t) != h.time || uint8(threads) != h.threads || len(p.hash) != keySize
+	return true, rehash, nil
+}
+
+// NewScrypt 声明 scrypt 算法的 [Hasher]
+//
+// ln 为 CPU/内存开销参数 N 的以 2 为底的对数，即 N=1<<ln；
+// r 和 p 为 scrypt 的块大小和并行度参数；
+func NewScrypt(ln uint8, r, p int) Hasher {
+	if ln == 0 || ln >= 64 || r <= 0 || p <= 0 {
+		panic("无效的参数")
+	}
+	return &scryptHasher{ln: ln, r: r, p: p}
+}
+
+func (h *scryptHasher) ID() string { return scryptID }
+
+func (h *scryptHasher) Hash(password []byte) (string, error) {
+	salt, err := newSalt(saltSize)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key(password, salt, 1<<h.ln, h.r, h.p, keySize)
+	if err != nil {
+		return "", err
+	}
+
+	p := &phc{
+		id: scryptID,
+		params: map[string]string{
+			"ln": strconv.Itoa(int(h.ln)),
+			"r":  strconv.Itoa(h.r),
+			"p":  strconv.Itoa(h.p),
+		},
+		salt: salt,
+		hash: key,
+	}
+	return p.String(), nil
+}
+
+func (h *scryptHasher) Verify(password []byte, hash string) (ok, rehash bool, err error) {
+	p, err := parsePHC(hash)
+	if err != nil {
+		return false, false, err
+	}
+	if p.id != scryptID {
+		return false, false, ErrInvalidHash()
+	}
+
+	if err := p.checkSize(); err != nil {
+		return false, false, err
+	}
+
+	ln, err := p.uintParam("ln", 1, 63)
+	if err != nil {
+		return false, false, err
+	}
+	r, err := p.uintParam("r", 1, maxMemory>>7)
+	if err != nil {
+		return false, false, err
+	}
+	pp, err := p.uintParam("p", 1, maxScryptP)
+	if err != nil {
+		return false, false, err
+	}
+	if 128*r > maxMemory>>ln { // scrypt 的内存用量为 128*r*N
+		return false, false, ErrInvalidHash()
+	}
+
+	key, err := scrypt.Key(password, p.salt, 1<<ln, int(r), int(pp), len(p.hash))
+	if err != nil {
+		return false, false, ErrInvalidHash()
+	}
+	if subtle.ConstantTimeCompare(key, p.hash) != 1 {
+		return false, false, nil
+	}
+
+	rehash = uint8(ln) != h.ln || int(r) != h.r || int(pp) != h.p || len(p.hash) != keySize
+	return true, rehash, nil
+}
+
+// NewBcrypt 声明 bcrypt 算法的 [Hasher]
+//
+// cost 为计算的开销，取值范围为 [bcrypt.MinCost, bcrypt.MaxCost]。
+//
+// NOTE: bcrypt 最多只处理密码的前 72 个字节，超过此长度的密码在 [Hasher.Hash] 中会返回错误。
+func NewBcrypt(cost int) Hasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		panic("无效的参数 cost")
+	}
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) ID() string { return bcryptID }
+
+func (h *bcryptHasher) Hash(password []byte) (string, error) {
+	bs, err := bcrypt.GenerateFromPassword(password, h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+func (h *bcryptHasher) Verify(password []byte, hash string) (ok, rehash bool, err error) {
+	switch err := bcrypt.CompareHashAndPassword([]byte(hash), password); {
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, false, nil
+	case err != nil:
+		return false, false, ErrInvalidHash()
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrInvalidHash()
+	}
+	return true, cost != h.cost, nil
+}
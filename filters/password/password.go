@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package password 密码的哈希和验证
+//
+// 哈希值采用 [PHC] 格式保存，bcrypt 则沿用其自身的 MCF 格式：
+//
+//	p := password.New(password.NewArgon2id(3, 64*1024, 4), password.NewBcrypt(10))
+//	hash, err := p.Hash("pass")
+//
+//	// 登录时验证，如果参数或算法已经改变，newHash 为新的哈希值，需要更新到数据库。
+//	ok, newHash, err := p.Verify("pass", hash)
+//
+// [PHC]: https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/web"
+)
+
+var (
+	errInvalidHash     = web.NewLocaleError("invalid password hash")
+	errHasherNotExists = web.NewLocaleError("not found password hasher")
+)
+
+var b64 = base64.RawStdEncoding
+
+// Hasher 密码的哈希算法
+type Hasher interface {
+	// ID 算法的唯一标记
+	//
+	// 对应哈希值中的 $id$ 部分，比如 argon2id。
+	ID() string
+
+	// Hash 生成密码 password 的哈希值
+	Hash(password []byte) (string, error)
+
+	// Verify 验证密码 password 与哈希值 hash 是否匹配
+	//
+	// hash 为已经确定由当前算法生成的哈希值；
+	// rehash 表示 hash 的参数与当前对象的参数不同，应该重新生成哈希值；
+	Verify(password []byte, hash string) (ok, rehash bool, err error)
+}
+
+// Password 密码管理
+type Password struct {
+	current Hasher
+	hashers map[string]Hasher
+}
+
+// ErrInvalidHash 无效的哈希值
+func ErrInvalidHash() error { return errInvalidHash }
+
+// ErrHasherNotExists 哈希值对应的算法不存在
+func ErrHasherNotExists() error { return errHasherNotExists }
+
+// New 声明 [Password]
+//
+// current 为生成新哈希值时使用的算法；
+// legacy 为仅用于验证旧哈希值的算法，由这些算法生成的哈希值在验证成功之后会被要求重新生成；
+func New(current Hasher, legacy ...Hasher) *Password {
+	if current == nil {
+		panic("参数 current 不能为空")
+	}
+
+	hashers := make(map[string]Hasher, len(legacy)+1)
+	for _, h := range legacy {
+		hashers[h.ID()] = h
+	}
+	hashers[current.ID()] = current
+
+	return &Password{
+		current: current,
+		hashers: hashers,
+	}
+}
+
+// Hash 生成密码的哈希值
+func (p *Password) Hash(password string) (string, error) { return p.current.Hash([]byte(password)) }
+
+// Verify 验证密码
+//
+// 如果验证成功且 hash 的算法或是参数与当前的设置不同，会同时返回新的哈希值 newHash，
+// 调用方应该用 newHash 替换原来保存的 hash，其它情况下 newHash 始终为空。
+func (p *Password) Verify(password, hash string) (ok bool, newHash string, err error) {
+	id, err := hashID(hash)
+	if err != nil {
+		return false, "", err
+	}
+
+	h, found := p.hashers[id]
+	if !found {
+		return false, "", ErrHasherNotExists()
+	}
+
+	ok, rehash, err := h.Verify([]byte(password), hash)
+	if err != nil || !ok {
+		return false, "", err
+	}
+
+	if rehash || h != p.current {
+		if newHash, err = p.Hash(password); err != nil {
+			return false, "", err
+		}
+	}
+	return true, newHash, nil
+}
+
+// 获取哈希值中的算法标记
+func hashID(hash string) (string, error) {
+	if len(hash) < 2 || hash[0] != '$' {
+		return "", ErrInvalidHash()
+	}
+
+	id, _, found := strings.Cut(hash[1:], "$")
+	switch {
+	case !found || id == "":
+		return "", ErrInvalidHash()
+	case id == "2b" || id == "2y": // bcrypt 的各个版本之间是兼容的
+		return bcryptID, nil
+	default:
+		return id, nil
+	}
+}
+
+func newSalt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// phc PHC 格式的各个组成部分
+type phc struct {
+	id      string
+	version string            // 可以为空
+	params  map[string]string // 可以为空
+	salt    []byte
+	hash    []byte
+}
+
+func (p *phc) String() string {
+	var b strings.Builder
+	b.WriteByte('$')
+	b.WriteString(p.id)
+
+	if p.version != "" {
+		b.WriteString("$v=")
+		b.WriteString(p.version)
+	}
+
+	if len(p.params) > 0 {
+		b.WriteByte('$')
+		b.WriteString(p.paramsString())
+	}
+
+	b.WriteByte('$')
+	b.WriteString(b64.EncodeToString(p.salt))
+	b.WriteByte('$')
+	b.WriteString(b64.EncodeToString(p.hash))
+	return b.String()
+}
+
+// 参数以固定的顺序输出，保证相同的参数生成的内容是相同的。
+func (p *phc) paramsString() string {
+	keys := phcParamsOrder[p.id]
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if v, found := p.params[k]; found {
+			items = append(items, k+"="+v)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+var phcParamsOrder = map[string][]string{
+	argon2idID: {"m", "t", "p"},
+	scryptID:   {"ln", "r", "p"},
+}
+
+func parsePHC(hash string) (*phc, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) < 4 || fields[0] != "" { // 至少包含 ["", id, salt, hash]
+		return nil, ErrInvalidHash()
+	}
+	fields = fields[1:]
+
+	p := &phc{id: fields[0]}
+	fields = fields[1:]
+
+	if strings.HasPrefix(fields[0], "v=") {
+		p.version = fields[0][2:]
+		fields = fields[1:]
+	}
+
+	switch len(fields) {
+	case 3:
+		p.params = make(map[string]string, 3)
+		for item := range strings.SplitSeq(fields[0], ",") {
+			k, v, found := strings.Cut(item, "=")
+			if !found {
+				return nil, ErrInvalidHash()
+			}
+			p.params[k] = v
+		}
+		fields = fields[1:]
+	case 2:
+	default:
+		return nil, ErrInvalidHash()
+	}
+
+	var err error
+	if p.salt, err = b64.DecodeString(fields[0]); err != nil {
+		return nil, ErrInvalidHash()
+	}
+	if p.hash, err = b64.DecodeString(fields[1]); err != nil {
+		return nil, ErrInvalidHash()
+	}
+	return p, nil
+}
+
+// 获取参数 name 的值，其取值范围为 [min, max]。
+func (p *phc) uintParam(name string, min, max uint64) (uint64, error) {
+	v, found := p.params[name]
+	if !found {
+		return 0, ErrInvalidHash()
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || n < min || n > max {
+		return 0, ErrInvalidHash()
+	}
+	return n, nil
+}
+
+// 检测盐值和哈希值的长度
+func (p *phc) checkSize() error {
+	if len(p.salt) < minSaltSize || len(p.hash) < minKeySize || len(p.hash) > maxKeySize {
+		return ErrInvalidHash()
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+var (
+	_ Hasher = &argon2idHasher{}
+	_ Hasher = &scryptHasher{}
+	_ Hasher = &bcryptHasher{}
+)
+
+func TestHasher(t *testing.T) {
+	a := assert.New(t, false)
+
+	hashers := []Hasher{
+		NewArgon2id(1, 1024, 1),
+		NewScrypt(10, 8, 1),
+		NewBcrypt(4),
+	}
+
+	for _, h := range hashers {
+		hash, err := h.Hash([]byte("pass"))
+		a.NotError(err).True(strings.HasPrefix(hash, "$"+h.ID()+"$"), hash)
+
+		ok, rehash, err := h.Verify([]byte("pass"), hash)
+		a.NotError(err).True(ok).False(rehash)
+
+		ok, rehash, err = h.Verify([]byte("pass1"), hash)
+		a.NotError(err).False(ok).False(rehash)
+
+		hash2, err := h.Hash([]byte("pass"))
+		a.NotError(err).NotEqual(hash2, hash) // 不同的盐值
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	a := assert.New(t, false)
+
+	h := NewArgon2id(1, 1024, 1)
+	hash, err := h.Hash([]byte("pass"))
+	a.NotError(err).True(strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	ok, rehash, err := NewArgon2id(2, 1024, 1).Verify([]byte("pass"), hash)
+	a.NotError(err).True(ok).True(rehash)
+
+	ok, rehash, err = h.Verify([]byte("pass"), "$argon2id$v=19$m=1024,t=1$c2FsdA$aGFzaA")
+	a.Equal(err, ErrInvalidHash()).False(ok).False(rehash)
+
+	// 无效的长度和参数
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$" + b64.EncodeToString(make([]byte, keySize)),
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$argon2id$v=19$m=1024,t=4294967295,p=1$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+	} {
+		ok, rehash, err = h.Verify([]byte("pass"), hash)
+		a.Equal(err, ErrInvalidHash(), hash).False(ok).False(rehash)
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	a := assert.New(t, false)
+
+	h := NewScrypt(10, 8, 1)
+	hash, err := h.Hash([]byte("pass"))
+	a.NotError(err).True(strings.HasPrefix(hash, "$scrypt$ln=10,r=8,p=1$"), hash)
+
+	ok, rehash, err := NewScrypt(11, 8, 1).Verify([]byte("pass"), hash)
+	a.NotError(err).True(ok).True(rehash)
+
+	// 无效的长度和参数
+	for _, hash := range []string{
+		"$scrypt$ln=10,r=8,p=1$c2FsdHNhbHQ$",
+		"$scrypt$ln=10,r=8,p=1$c2FsdA$" + b64.EncodeToString(make([]byte, keySize)),
+		"$scrypt$ln=10,r=0,p=1$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$scrypt$ln=10,r=8,p=0$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$scrypt$ln=40,r=8,p=1$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+		"$scrypt$ln=10,r=8,p=1000$c2FsdHNhbHQ$" + b64.EncodeToString(make([]byte, keySize)),
+	} {
+		ok, rehash, err = h.Verify([]byte("pass"), hash)
+		a.Equal(err, ErrInvalidHash(), hash).False(ok).False(rehash)
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	a := assert.New(t, false)
+
+	hash, err := NewBcrypt(4).Hash([]byte("pass"))
+	a.NotError(err)
+
+	ok, rehash, err := NewBcrypt(5).Verify([]byte("pass"), hash)
+	a.NotError(err).True(ok).True(rehash)
+
+	ok, rehash, err = NewBcrypt(5).Verify([]byte("pass"), "$2a$invalid")
+	a.Equal(err, ErrInvalidHash()).False(ok).False(rehash)
+}
+
+func TestPassword(t *testing.T) {
+	a := assert.New(t, false)
+
+	bc := NewBcrypt(4)
+	old, err := bc.Hash([]byte("pass"))
+	a.NotError(err)
+
+	p := New(NewArgon2id(1, 1024, 1), bc)
+	a.NotNil(p)
+
+	// 旧算法的哈希值，验证成功之后需要重新生成。
+	ok, newHash, err := p.Verify("pass", old)
+	a.NotError(err).True(ok).True(strings.HasPrefix(newHash, "$argon2id$"))
+
+	ok, newHash2, err := p.Verify("pass", newHash)
+	a.NotError(err).True(ok).Empty(newHash2)
+
+	ok, newHash2, err = p.Verify("pass1", newHash)
+	a.NotError(err).False(ok).Empty(newHash2)
+
+	// 2b 与 2a 兼容
+	ok, newHash, err = p.Verify("pass", "$2b"+old[3:])
+	a.NotError(err).True(ok).NotEmpty(newHash)
+
+	// 参数改变
+	p = New(NewArgon2id(2, 1024, 1))
+	ok, newHash2, err = p.Verify("pass", newHash)
+	a.NotError(err).True(ok).NotEmpty(newHash2).NotEqual(newHash2, newHash)
+
+	// 不支持的算法
+	ok, newHash, err = p.Verify("pass", old)
+	a.Equal(err, ErrHasherNotExists()).False(ok).Empty(newHash)
+
+	ok, newHash, err = p.Verify("pass", "invalid")
+	a.Equal(err, ErrInvalidHash()).False(ok).Empty(newHash)
+}
+
+func TestParsePHC(t *testing.T) {
+	a := assert.New(t, false)
+
+	p, err := parsePHC("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA")
+	a.NotError(err).
+		Equal(p.id, "argon2id").
+		Equal(p.version, "19").
+		Equal(p.params, map[string]string{"m": "1024", "t": "1", "p": "1"}).
+		Equal(p.salt, []byte("salt")).
+		Equal(p.hash, []byte("hash")).
+		Equal(p.String(), "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA")
+
+	p, err = parsePHC("$scrypt$c2FsdA$aGFzaA")
+	a.NotError(err).
+		Equal(p.id, "scrypt").
+		Empty(p.version).
+		Empty(p.params).
+		Equal(p.salt, []byte("salt"))
+
+	p, err = parsePHC("$scrypt$ln=1,r$c2FsdA$aGFzaA")
+	a.Equal(err, ErrInvalidHash()).Nil(p)
+
+	p, err = parsePHC("$scrypt$aGFzaA")
+	a.Equal(err, ErrInvalidHash()).Nil(p)
+
+	p, err = parsePHC("$scrypt$ln=1$c2FsdA$aGFzaA$aGFzaA")
+	a.Equal(err, ErrInvalidHash()).Nil(p)
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+
+	"github.com/issue9/webuse/v7/filters/strength"
+)
+
+// Policy 密码的策略
+//
+// 可以同时对密码的长度、组成以及是否已经泄漏进行检测。
+type Policy struct {
+	strength       *strength.Strength
+	minLen, maxLen int
+
+	breached    map[[sha1.Size]byte]struct{}
+	breachedMux sync.RWMutex
+}
+
+// NewPolicy 声明 [Policy] 对象
+//
+// s 对密码组成的要求，可以为空；
+// minLen 和 maxLen 为密码的长度限制，以字符为单位，如果为 0 表示不作限制；
+func NewPolicy(s *strength.Strength, minLen, maxLen int) *Policy {
+	if maxLen > 0 && minLen > maxLen {
+		panic("minLen 不能大于 maxLen")
+	}
+
+	return &Policy{
+		strength: s,
+		minLen:   minLen,
+		maxLen:   maxLen,
+		breached: make(map[[sha1.Size]byte]struct{}, 100),
+	}
+}
+
+// LoadBreached 从本地文件加载已泄漏的密码列表
+//
+// 文件中每一行表示一条记录，可以是明文的密码，
+// 也可以是大小写不敏感的 40 位十六进制 SHA-1 值，
+// SHA-1 值之后可以带上以冒号分隔的出现次数，即 [Pwned Passwords] 的下载格式。
+// 以 # 开头的行和空行将被忽略。
+//
+// 可以多次调用，内容会累加。
+//
+// [Pwned Passwords]: https://haveibeenpwned.com/Passwords
+func (p *Policy) LoadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.loadBreached(f)
+}
+
+// LoadBreachedFS 从 fsys 加载已泄漏的密码列表
+//
+// 文件格式可参考 [Policy.LoadBreached]。
+func (p *Policy) LoadBreachedFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.loadBreached(f)
+}
+
+func (p *Policy) loadBreached(r io.Reader) error {
+	items := make([][sha1.Size]byte, 0, 100)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if h, _, _ := strings.Cut(line, ":"); len(h) == sha1.Size*2 {
+			var sum [sha1.Size]byte
+			if _, err := hex.Decode(sum[:], []byte(h)); err == nil {
+				items = append(items, sum)
+				continue
+			}
+		}
+		items = append(items, sha1.Sum([]byte(line)))
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	p.breachedMux.Lock()
+	for _, item := range items {
+		p.breached[item] = struct{}{}
+	}
+	p.breachedMux.Unlock()
+	return nil
+}
+
+// IsBreached 密码是否存在于已泄漏的列表中
+func (p *Policy) IsBreached(pass string) bool {
+	p.breachedMux.RLock()
+	_, found := p.breached[sha1.Sum([]byte(pass))]
+	p.breachedMux.RUnlock()
+	return found
+}
+
+// Check 检测密码是否符合当前的策略
+//
+// 如果不符合，返回本地化的原因，否则返回 nil。
+func (p *Policy) Check(pass string) web.LocaleStringer {
+	l := utf8.RuneCountInString(pass)
+	switch {
+	case p.minLen > 0 && l < p.minLen:
+		return web.Phrase("the password must be at least %d characters", p.minLen)
+	case p.maxLen > 0 && l > p.maxLen:
+		return web.Phrase("the password must be at most %d characters", p.maxLen)
+	case p.strength != nil && !p.strength.Valid(pass):
+		return web.Phrase("the password is too weak")
+	case p.IsBreached(pass):
+		return web.Phrase("the password has appeared in a data breach")
+	default:
+		return nil
+	}
+}
+
+// Valid 密码是否符合当前的策略
+func (p *Policy) Valid(pass string) bool { return p.Check(pass) == nil }
+
+// Rule 将当前策略转换为 [filter.Rule]
+//
+// 验证失败时返回的错误信息与 [Policy.Check] 相同。
+func (p *Policy) Rule() filter.Rule[string] {
+	return func(name string, v *string) (string, web.LocaleStringer) {
+		if msg := p.Check(*v); msg != nil {
+			return name, msg
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package password
+
+import (
+	"os"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+
+	"github.com/issue9/webuse/v7/filters/strength"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	a := assert.New(t, false)
+
+	p := NewPolicy(&strength.Strength{Upper: 1, Number: 1}, 6, 10)
+	a.NotNil(p)
+
+	a.Equal(p.Check("Ab1"), web.Phrase("the password must be at least %d characters", 6)).
+		Equal(p.Check("Abc12345678"), web.Phrase("the password must be at most %d characters", 10)).
+		Equal(p.Check("abcdefg"), web.Phrase("the password is too weak")).
+		Nil(p.Check("Abc123456")).
+		True(p.Valid("中文Abc1234")) // 以字符计算长度
+
+	a.NotError(p.LoadBreached("./testdata/breached.txt"))
+	a.Equal(p.Check("Abc@123456"), web.Phrase("the password has appeared in a data breach")).
+		True(p.IsBreached("123456")).
+		True(p.IsBreached("qwerty")).
+		False(p.IsBreached("Abc123456"))
+
+	a.ErrorIs(p.LoadBreached("./testdata/not-exists.txt"), os.ErrNotExist)
+
+	p = NewPolicy(nil, 0, 0)
+	a.NotError(p.LoadBreachedFS(os.DirFS("./testdata"), "breached.txt")).
+		True(p.Valid("1")).
+		False(p.Valid("password"))
+
+	a.PanicString(func() {
+		NewPolicy(nil, 6, 5)
+	}, "minLen 不能大于 maxLen")
+}
+
+func TestPolicy_Rule(t *testing.T) {
+	a := assert.New(t, false)
+
+	p := NewPolicy(nil, 6, 0)
+	v := "12345"
+	name, msg := filter.New("pass", &v, p.Rule())()
+	a.Equal(name, "pass").Equal(msg, web.Phrase("the password must be at least %d characters", 6))
+
+	v = "123456"
+	name, msg = filter.New("pass", &v, p.Rule())()
+	a.Empty(name).Nil(msg)
+}
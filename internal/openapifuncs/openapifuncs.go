@@ -1,14 +1,20 @@
-// SPDX-FileCopyrightText: 2024-2025 caixw
+// SPDX-FileCopyrightText: 2024-2026 caixw
 //
 // SPDX-License-Identifier: MIT
 
 package openapifuncs
 
 import (
+	"bytes"
 	"encoding/json"
 	"html/template"
+	"io/fs"
 
 	"github.com/goccy/go-yaml"
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/html"
+
+	"github.com/issue9/webuse/v7/middlewares/secure"
 )
 
 var Funcs = template.FuncMap{
@@ -21,4 +27,39 @@ var Funcs = template.FuncMap{
 		a, _ := yaml.Marshal(v)
 		return template.JS(a)
 	},
+
+	// 通过 [html.Install] 安装的模板无法访问 [web.Context]，始终返回空值。
+	"nonce": func() string { return "" },
+}
+
+// Marshal 由 fsys 中的模板输出名为 name 的对象
+//
+// 模板中的 nonce 函数返回当前请求的 [secure.Nonce]，
+// 其它对象依然交由 m 处理。
+func Marshal(m web.MarshalFunc, name string, fsys fs.FS) web.MarshalFunc {
+	tpl := template.Must(template.New(name).Funcs(Funcs).ParseFS(fsys, "*.html"))
+
+	return func(ctx *web.Context, v any) ([]byte, error) {
+		hm, ok := v.(html.Marshaler)
+		if !ok || ctx == nil {
+			return m(ctx, v)
+		}
+
+		n, data := hm.MarshalHTML()
+		if n != name {
+			return m(ctx, v)
+		}
+
+		t, err := tpl.Clone() // 每个请求绑定不同的 nonce 函数，不能修改 tpl 本身。
+		if err != nil {
+			return nil, err
+		}
+		t.Funcs(template.FuncMap{"nonce": func() string { return secure.Nonce(ctx) }})
+
+		w := new(bytes.Buffer)
+		if err := t.ExecuteTemplate(w, name, data); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	}
 }
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package openapifuncs
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/secure"
+)
+
+type page string
+
+func (p page) MarshalHTML() (string, any) { return "page", string(p) }
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	fsys := fstest.MapFS{
+		"page.html": &fstest.MapFile{Data: []byte(`{{define "page"}}<p>{{.}}</p><script nonce="{{nonce}}"></script>{{end}}`)},
+	}
+	m := Marshal(func(*web.Context, any) ([]byte, error) { return []byte("other"), nil }, "page", fsys)
+
+	r := s.Routers().New("def", nil)
+	r.Use(secure.New(nil))
+	r.Get("/html", func(ctx *web.Context) web.Responser {
+		data, err := m(ctx, page("abc"))
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		other, err := m(ctx, "abc")
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		return web.OK(map[string]string{
+			"html":  string(data),
+			"want":  `<p>abc</p><script nonce="` + secure.Nonce(ctx) + `"></script>`,
+			"other": string(other),
+		})
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/html").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			data := map[string]string{}
+			a.NotError(json.Unmarshal(body, &data)).
+				Equal(data["html"], data["want"]).
+				NotContains(data["html"], `nonce=""`).
+				Equal(data["other"], "other")
+		})
+}
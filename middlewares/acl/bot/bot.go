@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package bot 机器人和滥用检测
+//
+// 由多个 [Rule] 对请求进行评分，根据总分采取不同的措施：
+//   - 放行；
+//   - 延迟一段时间之后再处理，降低脚本的效率；
+//   - 直接拒绝，返回 429；
+//   - 返回工作量证明的题目，客户端解答之后会获得一个有时效的通行 cookie，
+//     在有效期内不再对其进行检测。题目和通行 cookie 只对获取它们的客户端有效，
+//     且每个题目只能解答一次；
+package bot
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+const (
+	ChallengeHeader = "X-Bot-Challenge" // 工作量证明的题目
+	SolutionHeader  = "X-Bot-Solution"  // 工作量证明的答案
+)
+
+const challengeTTL = 5 * time.Minute // 题目的有效时间
+
+const scoreContext contextType = 1
+
+type contextType int
+
+// Action 对请求采取的措施
+type Action int
+
+const (
+	ActionAllow     Action = iota // 放行
+	ActionTarpit                  // 延迟之后放行
+	ActionReject                  // 返回 429
+	ActionChallenge               // 返回工作量证明的题目
+)
+
+// Bot 机器人检测的中间件
+type Bot struct {
+	secret []byte
+	cookie string
+	rules  []Rule
+	levels []*level
+
+	tarpit     time.Duration
+	c          web.Cache
+	gen        acl.GenFunc
+	difficulty int
+	lifetime   time.Duration // 通行 cookie 的有效时间
+}
+
+type level struct {
+	score  int
+	action Action
+}
+
+// New 声明 [Bot] 中间件
+//
+// secret 为签名题目和通行 cookie 的密钥，长度不能小于 16；
+// cookie 为通行 cookie 的名称；
+// rules 为评分规则，总分为所有规则的分值之和；
+//
+// 默认所有的请求都会放行，需要调用 [Bot.Tarpit]、[Bot.Reject] 和 [Bot.Challenge]
+// 指定在不同分值下采取的措施。
+func New(secret []byte, cookie string, rules ...Rule) *Bot {
+	if len(secret) < 16 {
+		panic("参数 secret 的长度不能小于 16")
+	}
+	if cookie == "" {
+		panic("参数 cookie 不能为空")
+	}
+
+	return &Bot{
+		secret: secret,
+		cookie: cookie,
+		rules:  rules,
+		levels: make([]*level, 0, 3),
+	}
+}
+
+// Tarpit 总分达到 score 时将请求延迟 d 之后再处理
+func (b *Bot) Tarpit(score int, d time.Duration) *Bot {
+	if d <= 0 {
+		panic("参数 d 必须大于 0")
+	}
+	b.tarpit = d
+	return b.setLevel(score, ActionTarpit)
+}
+
+// Reject 总分达到 score 时直接拒绝请求
+func (b *Bot) Reject(score int) *Bot { return b.setLevel(score, ActionReject) }
+
+// Challenge 总分达到 score 时要求客户端完成工作量证明
+//
+// c 用于记录已经解答过的题目，保证每个题目只能使用一次；
+// gen 用于区分客户端，题目和通行 cookie 只对获取它们的客户端有效，默认为不信任任何代理的 [acl.GenIP](nil)；
+// difficulty 为答案的哈希值中需要为 0 的前导位数量，每增加 1，计算量翻倍，取值范围为 [1, 32]；
+// lifetime 为完成之后获得的通行 cookie 的有效时间；
+func (b *Bot) Challenge(c web.Cache, gen acl.GenFunc, score, difficulty int, lifetime time.Duration) *Bot {
+	if c == nil {
+		panic("参数 c 不能为空")
+	}
+	if gen == nil {
+		gen = acl.GenIP(nil)
+	}
+	if difficulty <= 0 || difficulty > 32 {
+		panic("参数 difficulty 的取值范围为 [1, 32]")
+	}
+	if lifetime <= 0 {
+		panic("参数 lifetime 必须大于 0")
+	}
+
+	b.c = c
+	b.gen = gen
+	b.difficulty = difficulty
+	b.lifetime = lifetime
+	return b.setLevel(score, ActionChallenge)
+}
+
+func (b *Bot) setLevel(score int, a Action) *Bot {
+	b.levels = slices.DeleteFunc(b.levels, func(l *level) bool { return l.action == a })
+	b.levels = append(b.levels, &level{score: score, action: a})
+	slices.SortFunc(b.levels, func(a, b *level) int { return b.score - a.score })
+	return b
+}
+
+// 总分对应的措施
+func (b *Bot) action(score int) Action {
+	for _, l := range b.levels {
+		if score >= l.score {
+			return l.action
+		}
+	}
+	return ActionAllow
+}
+
+// 计算请求的总分
+func (b *Bot) score(ctx *web.Context) (int, error) {
+	var sum int
+	for _, r := range b.rules {
+		s, err := r.Score(ctx)
+		if err != nil {
+			return 0, err
+		}
+		sum += s
+	}
+	return sum, nil
+}
+
+func (b *Bot) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		now := ctx.Begin()
+
+		var client string
+		if b.difficulty > 0 {
+			var err error
+			if client, err = b.gen(ctx); err != nil {
+				return ctx.Error(err, web.ProblemInternalServerError)
+			}
+
+			if c, err := ctx.Request().Cookie(b.cookie); err == nil {
+				if _, ok := b.verify(purposePass, client, c.Value, now); ok {
+					return next(ctx)
+				}
+			}
+
+			h := ctx.Request().Header
+			if h.Get(ChallengeHeader) != "" {
+				ok, err := b.verifyChallenge(h.Get(ChallengeHeader), h.Get(SolutionHeader), client, now)
+				if err != nil {
+					return ctx.Error(err, web.ProblemInternalServerError)
+				}
+				if ok {
+					b.setPass(ctx, client, now)
+					return next(ctx)
+				}
+			}
+		}
+
+		score, err := b.score(ctx)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		ctx.SetVar(scoreContext, score)
+		ctx.Logs().AppendAttrs(map[string]any{"bot_score": score})
+
+		switch b.action(score) {
+		case ActionTarpit:
+			t := time.NewTimer(b.tarpit)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+			}
+			return next(ctx)
+		case ActionReject:
+			return ctx.Problem(web.ProblemTooManyRequests)
+		case ActionChallenge:
+			return ctx.Problem(web.ProblemForbidden).WithExtensions(b.newChallenge(client, now))
+		default:
+			return next(ctx)
+		}
+	}
+}
+
+// 设置通行 cookie
+func (b *Bot) setPass(ctx *web.Context, client string, now time.Time) {
+	expires := now.Add(b.lifetime)
+	ctx.SetCookies(&http.Cookie{
+		Name:     b.cookie,
+		Value:    b.token(purposePass, client, expires, nil),
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(b.lifetime.Seconds()),
+		Secure:   ctx.Request().TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// GetScore 获取当前请求的总分
+//
+// 持有通行 cookie 的请求不会被评分，此时返回 false。
+func GetScore(ctx *web.Context) (int, bool) {
+	if v, found := ctx.GetVar(scoreContext); found {
+		return v.(int), true
+	}
+	return 0, false
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+var _ web.Middleware = &Bot{}
+
+func TestNew(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.PanicString(func() { New([]byte("123"), "pass") }, "参数 secret 的长度不能小于 16")
+	a.PanicString(func() { New([]byte("0123456789abcdef"), "") }, "参数 cookie 不能为空")
+
+	c := testserver.New(a).Cache()
+	b := New([]byte("0123456789abcdef"), "pass")
+	a.Equal(b.action(100), ActionAllow)
+
+	b.Tarpit(10, time.Second).Reject(30).Challenge(c, nil, 20, 4, time.Hour)
+	a.NotNil(b.gen)
+	a.Equal(b.action(0), ActionAllow).
+		Equal(b.action(10), ActionTarpit).
+		Equal(b.action(19), ActionTarpit).
+		Equal(b.action(20), ActionChallenge).
+		Equal(b.action(30), ActionReject)
+
+	// 覆盖，采用总分达到的最高一级。
+	b.Reject(15)
+	a.Equal(b.action(15), ActionReject).
+		Equal(b.action(30), ActionChallenge).
+		Length(b.levels, 3)
+
+	a.PanicString(func() { b.Challenge(c, nil, 10, 33, time.Hour) }, "参数 difficulty 的取值范围为 [1, 32]")
+	a.PanicString(func() { b.Challenge(nil, nil, 10, 8, time.Hour) }, "参数 c 不能为空")
+	a.PanicString(func() { b.Tarpit(10, 0) }, "参数 d 必须大于 0")
+}
+
+func TestBot_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	b := New([]byte("0123456789abcdef"), "pass", UserAgentRule(10, "tarpit"), UserAgentRule(20, "challenge"), UserAgentRule(30, "reject")).
+		Tarpit(10, 100*time.Millisecond).
+		Challenge(s.Cache(), func(ctx *web.Context) (string, error) { return ctx.Request().Header.Get("X-Client"), nil }, 20, 8, time.Hour).
+		Reject(30)
+
+	r := s.Routers().New("def", nil)
+	r.Use(b)
+	r.Get("/signup", func(ctx *web.Context) web.Responser {
+		score, _ := GetScore(ctx)
+		return web.OK(score)
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	const url = "http://localhost:8080/signup"
+
+	servertest.Get(a, url).Header(header.UserAgent, "Mozilla/5.0").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		StringBody("0")
+
+	start := time.Now()
+	servertest.Get(a, url).Header(header.UserAgent, "tarpit").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		StringBody("10")
+	a.True(time.Since(start) >= 100*time.Millisecond)
+
+	servertest.Get(a, url).Header(header.UserAgent, "reject").Do(nil).
+		Status(http.StatusTooManyRequests)
+
+	// 工作量证明
+	var c *Challenge
+	servertest.Get(a, url).Header(header.UserAgent, "challenge").Header(header.Accept, header.JSON).
+		Header("X-Client", "c1").
+		Do(nil).
+		Status(http.StatusForbidden).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			p := &struct {
+				Extensions *Challenge `json:"extensions"`
+			}{}
+			a.NotError(json.Unmarshal(body, p)).NotNil(p.Extensions)
+			c = p.Extensions
+		})
+	a.NotNil(c).Equal(c.Difficulty, 8)
+	solution := Solve(c.Challenge, c.Difficulty)
+
+	// 错误的答案
+	invalid := "invalid"
+	for verifyWork(c.Challenge, invalid, c.Difficulty) {
+		invalid += "x"
+	}
+	servertest.Get(a, url).Header(header.UserAgent, "challenge").
+		Header("X-Client", "c1").
+		Header(ChallengeHeader, c.Challenge).
+		Header(SolutionHeader, invalid).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 其它客户端
+	servertest.Get(a, url).Header(header.UserAgent, "challenge").
+		Header("X-Client", "c2").
+		Header(ChallengeHeader, c.Challenge).
+		Header(SolutionHeader, solution).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	resp := servertest.Get(a, url).Header(header.UserAgent, "challenge").Header(header.Accept, header.JSON).
+		Header("X-Client", "c1").
+		Header(ChallengeHeader, c.Challenge).
+		Header(SolutionHeader, solution).
+		Do(nil).
+		Status(http.StatusOK).
+		Resp()
+	cookies := resp.Cookies()
+	a.Length(cookies, 1).Equal(cookies[0].Name, "pass").True(cookies[0].HttpOnly)
+
+	// 题目只能使用一次
+	resp = servertest.Get(a, url).Header(header.UserAgent, "challenge").
+		Header("X-Client", "c1").
+		Header(ChallengeHeader, c.Challenge).
+		Header(SolutionHeader, solution).
+		Do(nil).
+		Status(http.StatusForbidden).
+		Resp()
+	a.Empty(resp.Cookies())
+
+	// 持有通行 cookie，不再评分。
+	servertest.Get(a, url).Header(header.UserAgent, "reject").Header(header.Accept, header.JSON).
+		Header("X-Client", "c1").
+		Header(header.Cookie, cookies[0].Name+"="+cookies[0].Value).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("0")
+
+	// 通行 cookie 不能用于其它客户端
+	servertest.Get(a, url).Header(header.UserAgent, "reject").
+		Header("X-Client", "c2").
+		Header(header.Cookie, cookies[0].Name+"="+cookies[0].Value).
+		Do(nil).
+		Status(http.StatusTooManyRequests)
+
+	// 无效的 cookie
+	servertest.Get(a, url).Header(header.UserAgent, "reject").
+		Header(header.Cookie, cookies[0].Name+"=invalid").
+		Do(nil).
+		Status(http.StatusTooManyRequests)
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/cache"
+)
+
+// 签名的用途，防止将一种签名用于另一种用途。
+const (
+	purposeChallenge = "challenge"
+	purposePass      = "pass"
+)
+
+var encoding = base64.RawURLEncoding
+
+// Challenge 工作量证明的题目
+//
+// 客户端需要找到一个字符串 solution，使 sha256(Challenge + ":" + solution)
+// 的前 Difficulty 位均为 0，然后将 Challenge 和 solution 分别放在
+// [ChallengeHeader] 和 [SolutionHeader] 报头中重新发起请求。
+// 可参考 [Solve] 的实现。
+type Challenge struct {
+	Challenge  string `json:"challenge" xml:"challenge" cbor:"challenge" yaml:"challenge" comment:"challenge"`
+	Difficulty int    `json:"difficulty" xml:"difficulty" cbor:"difficulty" yaml:"difficulty" comment:"challenge difficulty"`
+}
+
+// Solve 计算工作量证明的答案
+func Solve(challenge string, difficulty int) string {
+	for i := uint64(0); ; i++ {
+		solution := strconv.FormatUint(i, 36)
+		if verifyWork(challenge, solution, difficulty) {
+			return solution
+		}
+	}
+}
+
+func verifyWork(challenge, solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+
+	var zeros int
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
+
+// 签名 data
+//
+// client 为客户端的标识，签名只对该客户端有效，防止将令牌转交给其它客户端使用。
+func (b *Bot) sign(purpose, client string, data []byte) []byte {
+	h := hmac.New(sha256.New, b.secret)
+	h.Write([]byte(purpose))
+	h.Write([]byte(client))
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// 生成带签名和过期时间的令牌
+//
+// 格式为 base64(expires + payload) + "." + base64(sign)。
+func (b *Bot) token(purpose, client string, expires time.Time, payload []byte) string {
+	data := binary.BigEndian.AppendUint64(nil, uint64(expires.Unix()))
+	data = append(data, payload...)
+	return encoding.EncodeToString(data) + "." + encoding.EncodeToString(b.sign(purpose, client, data))
+}
+
+// 验证由 token 生成的令牌，返回 payload。
+func (b *Bot) verify(purpose, client, token string, now time.Time) ([]byte, bool) {
+	d, s, found := strings.Cut(token, ".")
+	if !found {
+		return nil, false
+	}
+
+	data, err := encoding.DecodeString(d)
+	if err != nil || len(data) < 8 {
+		return nil, false
+	}
+	sign, err := encoding.DecodeString(s)
+	if err != nil || subtle.ConstantTimeCompare(sign, b.sign(purpose, client, data)) != 1 {
+		return nil, false
+	}
+
+	if expires := time.Unix(int64(binary.BigEndian.Uint64(data)), 0); now.After(expires) {
+		return nil, false
+	}
+	return data[8:], true
+}
+
+// 为客户端 client 生成新的题目
+func (b *Bot) newChallenge(client string, now time.Time) *Challenge {
+	payload := make([]byte, 17)
+	rand.Read(payload[:16])
+	payload[16] = byte(b.difficulty)
+
+	return &Challenge{
+		Challenge:  b.token(purposeChallenge, client, now.Add(challengeTTL), payload),
+		Difficulty: b.difficulty,
+	}
+}
+
+// 验证客户端 client 提交的答案
+//
+// 每个题目只能成功使用一次，之后的提交都将失败。
+func (b *Bot) verifyChallenge(challenge, solution, client string, now time.Time) (bool, error) {
+	payload, ok := b.verify(purposeChallenge, client, challenge, now)
+	if !ok || len(payload) != 17 || !verifyWork(challenge, solution, int(payload[16])) {
+		return false, nil
+	}
+
+	// 在题目的有效期内记录已经使用过的题目
+	_, setter, _, err := b.c.Counter("challenge_"+hex.EncodeToString(payload[:16]), challengeTTL)
+	if err != nil {
+		return false, err
+	}
+	n, err := setter(1)
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss()): // 记录恰好过期，题目也已经过期。
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func TestSolve(t *testing.T) {
+	a := assert.New(t, false)
+
+	for _, d := range []int{1, 8, 12} {
+		solution := Solve("challenge", d)
+		a.True(verifyWork("challenge", solution, d), d)
+	}
+}
+
+func TestBot_verify(t *testing.T) {
+	a := assert.New(t, false)
+	b := New([]byte("0123456789abcdef"), "pass")
+	now := time.Now()
+
+	token := b.token(purposePass, "c1", now.Add(time.Minute), []byte("data"))
+	payload, ok := b.verify(purposePass, "c1", token, now)
+	a.True(ok).Equal(payload, []byte("data"))
+
+	// 过期
+	_, ok = b.verify(purposePass, "c1", token, now.Add(2*time.Minute))
+	a.False(ok)
+
+	// 用途不同
+	_, ok = b.verify(purposeChallenge, "c1", token, now)
+	a.False(ok)
+
+	// 客户端不同
+	_, ok = b.verify(purposePass, "c2", token, now)
+	a.False(ok)
+
+	// 密钥不同
+	_, ok = New([]byte("0123456789ABCDEF"), "pass").verify(purposePass, "c1", token, now)
+	a.False(ok)
+
+	for _, v := range []string{"", ".", "abc", token + "x", "x" + token} {
+		_, ok = b.verify(purposePass, "c1", v, now)
+		a.False(ok, v)
+	}
+}
+
+func TestBot_verifyChallenge(t *testing.T) {
+	a := assert.New(t, false)
+	c := testserver.New(a).Cache()
+	b := New([]byte("0123456789abcdef"), "pass").Challenge(c, nil, 10, 8, time.Hour)
+	now := time.Now()
+
+	verify := func(challenge, solution, client string, now time.Time) bool {
+		ok, err := b.verifyChallenge(challenge, solution, client, now)
+		a.NotError(err)
+		return ok
+	}
+
+	ch := b.newChallenge("c1", now)
+	a.Equal(ch.Difficulty, 8)
+	solution := Solve(ch.Challenge, ch.Difficulty)
+	a.False(verify(ch.Challenge, solution, "c1", now.Add(challengeTTL+time.Second))).
+		False(verify(ch.Challenge, solution, "c2", now)). // 其它客户端
+		False(verify(ch.Challenge, "", "c1", now)).
+		False(verify("", solution, "c1", now)).
+		True(verify(ch.Challenge, solution, "c1", now)).
+		False(verify(ch.Challenge, solution, "c1", now)) // 只能使用一次
+
+	// 难度以生成题目时为准
+	ch = b.newChallenge("c1", now)
+	solution = Solve(ch.Challenge, ch.Difficulty)
+	b.Challenge(c, nil, 10, 20, time.Hour)
+	a.True(verify(ch.Challenge, solution, "c1", now))
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+// Rule 对请求进行评分的规则
+//
+// 分值越高，越有可能是机器人。
+type Rule interface {
+	Score(*web.Context) (int, error)
+}
+
+// RuleFunc 将函数转换为 [Rule]
+type RuleFunc func(*web.Context) (int, error)
+
+// FailedAuth 记录登录失败的次数
+type FailedAuth struct {
+	c   web.Cache
+	gen acl.GenFunc
+	ttl time.Duration
+}
+
+// DefaultUserAgents 常见的脚本和爬虫工具的 User-Agent 片段
+var DefaultUserAgents = []string{
+	"curl", "wget", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"scrapy", "httpclient", "libwww-perl", "okhttp", "java/", "headlesschrome", "phantomjs",
+}
+
+func (f RuleFunc) Score(ctx *web.Context) (int, error) { return f(ctx) }
+
+// HeaderRule 检测浏览器通常都会发送的报头
+//
+// User-Agent、Accept、Accept-Language 和 Accept-Encoding 每缺少一个，加 score 分。
+func HeaderRule(score int) Rule {
+	headers := []string{header.UserAgent, header.Accept, header.AcceptLanguage, header.AcceptEncoding}
+
+	return RuleFunc(func(ctx *web.Context) (int, error) {
+		var sum int
+		for _, h := range headers {
+			if ctx.Request().Header.Get(h) == "" {
+				sum += score
+			}
+		}
+		return sum, nil
+	})
+}
+
+// UserAgentRule 检测 User-Agent 中是否包含 agents 中的任意一项
+//
+// agents 不区分大小写，如果为空，则采用 [DefaultUserAgents]。
+// 包含任意一项时返回 score。
+func UserAgentRule(score int, agents ...string) Rule {
+	if len(agents) == 0 {
+		agents = DefaultUserAgents
+	}
+	agents = toLower(agents)
+
+	return RuleFunc(func(ctx *web.Context) (int, error) {
+		ua := strings.ToLower(ctx.Request().UserAgent())
+		for _, a := range agents {
+			if strings.Contains(ua, a) {
+				return score, nil
+			}
+		}
+		return 0, nil
+	})
+}
+
+// CadenceRule 检测请求的频率
+//
+// 同一来源在 window 时间内的请求超过 limit 次时，返回 score。
+// gen 为区分请求来源的方法，默认为不信任任何代理的 [acl.GenIP](nil)。
+func CadenceRule(c web.Cache, gen acl.GenFunc, limit uint64, window time.Duration, score int) Rule {
+	if limit == 0 || window <= 0 {
+		panic("参数 limit 和 window 必须大于 0")
+	}
+	if gen == nil {
+		gen = acl.GenIP(nil)
+	}
+
+	return RuleFunc(func(ctx *web.Context) (int, error) {
+		key, err := gen(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		index := ctx.Begin().UnixNano() / int64(window)
+		_, setter, _, err := c.Counter("cadence_"+key+"_"+strconv.FormatInt(index, 10), window)
+		if err != nil {
+			return 0, err
+		}
+		n, err := setter(1)
+		if err != nil {
+			return 0, err
+		}
+
+		if n > limit {
+			return score, nil
+		}
+		return 0, nil
+	})
+}
+
+// NewFailedAuth 声明 [FailedAuth] 对象
+//
+// ttl 为记录的保存时间，从最后一次失败开始计算；
+// gen 为区分请求来源的方法，默认为不信任任何代理的 [acl.GenIP](nil)；
+func NewFailedAuth(c web.Cache, gen acl.GenFunc, ttl time.Duration) *FailedAuth {
+	if gen == nil {
+		gen = acl.GenIP(nil)
+	}
+	return &FailedAuth{c: c, gen: gen, ttl: ttl}
+}
+
+func (f *FailedAuth) key(ctx *web.Context) (string, error) {
+	key, err := f.gen(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "failed_" + key, nil
+}
+
+// Record 记录一次失败
+//
+// 应该在验证失败的地方调用，比如登录接口。
+func (f *FailedAuth) Record(ctx *web.Context) error {
+	key, err := f.key(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, setter, _, err := f.c.Counter(key, f.ttl)
+	if err == nil {
+		_, err = setter(1)
+	}
+	return err
+}
+
+// Reset 清除失败的记录
+//
+// 一般在验证成功之后调用。
+func (f *FailedAuth) Reset(ctx *web.Context) error {
+	key, err := f.key(ctx)
+	if err != nil {
+		return err
+	}
+	return f.c.Delete(key)
+}
+
+// Rule 失败次数达到 threshold 时返回 score 的规则
+func (f *FailedAuth) Rule(threshold uint64, score int) Rule {
+	return RuleFunc(func(ctx *web.Context) (int, error) {
+		key, err := f.key(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		n, _, _, err := f.c.Counter(key, f.ttl)
+		if err != nil {
+			return 0, err
+		}
+
+		if n >= threshold {
+			return score, nil
+		}
+		return 0, nil
+	})
+}
+
+func toLower(s []string) []string {
+	ret := make([]string, 0, len(s))
+	for _, v := range s {
+		ret = append(ret, strings.ToLower(v))
+	}
+	return ret
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/cache"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func newContext(s web.Server, ip string, h map[string]string) *web.Context {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = ip + ":8080"
+	for k, v := range h {
+		r.Header.Set(k, v)
+	}
+	return s.NewContext(httptest.NewRecorder(), r, types.NewContext())
+}
+
+func TestHeaderRule(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	r := HeaderRule(5)
+
+	score, err := r.Score(newContext(s, "1.1.1.1", nil))
+	a.NotError(err).Equal(score, 20)
+
+	score, err = r.Score(newContext(s, "1.1.1.1", map[string]string{
+		header.UserAgent:      "Mozilla/5.0",
+		header.Accept:         "*/*",
+		header.AcceptLanguage: "zh-CN",
+	}))
+	a.NotError(err).Equal(score, 5)
+}
+
+func TestUserAgentRule(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	r := UserAgentRule(10)
+	score, err := r.Score(newContext(s, "1.1.1.1", map[string]string{header.UserAgent: "curl/8.0"}))
+	a.NotError(err).Equal(score, 10)
+	score, err = r.Score(newContext(s, "1.1.1.1", map[string]string{header.UserAgent: "Python-Requests/2.31"}))
+	a.NotError(err).Equal(score, 10)
+	score, err = r.Score(newContext(s, "1.1.1.1", map[string]string{header.UserAgent: "Mozilla/5.0"}))
+	a.NotError(err).Equal(score, 0)
+
+	r = UserAgentRule(10, "Mozilla")
+	score, err = r.Score(newContext(s, "1.1.1.1", map[string]string{header.UserAgent: "mozilla/5.0"}))
+	a.NotError(err).Equal(score, 10)
+}
+
+func TestCadenceRule(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	c := cache.Prefix(s.Cache(), "bot-")
+
+	r := CadenceRule(c, nil, 2, time.Hour, 10)
+	for range 2 {
+		score, err := r.Score(newContext(s, "1.1.1.1", nil))
+		a.NotError(err).Equal(score, 0)
+	}
+	score, err := r.Score(newContext(s, "1.1.1.1", nil))
+	a.NotError(err).Equal(score, 10)
+
+	// 不同的来源
+	score, err = r.Score(newContext(s, "2.2.2.2", nil))
+	a.NotError(err).Equal(score, 0)
+
+	a.PanicString(func() {
+		CadenceRule(c, nil, 0, time.Hour, 10)
+	}, "参数 limit 和 window 必须大于 0")
+}
+
+func TestFailedAuth(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	f := NewFailedAuth(cache.Prefix(s.Cache(), "bot-"), nil, time.Hour)
+	r := f.Rule(2, 15)
+
+	ctx := newContext(s, "1.1.1.1", nil)
+	score, err := r.Score(ctx)
+	a.NotError(err).Equal(score, 0)
+
+	a.NotError(f.Record(ctx))
+	score, err = r.Score(ctx)
+	a.NotError(err).Equal(score, 0)
+
+	a.NotError(f.Record(ctx))
+	score, err = r.Score(ctx)
+	a.NotError(err).Equal(score, 15)
+
+	score, err = r.Score(newContext(s, "2.2.2.2", nil))
+	a.NotError(err).Equal(score, 0)
+
+	a.NotError(f.Reset(ctx))
+	score, err = r.Score(ctx)
+	a.NotError(err).Equal(score, 0)
+}
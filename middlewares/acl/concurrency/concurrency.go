@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package concurrency 限制并发请求数量的中间件
+//
+// 与按频率限流的 ratelimit 不同，这里限制的是同时处理的请求数量，
+// 可以防止处理缓慢的接口堆积过多的请求。超出上限的请求会在队列中等待一段时间，
+// 依然无法处理的请求直接返回 503。
+//
+// 并发上限由 [Limit] 决定，目前提供了以下几种：
+//   - [NewFixed] 固定的上限；
+//   - [NewAIMD] 根据处理时间进行加法增大乘法减小；
+//   - [NewGradient] 根据处理时间的变化梯度调整；
+//   - [NewSystat] 在其它算法的基础上根据系统的 CPU 使用率调整；
+package concurrency
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+// Concurrency 限制并发请求数量的中间件
+type Concurrency struct {
+	global  *limiter
+	route   func() Limit
+	keyed   *keyed
+	gen     acl.GenFunc
+	queue   int
+	timeout time.Duration
+}
+
+// New 声明 [Concurrency] 中间件
+//
+// global 为全局的并发上限，为空表示不作全局限制；
+// queue 为每个上限对应的等待队列的长度，为 0 表示不等待；
+// timeout 为请求在队列中等待的最长时间，同时也作为 Retry-After 报头的值；
+func New(global Limit, queue int, timeout time.Duration) *Concurrency {
+	if queue < 0 {
+		panic("参数 queue 不能小于 0")
+	}
+	if timeout <= 0 {
+		panic("参数 timeout 必须大于 0")
+	}
+
+	c := &Concurrency{queue: queue, timeout: timeout}
+	if global != nil {
+		c.global = newLimiter(global, queue)
+	}
+	return c
+}
+
+// PerRoute 为每个路由单独设置并发上限
+//
+// f 为每个路由生成一个 [Limit] 对象。需要在作为中间件使用之前调用。
+func (c *Concurrency) PerRoute(f func() Limit) *Concurrency {
+	c.route = f
+	return c
+}
+
+// PerIdentity 为每个用户单独设置并发上限
+//
+// gen 用于区分请求所属的用户，f 为每个用户生成一个 [Limit] 对象。
+// 用户没有正在处理的请求时，其 [Limit] 对象会被释放，所以不适合采用动态调整的 [Limit]。
+func (c *Concurrency) PerIdentity(gen acl.GenFunc, f func() Limit) *Concurrency {
+	c.gen = gen
+	c.keyed = newKeyed(f, c.queue)
+	return c
+}
+
+func (c *Concurrency) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
+	var route *limiter
+	if c.route != nil {
+		route = newLimiter(c.route(), c.queue)
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		// 按用户、路由、全局的顺序获取许可，
+		// 以免单个用户或是路由的请求在等待时占用全局的许可。
+		limiters := make([]*limiter, 0, 3)
+
+		if c.keyed != nil {
+			key, err := c.gen(ctx)
+			if err != nil {
+				return ctx.Error(err, web.ProblemInternalServerError)
+			}
+			l := c.keyed.get(key)
+			defer c.keyed.put(key, l)
+			limiters = append(limiters, l)
+		}
+		if route != nil {
+			limiters = append(limiters, route)
+		}
+		if c.global != nil {
+			limiters = append(limiters, c.global)
+		}
+
+		deadline := time.Now().Add(c.timeout)
+		for i, l := range limiters {
+			if !l.acquire(ctx, deadline) {
+				for _, acquired := range limiters[:i] {
+					acquired.release(0, false, false)
+				}
+				return c.shed(ctx)
+			}
+		}
+
+		start := time.Now()
+		defer func() {
+			rtt := time.Since(start)
+			overload := ctx.Err() != nil // 客户端因等待过久而取消
+			for _, l := range limiters {
+				l.release(rtt, overload, true)
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+func (c *Concurrency) shed(ctx *web.Context) web.Responser {
+	retry := int64((c.timeout + time.Second - 1) / time.Second)
+	ctx.Header().Set(header.RetryAfter, strconv.FormatInt(retry, 10))
+	return ctx.Problem(web.ProblemServiceUnavailable)
+}
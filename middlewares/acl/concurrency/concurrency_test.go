@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package concurrency
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+var _ web.Middleware = &Concurrency{}
+
+func TestNew(t *testing.T) {
+	a := assert.New(t, false)
+
+	c := New(nil, 0, time.Second)
+	a.NotNil(c).Nil(c.global)
+
+	a.PanicString(func() { New(nil, -1, time.Second) }, "参数 queue 不能小于 0")
+	a.PanicString(func() { New(nil, 1, 0) }, "参数 timeout 必须大于 0")
+}
+
+func TestConcurrency_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	c := New(NewFixed(3), 1, 100*time.Millisecond).
+		PerRoute(func() Limit { return NewFixed(2) }).
+		PerIdentity(func(ctx *web.Context) (string, error) {
+			return ctx.Request().Header.Get("x-uid"), nil
+		}, func() Limit { return NewFixed(1) })
+
+	block := make(chan struct{})
+	r := s.Routers().New("def", nil)
+	r.Use(c)
+	r.Get("/slow1", func(*web.Context) web.Responser {
+		<-block
+		return web.Created(nil, "")
+	})
+	r.Get("/slow2", func(*web.Context) web.Responser {
+		<-block
+		return web.Created(nil, "")
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	wg := &sync.WaitGroup{}
+	get := func(path, uid string) {
+		wg.Go(func() {
+			servertest.Get(a, "http://localhost:8080"+path).Header("x-uid", uid).Do(nil).
+				Status(http.StatusCreated)
+		})
+	}
+
+	// slow1 占满路由的限制，slow2 占用一个全局的限制。
+	get("/slow1", "1")
+	get("/slow1", "2")
+	get("/slow2", "3")
+	time.Sleep(50 * time.Millisecond)
+
+	// 同一用户
+	servertest.Get(a, "http://localhost:8080/slow2").Header("x-uid", "1").Do(nil).
+		Status(http.StatusServiceUnavailable).
+		Header(header.RetryAfter, "1")
+
+	// 同一路由
+	servertest.Get(a, "http://localhost:8080/slow1").Header("x-uid", "4").Do(nil).
+		Status(http.StatusServiceUnavailable)
+
+	// 全局
+	servertest.Get(a, "http://localhost:8080/slow2").Header("x-uid", "4").Do(nil).
+		Status(http.StatusServiceUnavailable)
+
+	// 在队列中等待
+	get("/slow2", "5")
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	a.Equal(c.global.inflight, 0).Length(c.keyed.items, 0)
+}
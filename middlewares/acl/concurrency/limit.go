@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package concurrency
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issue9/events"
+
+	"github.com/issue9/webuse/v7/services/systat"
+)
+
+// Limit 并发数量的上限
+//
+// 除了固定的上限，还可以根据请求的处理时间等数据动态调整。
+type Limit interface {
+	// Limit 当前允许的最大并发数量
+	Limit() int
+
+	// Sample 根据完成的请求调整上限
+	//
+	// rtt 为请求的处理时间；
+	// inflight 为请求完成时正在处理的请求数量，包含当前请求；
+	// overload 表示系统是否已经过载，比如客户端因等待过久而取消了请求；
+	Sample(rtt time.Duration, inflight int, overload bool)
+}
+
+type fixed int
+
+type aimd struct {
+	mux       sync.Mutex
+	limit     float64
+	min, max  float64
+	threshold time.Duration
+	backoff   float64
+}
+
+type gradient struct {
+	mux      sync.Mutex
+	limit    float64
+	min, max float64
+	short    float64 // 短期的平均处理时间，单位为纳秒。
+	long     float64 // 长期的平均处理时间，作为无负载时的参考值，单位为纳秒。
+}
+
+type stat struct {
+	l         Limit
+	threshold float64
+	cpu       atomic.Uint64 // math.Float64bits
+}
+
+// NewFixed 固定的并发上限
+func NewFixed(n int) Limit {
+	if n <= 0 {
+		panic("参数 n 必须大于 0")
+	}
+	return fixed(n)
+}
+
+func (l fixed) Limit() int { return int(l) }
+
+func (l fixed) Sample(time.Duration, int, bool) {}
+
+// NewAIMD 加法增大乘法减小算法
+//
+// 请求的处理时间未超过 threshold 时，上限加 1，否则上限乘以 0.9。
+//
+// initial 为初始的上限；min 和 max 为上限的取值范围；
+func NewAIMD(initial, min, max int, threshold time.Duration) Limit {
+	if min <= 0 || min > max || initial < min || initial > max {
+		panic("参数必须满足 0 < min <= initial <= max")
+	}
+	if threshold <= 0 {
+		panic("参数 threshold 必须大于 0")
+	}
+
+	return &aimd{
+		limit:     float64(initial),
+		min:       float64(min),
+		max:       float64(max),
+		threshold: threshold,
+		backoff:   0.9,
+	}
+}
+
+func (l *aimd) Limit() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return int(l.limit)
+}
+
+func (l *aimd) Sample(rtt time.Duration, inflight int, overload bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	switch {
+	case overload || rtt > l.threshold:
+		l.limit = max(l.min, l.limit*l.backoff)
+	case float64(inflight)*2 >= l.limit: // 负载过低时上限并未起作用，不需要增加。
+		l.limit = min(l.max, l.limit+1)
+	}
+}
+
+// NewGradient 梯度算法
+//
+// 以长期平均处理时间与短期平均处理时间的比值作为梯度调整上限，
+// 处理时间变长时减小上限，并额外保留 √limit 的余量用于探测更高的上限。
+//
+// initial 为初始的上限；min 和 max 为上限的取值范围；
+func NewGradient(initial, min, max int) Limit {
+	if min <= 0 || min > max || initial < min || initial > max {
+		panic("参数必须满足 0 < min <= initial <= max")
+	}
+
+	return &gradient{
+		limit: float64(initial),
+		min:   float64(min),
+		max:   float64(max),
+	}
+}
+
+func (l *gradient) Limit() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return int(l.limit)
+}
+
+func (l *gradient) Sample(rtt time.Duration, inflight int, overload bool) {
+	const (
+		shortWindow = 10
+		longWindow  = 600
+		smoothing   = 0.2
+	)
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	sample := max(float64(rtt), 1) // 防止除以 0
+	if l.long == 0 {
+		l.long, l.short = sample, sample
+	} else {
+		l.short += (sample - l.short) / shortWindow
+		l.long += (sample - l.long) / longWindow
+	}
+
+	// 负载下降之后，长期的平均值会高于短期的平均值，需要尽快回落。
+	if l.long/l.short > 2 {
+		l.long *= 0.95
+	}
+
+	if !overload && float64(inflight)*2 < l.limit { // 负载过低时上限并未起作用，不需要调整。
+		return
+	}
+
+	g := 0.5
+	if !overload {
+		g = max(0.5, min(1, l.long/l.short))
+	}
+	limit := l.limit*g + math.Sqrt(l.limit)
+	limit = l.limit*(1-smoothing) + limit*smoothing
+	l.limit = max(l.min, min(l.max, limit))
+}
+
+// NewSystat 在 l 的基础上根据系统的 CPU 使用率调整上限
+//
+// sub 为 [systat.Init] 返回的对象，当系统的 CPU 使用率超过 threshold 时，
+// 所有的请求均被视为过载，由 l 减小上限。threshold 的取值范围为 (0, 100]。
+func NewSystat(l Limit, sub events.Subscriber[*systat.Stats], threshold float64) Limit {
+	if threshold <= 0 || threshold > 100 {
+		panic("参数 threshold 的取值范围为 (0, 100]")
+	}
+
+	s := &stat{l: l, threshold: threshold}
+	sub.Subscribe(func(data *systat.Stats) {
+		s.cpu.Store(math.Float64bits(data.OS.CPU))
+	})
+	return s
+}
+
+func (l *stat) Limit() int { return l.l.Limit() }
+
+func (l *stat) Sample(rtt time.Duration, inflight int, overload bool) {
+	overload = overload || math.Float64frombits(l.cpu.Load()) > l.threshold
+	l.l.Sample(rtt, inflight, overload)
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/events"
+
+	"github.com/issue9/webuse/v7/services/systat"
+)
+
+var (
+	_ Limit = fixed(1)
+	_ Limit = &aimd{}
+	_ Limit = &gradient{}
+	_ Limit = &stat{}
+)
+
+func TestNewFixed(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := NewFixed(5)
+	a.Equal(l.Limit(), 5)
+	l.Sample(time.Hour, 5, true)
+	a.Equal(l.Limit(), 5)
+
+	a.PanicString(func() { NewFixed(0) }, "参数 n 必须大于 0")
+}
+
+func TestNewAIMD(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := NewAIMD(10, 5, 12, 100*time.Millisecond)
+	a.Equal(l.Limit(), 10)
+
+	// 负载过低，不作调整。
+	l.Sample(time.Millisecond, 1, false)
+	a.Equal(l.Limit(), 10)
+
+	l.Sample(time.Millisecond, 5, false)
+	a.Equal(l.Limit(), 11)
+	l.Sample(time.Millisecond, 10, false)
+	l.Sample(time.Millisecond, 10, false)
+	a.Equal(l.Limit(), 12) // 不超过 max
+
+	l.Sample(time.Second, 10, false)
+	a.Equal(l.Limit(), 10) // 12*0.9
+
+	for range 10 {
+		l.Sample(time.Millisecond, 10, true)
+	}
+	a.Equal(l.Limit(), 5) // 不低于 min
+
+	a.PanicString(func() {
+		NewAIMD(1, 5, 12, time.Second)
+	}, "参数必须满足 0 < min <= initial <= max")
+	a.PanicString(func() {
+		NewAIMD(5, 5, 12, 0)
+	}, "参数 threshold 必须大于 0")
+}
+
+func TestNewGradient(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := NewGradient(20, 5, 100)
+	a.Equal(l.Limit(), 20)
+
+	// 处理时间稳定，逐渐增加上限。
+	for range 20 {
+		l.Sample(10*time.Millisecond, l.Limit(), false)
+	}
+	stable := l.Limit()
+	a.True(stable > 20, stable)
+
+	// 处理时间变长，减小上限。
+	for range 20 {
+		l.Sample(100*time.Millisecond, l.Limit(), false)
+	}
+	slow := l.Limit()
+	a.True(slow < stable, slow)
+
+	// 过载
+	for range 50 {
+		l.Sample(10*time.Millisecond, l.Limit(), true)
+	}
+	a.Equal(l.Limit(), 5)
+
+	// 负载过低，不作调整。
+	l.Sample(time.Second, 1, false)
+	a.Equal(l.Limit(), 5)
+
+	a.PanicString(func() {
+		NewGradient(0, 0, 12)
+	}, "参数必须满足 0 < min <= initial <= max")
+}
+
+type subscriber struct {
+	f events.SubscribeFunc[*systat.Stats]
+}
+
+func (s *subscriber) Subscribe(f events.SubscribeFunc[*systat.Stats]) context.CancelFunc {
+	s.f = f
+	return func() {}
+}
+
+func TestNewSystat(t *testing.T) {
+	a := assert.New(t, false)
+
+	sub := &subscriber{}
+	l := NewSystat(NewAIMD(10, 5, 20, time.Second), sub, 80)
+	a.NotNil(sub.f).Equal(l.Limit(), 10)
+
+	sub.f(&systat.Stats{OS: &systat.OS{CPU: 50}})
+	l.Sample(time.Millisecond, 10, false)
+	a.Equal(l.Limit(), 11)
+
+	sub.f(&systat.Stats{OS: &systat.OS{CPU: 90}})
+	l.Sample(time.Millisecond, 10, false)
+	a.Equal(l.Limit(), 9) // 11*0.9
+
+	a.PanicString(func() {
+		NewSystat(NewFixed(1), sub, 101)
+	}, "参数 threshold 的取值范围为 (0, 100]")
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// 带等待队列的信号量
+type limiter struct {
+	limit Limit
+	queue int // 等待队列的最大长度
+
+	mux      sync.Mutex
+	inflight int
+	waiters  *list.List // chan struct{}
+
+	refs int // 引用计数，由 keyed 维护。
+}
+
+// 以 key 区分的多个 limiter
+//
+// 没有请求引用的 limiter 会被删除，以免占用过多的内存。
+type keyed struct {
+	limit func() Limit
+	queue int
+
+	mux   sync.Mutex
+	items map[string]*limiter
+}
+
+func newLimiter(l Limit, queue int) *limiter {
+	return &limiter{limit: l, queue: queue, waiters: list.New()}
+}
+
+// 获取许可
+//
+// 没有空闲的许可时进入等待队列，直到获得许可、超过 deadline 或是 ctx 被取消。
+func (l *limiter) acquire(ctx context.Context, deadline time.Time) bool {
+	l.mux.Lock()
+	if l.inflight < l.limit.Limit() {
+		l.inflight++
+		l.mux.Unlock()
+		return true
+	}
+	if l.waiters.Len() >= l.queue {
+		l.mux.Unlock()
+		return false
+	}
+	ch := make(chan struct{})
+	elem := l.waiters.PushBack(ch)
+	l.mux.Unlock()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	select {
+	case <-ch: // 超时的同时获得了许可
+		return true
+	default:
+		l.waiters.Remove(elem)
+		return false
+	}
+}
+
+// 释放许可
+//
+// rtt 和 overload 用于调整并发上限，如果 sample 为 false，则不作调整。
+func (l *limiter) release(rtt time.Duration, overload, sample bool) {
+	if sample {
+		l.mux.Lock()
+		inflight := l.inflight
+		l.mux.Unlock()
+		l.limit.Sample(rtt, inflight, overload)
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.inflight--
+	for l.waiters.Len() > 0 && l.inflight < l.limit.Limit() {
+		l.inflight++
+		close(l.waiters.Remove(l.waiters.Front()).(chan struct{}))
+	}
+}
+
+func newKeyed(f func() Limit, queue int) *keyed {
+	return &keyed{limit: f, queue: queue, items: make(map[string]*limiter, 100)}
+}
+
+func (k *keyed) get(key string) *limiter {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	l, found := k.items[key]
+	if !found {
+		l = newLimiter(k.limit(), k.queue)
+		k.items[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (k *keyed) put(key string, l *limiter) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if l.refs--; l.refs == 0 {
+		delete(k.items, key)
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+)
+
+func (l *limiter) stat() (inflight, waiters int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.inflight, l.waiters.Len()
+}
+
+func TestLimiter(t *testing.T) {
+	a := assert.New(t, false)
+	ctx := context.Background()
+
+	l := newLimiter(NewFixed(2), 1)
+	deadline := time.Now().Add(50 * time.Millisecond)
+	a.True(l.acquire(ctx, deadline)).
+		True(l.acquire(ctx, deadline))
+
+	// 队列已满
+	l.waiters.PushBack(make(chan struct{}))
+	a.False(l.acquire(ctx, deadline))
+	l.waiters.Init()
+
+	// 超时
+	a.False(l.acquire(ctx, deadline)).
+		Equal(l.waiters.Len(), 0)
+
+	// 等待过程中获得许可
+	done := make(chan bool)
+	go func() { done <- l.acquire(ctx, time.Now().Add(time.Second)) }()
+	time.Sleep(10 * time.Millisecond)
+	_, waiters := l.stat()
+	a.Equal(waiters, 1)
+	l.release(0, false, false)
+	a.True(<-done)
+	inflight, waiters := l.stat()
+	a.Equal(inflight, 2).Equal(waiters, 0)
+
+	// 取消
+	cctx, cancel := context.WithCancel(ctx)
+	go func() { done <- l.acquire(cctx, time.Now().Add(time.Second)) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	a.False(<-done).Equal(l.inflight, 2)
+
+	l.release(0, false, false)
+	l.release(0, false, false)
+	a.Equal(l.inflight, 0)
+}
+
+func TestLimiter_concurrency(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := newLimiter(NewFixed(5), 100)
+	var curr, peak atomic.Int64
+	wg := &sync.WaitGroup{}
+	for range 50 {
+		wg.Go(func() {
+			a.True(l.acquire(context.Background(), time.Now().Add(5*time.Second)))
+			n := curr.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			curr.Add(-1)
+			l.release(time.Millisecond, false, true)
+		})
+	}
+	wg.Wait()
+
+	a.Equal(peak.Load(), 5).Equal(l.inflight, 0).Equal(l.waiters.Len(), 0)
+}
+
+func TestKeyed(t *testing.T) {
+	a := assert.New(t, false)
+
+	k := newKeyed(func() Limit { return NewFixed(1) }, 0)
+	l1 := k.get("1")
+	l2 := k.get("1")
+	a.Equal(l1, l2).Length(k.items, 1)
+
+	l3 := k.get("2")
+	a.NotEqual(l1, l3).Length(k.items, 2)
+
+	k.put("1", l1)
+	a.Length(k.items, 2)
+	k.put("1", l2)
+	k.put("2", l3)
+	a.Length(k.items, 0)
+}
@@ -0,0 +1,284 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package geo 根据客户端的地理位置进行访问控制
+//
+// 地理位置数据来自于 [MaxMind DB] 格式的数据库，
+// 比如 GeoLite2-Country、GeoLite2-ASN 或是其它兼容的数据库。
+//
+// [MaxMind DB]: https://maxmind.github.io/MaxMind-DB/
+package geo
+
+import (
+	"io/fs"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issue9/web"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+type contextType int
+
+const locationContext contextType = 0
+
+// Location 客户端的地理位置
+type Location struct {
+	// 国家或地区的代码
+	//
+	// 采用 ISO 3166-1 的两位字母代码，比如 CN、US 等。
+	Country string `json:"country,omitempty" xml:"country,attr,omitempty" yaml:"country,omitempty" cbor:"country,omitempty" comment:"country code"`
+
+	// 自治系统编号
+	ASN uint `json:"asn,omitempty" xml:"asn,attr,omitempty" yaml:"asn,omitempty" cbor:"asn,omitempty" comment:"autonomous system number"`
+
+	// 自治系统所属的组织
+	Organization string `json:"organization,omitempty" xml:"organization,omitempty" yaml:"organization,omitempty" cbor:"organization,omitempty" comment:"autonomous system organization"`
+}
+
+// 数据库中与 [Location] 相关的字段
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	ASN          uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+type database struct {
+	fsys    fs.FS
+	name    string
+	modTime time.Time
+	size    int64
+	reader  atomic.Pointer[maxminddb.Reader]
+}
+
+// Geo 根据地理位置过滤请求的中间件
+//
+// 拒绝的规则优先于允许的规则；
+// 如果指定了允许的国家或是 ASN，那么只有匹配其中之一的请求才被允许，
+// 无法确定地理位置的请求（比如内网地址）也会被拒绝。
+//
+// 所有的方法都是并发安全的。
+type Geo struct {
+	resolver *acl.Resolver
+	dbs      []*database
+
+	mux            sync.RWMutex
+	allowCountries []string
+	denyCountries  []string
+	allowASN       []uint
+	denyASN        []uint
+}
+
+// New 声明 [Geo] 对象
+//
+// r 用于获取客户端的 IP，如果为空，表示不信任任何代理，直接采用连接的地址；
+// interval 为检测数据库文件是否有变化的时间间隔，如果文件有变化会重新加载，为 0 表示不检测；
+// fsys 和 names 为数据库文件，可以同时指定多个，比如国家和 ASN 的数据库，查询结果将被合并。
+func New(s web.Server, r *acl.Resolver, interval time.Duration, fsys fs.FS, names ...string) (*Geo, error) {
+	if len(names) == 0 {
+		panic("参数 names 不能为空")
+	}
+
+	if r == nil {
+		r = &acl.Resolver{}
+	}
+
+	g := &Geo{resolver: r, dbs: make([]*database, 0, len(names))}
+	for _, name := range names {
+		db := &database{fsys: fsys, name: name}
+		if err := db.load(); err != nil {
+			return nil, err
+		}
+		g.dbs = append(g.dbs, db)
+	}
+
+	if interval > 0 {
+		s.Services().AddTicker(web.Phrase("reload geo database"), g.reload, interval, false, true)
+	}
+
+	return g, nil
+}
+
+// Allow 允许访问的国家或地区代码
+func (g *Geo) Allow(country ...string) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.allowCountries = appendCountries(g.allowCountries, country)
+}
+
+// Deny 拒绝访问的国家或地区代码
+func (g *Geo) Deny(country ...string) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.denyCountries = appendCountries(g.denyCountries, country)
+}
+
+// AllowASN 允许访问的自治系统编号
+func (g *Geo) AllowASN(asn ...uint) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.allowASN = appendASN(g.allowASN, asn)
+}
+
+// DenyASN 拒绝访问的自治系统编号
+func (g *Geo) DenyASN(asn ...uint) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.denyASN = appendASN(g.denyASN, asn)
+}
+
+// Reset 清除所有的规则
+func (g *Geo) Reset() {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.allowCountries = nil
+	g.denyCountries = nil
+	g.allowASN = nil
+	g.denyASN = nil
+}
+
+// Lookup 查询 ip 的地理位置
+//
+// 如果数据库中不存在该 IP，返回的 [Location] 各字段均为零值。
+func (g *Geo) Lookup(ip netip.Addr) (*Location, error) {
+	ip = ip.Unmap()
+	loc := &Location{}
+	for _, db := range g.dbs {
+		reader := db.reader.Load()
+		if ip.Is6() && reader.Metadata.IPVersion == 4 {
+			continue
+		}
+
+		var r record
+		if err := reader.Lookup(net.IP(ip.AsSlice()), &r); err != nil {
+			return nil, err
+		}
+
+		if loc.Country == "" {
+			if loc.Country = r.Country.ISOCode; loc.Country == "" {
+				loc.Country = r.RegisteredCountry.ISOCode
+			}
+		}
+		if loc.ASN == 0 {
+			loc.ASN = r.ASN
+			loc.Organization = r.Organization
+		}
+	}
+	return loc, nil
+}
+
+func (g *Geo) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
+	return func(ctx *web.Context) web.Responser {
+		ip, err := g.resolver.ClientIP(ctx)
+		if err != nil {
+			return ctx.Problem(web.ProblemBadRequest)
+		}
+
+		loc, err := g.Lookup(ip)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		ctx.SetVar(locationContext, loc)
+		ctx.Logs().AppendAttrs(map[string]any{"country": loc.Country, "asn": loc.ASN})
+
+		if !g.allowed(loc) {
+			return ctx.Problem(web.ProblemForbidden)
+		}
+		return next(ctx)
+	}
+}
+
+func (g *Geo) allowed(loc *Location) bool {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	if (loc.Country != "" && slices.Contains(g.denyCountries, loc.Country)) ||
+		(loc.ASN != 0 && slices.Contains(g.denyASN, loc.ASN)) {
+		return false
+	}
+
+	if len(g.allowCountries) == 0 && len(g.allowASN) == 0 {
+		return true
+	}
+	return (loc.Country != "" && slices.Contains(g.allowCountries, loc.Country)) ||
+		(loc.ASN != 0 && slices.Contains(g.allowASN, loc.ASN))
+}
+
+// 重新加载有变化的数据库
+func (g *Geo) reload(time.Time) error {
+	for _, db := range g.dbs {
+		if err := db.load(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 加载数据库
+//
+// 仅在文件的修改时间或大小发生变化时才会重新加载。
+func (db *database) load() error {
+	stat, err := fs.Stat(db.fsys, db.name)
+	if err != nil {
+		return err
+	}
+	if db.reader.Load() != nil && stat.ModTime().Equal(db.modTime) && stat.Size() == db.size {
+		return nil
+	}
+
+	data, err := fs.ReadFile(db.fsys, db.name)
+	if err != nil {
+		return err
+	}
+
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	db.reader.Store(reader)
+	db.modTime, db.size = stat.ModTime(), stat.Size()
+	return nil
+}
+
+// GetLocation 获取由 [Geo] 中间件解析的地理位置
+//
+// 如果未经过 [Geo] 中间件，则返回 nil。
+func GetLocation(ctx *web.Context) *Location {
+	if v, found := ctx.GetVar(locationContext); found {
+		return v.(*Location)
+	}
+	return nil
+}
+
+func appendCountries(list, country []string) []string {
+	for _, c := range country {
+		if c = strings.ToUpper(strings.TrimSpace(c)); !slices.Contains(list, c) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func appendASN(list, asn []uint) []uint {
+	for _, n := range asn {
+		if !slices.Contains(list, n) {
+			list = append(list, n)
+		}
+	}
+	return list
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"net/http"
+	"net/netip"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+var _ web.Middleware = &Geo{}
+
+// 生成仅包含 IPv4 地址的 MaxMind DB 数据
+func buildMMDB(records map[string]map[string]any) []byte {
+	type node struct {
+		children [2]*node
+		data     int // 数据在数据段中的偏移，-1 表示非叶子节点。
+	}
+
+	data := &bytes.Buffer{}
+	root := &node{data: -1}
+	for _, prefix := range slices.Sorted(maps.Keys(records)) {
+		p := netip.MustParsePrefix(prefix)
+		offset := data.Len()
+		encodeMMDB(data, records[prefix])
+
+		n := root
+		ip := p.Addr().As4()
+		for i := range p.Bits() {
+			b := (ip[i/8] >> (7 - i%8)) & 1
+			if n.children[b] == nil {
+				n.children[b] = &node{data: -1}
+			}
+			n = n.children[b]
+		}
+		n.data = offset
+	}
+
+	nodes := []*node{root}
+	for i := 0; i < len(nodes); i++ {
+		for _, c := range nodes[i].children {
+			if c != nil && c.data < 0 {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	index := make(map[*node]int, len(nodes))
+	for i, n := range nodes {
+		index[n] = i
+	}
+
+	buf := &bytes.Buffer{}
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, c := range n.children {
+			v := count // 空记录
+			switch {
+			case c == nil:
+			case c.data >= 0:
+				v = count + 16 + c.data
+			default:
+				v = index[c]
+			}
+			buf.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data.Bytes())
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encodeMMDB(buf, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "test",
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint32(time.Now().Unix()),
+	})
+
+	return buf.Bytes()
+}
+
+func encodeMMDB(buf *bytes.Buffer, v any) {
+	writeUint := func(typ byte, n uint64) {
+		b := binary.BigEndian.AppendUint64(nil, n)
+		b = bytes.TrimLeft(b, "\x00")
+		buf.WriteByte(typ<<5 | byte(len(b)))
+		buf.Write(b)
+	}
+
+	switch val := v.(type) {
+	case string:
+		if l := len(val); l < 29 {
+			buf.WriteByte(2<<5 | byte(l))
+		} else { // 长度在 [29, 285) 之间
+			buf.WriteByte(2<<5 | 29)
+			buf.WriteByte(byte(l - 29))
+		}
+		buf.WriteString(val)
+	case uint16:
+		writeUint(5, uint64(val))
+	case uint32:
+		writeUint(6, uint64(val))
+	case map[string]any:
+		buf.WriteByte(7<<5 | byte(len(val)))
+		for _, k := range slices.Sorted(maps.Keys(val)) {
+			encodeMMDB(buf, k)
+			encodeMMDB(buf, val[k])
+		}
+	default:
+		panic("不支持的类型")
+	}
+}
+
+func newFS() fstest.MapFS {
+	return fstest.MapFS{
+		"country.mmdb": &fstest.MapFile{
+			ModTime: time.Now(),
+			Data: buildMMDB(map[string]map[string]any{
+				"1.0.0.0/8": {"country": map[string]any{"iso_code": "CN"}},
+				"2.2.0.0/16": {
+					"country":            map[string]any{"iso_code": "US"},
+					"registered_country": map[string]any{"iso_code": "CN"},
+				},
+				"3.3.3.0/24": {"registered_country": map[string]any{"iso_code": "JP"}},
+			}),
+		},
+		"asn.mmdb": &fstest.MapFile{
+			ModTime: time.Now(),
+			Data: buildMMDB(map[string]map[string]any{
+				"1.1.0.0/16": {"autonomous_system_number": uint32(4134), "autonomous_system_organization": "CHINANET"},
+				"2.0.0.0/8":  {"autonomous_system_number": uint32(7018), "autonomous_system_organization": "ATT"},
+			}),
+		},
+		"invalid.mmdb": &fstest.MapFile{Data: []byte("invalid")},
+	}
+}
+
+func TestGeo_Lookup(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	fsys := newFS()
+
+	g, err := New(s, nil, 0, fsys, "country.mmdb", "asn.mmdb")
+	a.NotError(err).NotNil(g)
+
+	loc, err := g.Lookup(netip.MustParseAddr("1.1.1.1"))
+	a.NotError(err).Equal(loc, &Location{Country: "CN", ASN: 4134, Organization: "CHINANET"})
+
+	loc, err = g.Lookup(netip.MustParseAddr("2.2.2.2"))
+	a.NotError(err).Equal(loc, &Location{Country: "US", ASN: 7018, Organization: "ATT"})
+
+	loc, err = g.Lookup(netip.MustParseAddr("::ffff:3.3.3.3"))
+	a.NotError(err).Equal(loc, &Location{Country: "JP"})
+
+	loc, err = g.Lookup(netip.MustParseAddr("4.4.4.4"))
+	a.NotError(err).Equal(loc, &Location{})
+
+	loc, err = g.Lookup(netip.MustParseAddr("2001:db8::1"))
+	a.NotError(err).Equal(loc, &Location{})
+
+	g, err = New(s, nil, 0, fsys, "invalid.mmdb")
+	a.Error(err).Nil(g)
+
+	g, err = New(s, nil, 0, fsys, "not-exists.mmdb")
+	a.Error(err).Nil(g)
+
+	a.PanicString(func() {
+		_, _ = New(s, nil, 0, fsys)
+	}, "参数 names 不能为空")
+}
+
+func TestGeo_reload(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	fsys := newFS()
+
+	g, err := New(s, nil, 0, fsys, "country.mmdb")
+	a.NotError(err).NotNil(g)
+	loc, err := g.Lookup(netip.MustParseAddr("4.4.4.4"))
+	a.NotError(err).Equal(loc.Country, "")
+
+	// 文件未改变
+	a.NotError(g.reload(s.Now()))
+
+	fsys["country.mmdb"].Data = buildMMDB(map[string]map[string]any{
+		"4.0.0.0/8": {"country": map[string]any{"iso_code": "DE"}},
+	})
+	fsys["country.mmdb"].ModTime = time.Now().Add(time.Second)
+	a.NotError(g.reload(s.Now()))
+	loc, err = g.Lookup(netip.MustParseAddr("4.4.4.4"))
+	a.NotError(err).Equal(loc.Country, "DE")
+
+	// 加载失败，保留原来的数据。
+	fsys["country.mmdb"].Data = []byte("invalid")
+	fsys["country.mmdb"].ModTime = time.Now().Add(2 * time.Second)
+	a.Error(g.reload(s.Now()))
+	loc, err = g.Lookup(netip.MustParseAddr("4.4.4.4"))
+	a.NotError(err).Equal(loc.Country, "DE")
+}
+
+func TestGeo_allowed(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	g, err := New(s, nil, 0, newFS(), "country.mmdb", "asn.mmdb")
+	a.NotError(err).NotNil(g)
+
+	cn := &Location{Country: "CN", ASN: 4134}
+	us := &Location{Country: "US", ASN: 7018}
+	unknown := &Location{}
+
+	a.True(g.allowed(cn)).True(g.allowed(us)).True(g.allowed(unknown))
+
+	g.Deny("us")
+	a.True(g.allowed(cn)).False(g.allowed(us)).True(g.allowed(unknown))
+
+	g.Allow(" cn ")
+	a.True(g.allowed(cn)).False(g.allowed(us)).False(g.allowed(unknown))
+
+	g.DenyASN(4134)
+	a.False(g.allowed(cn))
+
+	g.Reset()
+	g.AllowASN(7018)
+	a.False(g.allowed(cn)).True(g.allowed(us)).False(g.allowed(unknown))
+}
+
+func TestGeo_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	r, err := acl.NewResolver([]string{"127.0.0.0/8", "::1"}, header.XForwardedFor)
+	a.NotError(err)
+	g, err := New(s, r, 0, newFS(), "country.mmdb", "asn.mmdb")
+	a.NotError(err).NotNil(g)
+	g.Deny("US")
+
+	router := s.Routers().New("def", nil)
+	router.Use(g)
+	router.Get("/test", func(ctx *web.Context) web.Responser {
+		return web.OK(GetLocation(ctx))
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/test").
+		Header(header.XForwardedFor, "1.1.1.1").
+		Header(header.Accept, "application/json").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{"country":"CN","asn":4134,"organization":"CHINANET"}`)
+
+	servertest.Get(a, "http://localhost:8080/test").
+		Header(header.XForwardedFor, "2.2.2.2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Get(a, "http://localhost:8080/test").
+		Header(header.XForwardedFor, "4.4.4.4").
+		Header(header.Accept, "application/json").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`{}`)
+}
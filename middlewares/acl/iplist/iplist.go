@@ -6,33 +6,67 @@
 package iplist
 
 import (
+	"net/netip"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
 )
 
 // IPLister 根据客户端的 IP 过滤
+//
+// 所有的方法都是并发安全的。
 type IPLister interface {
 	web.Middleware
 
-	// Set 设置名单列表
+	// Set 添加名单
+	//
+	// ip 可以是以下格式：
+	//  - 具体的 IP 地址，比如 44.44.44.44 或是 2001:db8::1；
+	//  - CIDR，比如 10.0.0.0/8 或是 2001:db8::/32；
+	//  - 范围，以 - 连接起止地址，比如 10.0.0.1-10.0.0.9；
+	//  - 以 /* 结尾的 IPv4 前缀，比如 192.168/*，等同于 192.168.0.0/16；
 	//
-	// ip IP 地址，可以是具体的 IP 地址，比如 44.44.44.44，
-	// 也可以是通配符(以 /* 结尾)，比如 2.2/*，表示匹配 2.2.1.1 也匹配 2.22.1.1，
-	// 但是并不匹配 ipv6 的相同地址 ::0202:0202。
 	// 如果已经存在相同的值，则不会重复添加。
+	// 只要有一个值格式错误，就不会添加任何值。
+	Set(ip ...string) error
+
+	// Delete 删除名单
 	//
-	// NOTE: 传递空值将清空内容。
-	Set(ip ...string)
+	// ip 的格式与 Set 相同，且需要与添加时的值表示相同的范围，不存在的值将被忽略。
+	Delete(ip ...string) error
+
+	// Reset 清空名单
+	Reset()
+
+	// Replace 以 ip 替换整个名单
+	//
+	// ip 的格式与 Set 相同，只要有一个值格式错误，就不会作任何修改。
+	// 替换是原子操作，不会出现部分生效的情况。
+	Replace(ip ...string) error
 
 	// List 返回名单列表
+	//
+	// 返回的是规范化之后的值，比如 10.1.2.3/8 会被表示为 10.0.0.0/8。
 	List() []string
 }
 
 type common struct {
-	list     []string
-	wildcard []string
+	resolver *acl.Resolver
+
+	mux     sync.Mutex // 保护 entries 的修改
+	entries []*entry
+	trie    atomic.Pointer[trie]
+}
+
+type entry struct {
+	value    string // 规范化之后的值
+	prefixes []netip.Prefix
 }
 
 type white struct {
@@ -43,69 +77,129 @@ type black struct {
 	*common
 }
 
-func newCommon() *common {
-	return &common{
-		list:     make([]string, 0, 10),
-		wildcard: make([]string, 0, 10),
+func newCommon(r *acl.Resolver) *common {
+	if r == nil {
+		r = &acl.Resolver{}
 	}
+
+	c := &common{resolver: r, entries: make([]*entry, 0, 10)}
+	c.trie.Store(newTrie())
+	return c
 }
 
 // NewWhite 声明白名单过滤器
 //
 // 所有未在白名单中的 IP 都将被禁止访问。
-func NewWhite() IPLister { return &white{common: newCommon()} }
+// r 用于获取客户端的 IP，如果为空，表示不信任任何代理，直接采用连接的地址。
+func NewWhite(r *acl.Resolver) IPLister { return &white{common: newCommon(r)} }
 
-// NewWhite 声明黑名单过滤器
+// NewBlack 声明黑名单过滤器
 //
 // 所有未在黑名单中的 IP 才允许访问。
-func NewBlack() IPLister { return &black{common: newCommon()} }
+// r 用于获取客户端的 IP，如果为空，表示不信任任何代理，直接采用连接的地址。
+func NewBlack(r *acl.Resolver) IPLister { return &black{common: newCommon(r)} }
 
-func (l *common) Set(ip ...string) {
-	for _, i := range ip {
-		if strings.HasSuffix(i, "/*") {
-			i = strings.TrimSuffix(i, "/*")
+func (l *common) Set(ip ...string) error {
+	entries, err := parseEntries(ip)
+	if err != nil {
+		return err
+	}
 
-			if slices.Index(l.wildcard, i) < 0 {
-				l.wildcard = append(l.wildcard, i)
-			}
-		} else {
-			if slices.Index(l.list, i) < 0 {
-				l.list = append(l.list, i)
-			}
-		}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.add(entries)
+	return nil
+}
+
+func (l *common) Delete(ip ...string) error {
+	entries, err := parseEntries(ip)
+	if err != nil {
+		return err
 	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.entries = slices.DeleteFunc(l.entries, func(v *entry) bool {
+		return slices.ContainsFunc(entries, func(e *entry) bool { return v.value == e.value })
+	})
+	l.rebuild()
+	return nil
+}
+
+func (l *common) Replace(ip ...string) error {
+	entries, err := parseEntries(ip)
+	if err != nil {
+		return err
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.entries = l.entries[:0]
+	l.add(entries)
+	return nil
+}
+
+func (l *common) Reset() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.entries = l.entries[:0]
+	l.rebuild()
 }
 
 func (l *common) List() []string {
-	list := slices.Clone(l.list)
-	for _, w := range l.wildcard {
-		list = append(list, w+"/*")
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	list := make([]string, 0, len(l.entries))
+	for _, e := range l.entries {
+		list = append(list, e.value)
 	}
 	return list
 }
 
-func (l *common) match(ip string) bool {
-	if slices.Index(l.list, ip) >= 0 {
-		return true
+// 添加 entries 中不存在的值并重新生成前缀树
+//
+// 调用方需要持有 mux 的锁。
+func (l *common) add(entries []*entry) {
+	exists := make(map[string]struct{}, len(l.entries)+len(entries)) // 名单可能很大，不采用 slices.Contains。
+	for _, e := range l.entries {
+		exists[e.value] = struct{}{}
 	}
 
-	for _, ip := range l.wildcard {
-		if strings.HasPrefix(ip, ip) {
-			return true
+	for _, e := range entries {
+		if _, found := exists[e.value]; !found {
+			l.entries = append(l.entries, e)
+			exists[e.value] = struct{}{}
 		}
 	}
+	l.rebuild()
+}
 
-	return false
+// 根据 entries 重新生成前缀树
+//
+// 调用方需要持有 mux 的锁。
+func (l *common) rebuild() {
+	prefixes := make([]netip.Prefix, 0, len(l.entries))
+	for _, e := range l.entries {
+		prefixes = append(prefixes, e.prefixes...)
+	}
+	l.trie.Store(newTrie(prefixes...))
 }
 
+func (l *common) match(ip netip.Addr) bool { return l.trie.Load().contains(ip) }
+
 func (l *white) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
 	return func(ctx *web.Context) web.Responser {
-		host, err := splitIP(ctx.ClientIP())
+		ip, err := l.resolver.ClientIP(ctx)
 		if err != nil {
 			return ctx.Error(err, web.ProblemBadRequest)
 		}
 
-		if l.match(host) {
+		if l.match(ip) {
 			return next(ctx)
 		}
 		return ctx.Problem(web.ProblemForbidden)
@@ -114,34 +208,119 @@ func (l *white) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc
 
 func (l *black) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc {
 	return func(ctx *web.Context) web.Responser {
-		host, err := splitIP(ctx.ClientIP())
+		ip, err := l.resolver.ClientIP(ctx)
 		if err != nil {
-			return ctx.Problem(web.ProblemBadRequest)
+			return ctx.Error(err, web.ProblemBadRequest)
 		}
 
-		if l.match(host) {
+		if l.match(ip) {
 			return ctx.Problem(web.ProblemForbidden)
 		}
 		return next(ctx)
 	}
 }
 
-func splitIP(ip string) (string, error) {
-	if ip[0] == '[' { // ipv6 且带端口
-		if ip[len(ip)-1] == ']' {
-			return ip[1 : len(ip)-1], nil
-		} else if index := strings.LastIndex(ip, "]:"); index >= 0 {
-			return ip[1:index], nil
+func parseEntries(ip []string) ([]*entry, error) {
+	entries := make([]*entry, 0, len(ip))
+	for _, i := range ip {
+		e, err := parseEntry(strings.TrimSpace(i))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseEntry(ip string) (*entry, error) {
+	if v, ok := strings.CutSuffix(ip, "/*"); ok { // IPv4 前缀
+		parts := strings.Split(v, ".")
+		if len(parts) > 4 {
+			return nil, web.NewLocaleError("invalid ip %s", ip)
+		}
+		bits := len(parts) * 8
+		for len(parts) < 4 {
+			parts = append(parts, "0")
+		}
+		p, err := netip.ParsePrefix(strings.Join(parts, ".") + "/" + strconv.Itoa(bits))
+		if err != nil || !p.Addr().Is4() {
+			return nil, web.NewLocaleError("invalid ip %s", ip)
+		}
+		return &entry{value: p.String(), prefixes: []netip.Prefix{p}}, nil
+	}
+
+	if from, to, ok := strings.Cut(ip, "-"); ok { // 范围
+		start, err1 := netip.ParseAddr(strings.TrimSpace(from))
+		end, err2 := netip.ParseAddr(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil {
+			return nil, web.NewLocaleError("invalid ip %s", ip)
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.BitLen() != end.BitLen() || start.Compare(end) > 0 {
+			return nil, web.NewLocaleError("invalid ip %s", ip)
+		}
+		if start == end {
+			return addrEntry(start), nil
+		}
+		return &entry{value: start.String() + "-" + end.String(), prefixes: rangePrefixes(start, end)}, nil
+	}
+
+	if strings.IndexByte(ip, '/') >= 0 { // CIDR
+		p, err := netip.ParsePrefix(ip)
+		if err != nil {
+			return nil, web.NewLocaleError("invalid ip %s", ip)
+		}
+		if p.Addr().Is4In6() {
+			if p.Bits() < 96 { // 匹配时地址会被转换为 IPv4，这样的前缀永远无法匹配。
+				return nil, web.NewLocaleError("invalid ip %s", ip)
+			}
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
 		}
-		return "", web.NewLocaleError("invalid ip %s", ip)
+		p = p.Masked()
+		return &entry{value: p.String(), prefixes: []netip.Prefix{p}}, nil
 	}
 
-	if index := strings.LastIndexByte(ip, ':'); index >= 0 {
-		ip4 := ip[:index]
-		if strings.IndexByte(ip4, ':') >= 0 { // ip4 不可能包含两个 :
-			return "", web.NewLocaleError("invalid ip %s", ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, web.NewLocaleError("invalid ip %s", ip)
+	}
+	return addrEntry(addr.Unmap()), nil
+}
+
+func addrEntry(addr netip.Addr) *entry {
+	return &entry{value: addr.String(), prefixes: []netip.Prefix{netip.PrefixFrom(addr, addr.BitLen())}}
+}
+
+// 将 [start, end] 的范围拆分为最少的前缀
+func rangePrefixes(start, end netip.Addr) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, 5)
+	for {
+		bits := start.BitLen()
+		for bits > 0 {
+			p := netip.PrefixFrom(start, bits-1).Masked()
+			if p.Addr() != start || lastAddr(p).Compare(end) > 0 {
+				break
+			}
+			bits--
+		}
+
+		p := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, p)
+
+		last := lastAddr(p)
+		if last.Compare(end) >= 0 {
+			return prefixes
 		}
-		return ip4, nil
+		start = last.Next()
+	}
+}
+
+// 前缀 p 中的最后一个地址
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
 	}
-	return ip, nil
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
 }
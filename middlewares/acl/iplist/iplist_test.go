@@ -6,6 +6,7 @@ package iplist
 
 import (
 	"net/http"
+	"net/netip"
 	"testing"
 
 	"github.com/issue9/assert/v4"
@@ -14,6 +15,7 @@ import (
 	"github.com/issue9/web/server/servertest"
 
 	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/acl"
 )
 
 var (
@@ -24,23 +26,77 @@ var (
 func TestIPLister_Set(t *testing.T) {
 	a := assert.New(t, false)
 
-	l := NewWhite()
+	l := NewWhite(nil)
 	a.NotNil(l)
 
-	l.Set("192.168.1.1")
+	a.NotError(l.Set("192.168.1.1"))
 	a.Length(l.List(), 1)
 
-	l.Set("192.168.1.1", "192.168.2/*")
-	a.Length(l.List(), 2).Equal(l.List()[1], "192.168.2/*")
+	a.NotError(l.Set("192.168.1.1", "192.168.2/*"))
+	a.Equal(l.List(), []string{"192.168.1.1", "192.168.2.0/24"})
+
+	a.NotError(l.Set("10.1.2.3/8", "2001:DB8::1/32", "::ffff:172.16.0.1", "10.0.0.1-10.0.0.9", "10.0.0.5-10.0.0.5"))
+	a.Equal(l.List(), []string{"192.168.1.1", "192.168.2.0/24", "10.0.0.0/8", "2001:db8::/32", "172.16.0.1", "10.0.0.1-10.0.0.9", "10.0.0.5"})
+
+	// 格式错误，不会添加任何值。
+	a.Error(l.Set("1.1.1.1", "1.1.1"))
+	a.Error(l.Set("10.0.0.9-10.0.0.1"))
+	a.Error(l.Set("10.0.0.1-::1"))
+	a.Error(l.Set("1.2.3.4.5/*"))
+	a.Error(l.Set("10.0.0.0/33"))
+	a.Error(l.Set("::ffff:10.0.0.0/80"))
+	a.Length(l.List(), 7)
+
+	a.NotError(l.Delete("10.1.0.0/8", "2001:db8::1", "10.0.0.1 - 10.0.0.9"))
+	a.Equal(l.List(), []string{"192.168.1.1", "192.168.2.0/24", "2001:db8::/32", "172.16.0.1", "10.0.0.5"})
+	a.Error(l.Delete("1.1.1"))
+
+	l.Reset()
+	a.Empty(l.List()).False(l.(*white).match(netip.MustParseAddr("192.168.1.1")))
+}
+
+func TestIPLister_match(t *testing.T) {
+	a := assert.New(t, false)
+
+	l := NewBlack(nil).(*black)
+	a.NotError(l.Set("10.0.0.0/8", "2001:db8::/32", "192.168.1.10-192.168.1.20", "172.16.0.1"))
+
+	for _, ip := range []string{"10.0.0.1", "10.255.255.255", "2001:db8::1", "192.168.1.10", "192.168.1.16", "192.168.1.20", "172.16.0.1", "::ffff:10.1.1.1"} {
+		a.True(l.match(netip.MustParseAddr(ip)), ip)
+	}
+
+	for _, ip := range []string{"11.0.0.1", "2001:db9::1", "192.168.1.9", "192.168.1.21", "172.16.0.2", "::a00:1"} {
+		a.False(l.match(netip.MustParseAddr(ip)), ip)
+	}
+}
+
+func TestRangePrefixes(t *testing.T) {
+	a := assert.New(t, false)
+
+	ps := rangePrefixes(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.9"))
+	a.Equal(ps, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.2/31"),
+		netip.MustParsePrefix("10.0.0.4/30"),
+		netip.MustParsePrefix("10.0.0.8/31"),
+	})
+
+	ps = rangePrefixes(netip.MustParseAddr("0.0.0.0"), netip.MustParseAddr("255.255.255.255"))
+	a.Equal(ps, []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")})
+
+	ps = rangePrefixes(netip.MustParseAddr("2001:db8::"), netip.MustParseAddr("2001:db8::ffff"))
+	a.Equal(ps, []netip.Prefix{netip.MustParsePrefix("2001:db8::/112")})
 }
 
 func TestWhite_Middleware(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
 
-	l := NewWhite()
+	r, err := acl.NewResolver([]string{"127.0.0.0/8", "::1"}, header.XForwardedFor)
+	a.NotError(err)
+	l := NewWhite(r)
 	a.NotNil(l)
-	l.Set("192.168.1.1")
+	a.NotError(l.Set("192.168.1.1"))
 
 	router := s.Routers().New("def", nil)
 	router.Use(l)
@@ -61,7 +117,7 @@ func TestWhite_Middleware(t *testing.T) {
 		Do(nil).
 		Status(http.StatusForbidden)
 
-	l.Set("192.168.1.2", "192.168.1.1", "192.168.2/*")
+	a.NotError(l.Set("192.168.1.2", "192.168.1.1", "192.168.2/*"))
 
 	servertest.Get(a, "http://localhost:8080/test").
 		Header(header.XForwardedFor, "192.168.1.1").
@@ -83,9 +139,11 @@ func TestBlack_Middleware(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
 
-	l := NewBlack()
+	r, err := acl.NewResolver([]string{"127.0.0.0/8", "::1"}, header.XForwardedFor)
+	a.NotError(err)
+	l := NewBlack(r)
 	a.NotNil(l)
-	l.Set("192.168.1.1")
+	a.NotError(l.Set("192.168.1.1"))
 
 	router := s.Routers().New("def", nil)
 	router.Use(l)
@@ -106,7 +164,7 @@ func TestBlack_Middleware(t *testing.T) {
 		Do(nil).
 		Status(http.StatusCreated)
 
-	l.Set("192.168.1.2", "192.168.1.1", "192.168.2/*")
+	a.NotError(l.Set("192.168.1.2", "192.168.1.1", "192.168.2/*"))
 
 	servertest.Get(a, "http://localhost:8080/test").
 		Header(header.XForwardedFor, "192.168.1.1").
@@ -124,18 +182,18 @@ func TestBlack_Middleware(t *testing.T) {
 		Status(http.StatusForbidden)
 }
 
-func TestSplitIP(t *testing.T) {
+func TestIPLister_Replace(t *testing.T) {
 	a := assert.New(t, false)
 
-	ip, err := splitIP("192.168.1.1")
-	a.NotError(err).Equal(ip, "192.168.1.1")
+	l := NewWhite(nil)
+	a.NotError(l.Set("192.168.1.1", "10.0.0.0/8"))
 
-	ip, err = splitIP("192.168.1.1:8080")
-	a.NotError(err).Equal(ip, "192.168.1.1")
+	a.NotError(l.Replace("172.16.0.0/12", "172.16.0.0/12", "192.168.1.1"))
+	a.Equal(l.List(), []string{"172.16.0.0/12", "192.168.1.1"})
 
-	ip, err = splitIP("[FC00:0:130F::9C0:876A:130B]:8080")
-	a.NotError(err).Equal(ip, "FC00:0:130F::9C0:876A:130B")
+	a.Error(l.Replace("10.0.0.1", "invalid"))
+	a.Equal(l.List(), []string{"172.16.0.0/12", "192.168.1.1"})
 
-	ip, err = splitIP("[FC00:0:130F::9C0:876A:130B]")
-	a.NotError(err).Equal(ip, "FC00:0:130F::9C0:876A:130B")
+	a.NotError(l.Replace())
+	a.Empty(l.List())
 }
@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package iplist
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+)
+
+var (
+	// 未指定 client 时，[NewURLSource] 采用的客户端
+	defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+	// 远程地址返回内容的最大长度
+	maxURLSourceSize int64 = 10 << 20
+)
+
+// Source 名单的数据源
+type Source interface {
+	// Load 加载完整的名单
+	//
+	// 返回值的格式与 [IPLister.Set] 的参数相同。
+	Load() ([]string, error)
+}
+
+// SourceFunc 将函数转换为 [Source] 接口
+type SourceFunc func() ([]string, error)
+
+type fileSource struct {
+	fsys fs.FS
+	name string
+
+	mux     sync.Mutex
+	modTime time.Time
+	size    int64
+	list    []string
+}
+
+type urlSource struct {
+	client *http.Client
+	url    string
+
+	mux          sync.Mutex
+	etag         string
+	lastModified string
+	list         []string
+}
+
+// CacheSource 以 [web.Cache] 作为名单的数据源
+//
+// 多个节点使用相同的缓存服务时，可以通过 [CacheSource.Save] 在任意节点修改名单，
+// 再由 [Sync] 同步到各个节点。
+type CacheSource struct {
+	c   web.Cache
+	key string
+}
+
+func (f SourceFunc) Load() ([]string, error) { return f() }
+
+// NewFileSource 从文件加载名单
+//
+// 文件中每行表示一条记录，格式与 [IPLister.Set] 的参数相同，
+// 空行以及以 # 开头的注释行将被忽略，行内 # 之后的内容也被当作注释。
+//
+// 仅在文件的修改时间或大小发生变化时才会重新读取文件内容。
+func NewFileSource(fsys fs.FS, name string) Source {
+	return &fileSource{fsys: fsys, name: name}
+}
+
+// NewURLSource 从远程地址加载名单
+//
+// 远程地址返回的内容格式与 [NewFileSource] 相同，比如各类威胁情报提供的黑名单。
+// 如果服务端支持 ETag 或是 Last-Modified，在内容未改变时不会重复下载。
+//
+// client 如果为空，则采用超时时间为 30 秒的客户端；
+// 返回的内容不能超过 10M，否则返回错误。
+func NewURLSource(client *http.Client, url string) Source {
+	if client == nil {
+		client = defaultClient
+	}
+	return &urlSource{client: client, url: url}
+}
+
+// NewCacheSource 声明 [CacheSource] 对象
+//
+// key 为名单在缓存中的名称。
+func NewCacheSource(c web.Cache, key string) *CacheSource {
+	return &CacheSource{c: c, key: key}
+}
+
+func (s *fileSource) Load() ([]string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stat, err := fs.Stat(s.fsys, s.name)
+	if err != nil {
+		return nil, err
+	}
+	if s.list != nil && stat.ModTime().Equal(s.modTime) && stat.Size() == s.size {
+		return s.list, nil
+	}
+
+	f, err := s.fsys.Open(s.name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list, err := parseLines(f)
+	if err != nil {
+		return nil, err
+	}
+
+	s.list, s.modTime, s.size = list, stat.ModTime(), stat.Size()
+	return list, nil
+}
+
+func (s *urlSource) Load() ([]string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.list != nil {
+		if s.etag != "" {
+			req.Header.Set("If-None-Match", s.etag)
+		}
+		if s.lastModified != "" {
+			req.Header.Set("If-Modified-Since", s.lastModified)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.list != nil:
+		return s.list, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, web.NewLocaleError("fetch %s failed with status %d", s.url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxURLSourceSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxURLSourceSize {
+		return nil, web.NewLocaleError("the content of %s exceeds %d bytes", s.url, maxURLSourceSize)
+	}
+
+	list, err := parseLines(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	s.list = list
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	return list, nil
+}
+
+func (s *CacheSource) Load() ([]string, error) {
+	var list []string
+	switch err := s.c.Get(s.key, &list); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		return []string{}, nil
+	case err != nil:
+		return nil, err
+	}
+	return list, nil
+}
+
+// Save 将 ip 作为完整的名单写入缓存
+//
+// ip 的格式与 [IPLister.Set] 相同，写入的是规范化之后的值。
+func (s *CacheSource) Save(ip ...string) error {
+	entries, err := parseEntries(ip)
+	if err != nil {
+		return err
+	}
+
+	list := make([]string, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e.value)
+	}
+	return s.c.Set(s.key, list, cache.Forever)
+}
+
+// 按行读取名单
+func parseLines(r io.Reader) ([]string, error) {
+	list := make([]string, 0, 100)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			list = append(list, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+type syncer struct {
+	l    IPLister
+	src  Source
+	mux  sync.Mutex
+	last []string
+}
+
+// Sync 从 src 加载名单并替换 l 的内容
+//
+// 会立即加载一次，如果出错则返回错误。
+// interval 大于 0 时，会通过 [web.Server.Services] 每隔 interval 重新加载，
+// 仅在内容有变化时才替换名单，加载出错时会保留原有的名单，错误由服务记录到日志。
+// 名单的替换通过 [IPLister.Replace] 完成，不会影响正在进行的请求。
+//
+// 同一个 [IPLister] 不应该同时与多个 [Source] 同步，后加载的会覆盖之前的内容。
+func Sync(s web.Server, l IPLister, src Source, interval time.Duration) error {
+	sy := &syncer{l: l, src: src}
+	if err := sy.sync(s.Now()); err != nil {
+		return err
+	}
+
+	if interval > 0 {
+		s.Services().AddTicker(web.Phrase("sync ip list"), sy.sync, interval, false, true)
+	}
+	return nil
+}
+
+func (sy *syncer) sync(time.Time) error {
+	sy.mux.Lock()
+	defer sy.mux.Unlock()
+
+	list, err := sy.src.Load()
+	if err != nil {
+		return err
+	}
+
+	if sy.last != nil && slices.Equal(list, sy.last) {
+		return nil
+	}
+
+	if err := sy.l.Replace(list...); err != nil {
+		return err
+	}
+	sy.last = slices.Clone(list)
+	return nil
+}
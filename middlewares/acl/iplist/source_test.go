@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package iplist
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+var _ Source = SourceFunc(nil)
+
+func TestParseLines(t *testing.T) {
+	a := assert.New(t, false)
+
+	list, err := parseLines(strings.NewReader(`
+# comment
+192.168.1.1
+  10.0.0.0/8 # inline comment
+
+2001:db8::/32
+`))
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8", "2001:db8::/32"})
+
+	list, err = parseLines(strings.NewReader(""))
+	a.NotError(err).NotNil(list).Empty(list)
+}
+
+func TestFileSource(t *testing.T) {
+	a := assert.New(t, false)
+
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"list.txt": &fstest.MapFile{Data: []byte("192.168.1.1\n10.0.0.0/8"), ModTime: now},
+	}
+	src := NewFileSource(fsys, "list.txt")
+
+	list, err := src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8"})
+
+	// 修改时间和大小未变，不会重新读取。
+	fsys["list.txt"].Data = []byte("192.168.1.2\n10.0.0.0/8")
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8"})
+
+	fsys["list.txt"].ModTime = now.Add(time.Second)
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.2", "10.0.0.0/8"})
+
+	_, err = NewFileSource(fsys, "not-exists.txt").Load()
+	a.Error(err)
+}
+
+func TestURLSource(t *testing.T) {
+	a := assert.New(t, false)
+
+	var body atomic.Value
+	body.Store("192.168.1.1\n")
+	var fetched atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		data := body.Load().(string)
+		etag := `"` + strings.TrimSpace(data) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetched.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	src := NewURLSource(nil, srv.URL+"/list.txt")
+	list, err := src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1"}).Equal(fetched.Load(), 1)
+
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1"}).Equal(fetched.Load(), 1)
+
+	body.Store("192.168.1.2\n")
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.2"}).Equal(fetched.Load(), 2)
+
+	_, err = NewURLSource(srv.Client(), srv.URL+"/not-exists").Load()
+	a.Error(err)
+
+	// 未指定 client 时带超时时间
+	a.True(src.(*urlSource).client.Timeout > 0)
+
+	// 超过大小限制
+	size := maxURLSourceSize
+	maxURLSourceSize = 5
+	defer func() { maxURLSourceSize = size }()
+	body.Store("192.168.1.3\n")
+	_, err = src.Load()
+	a.Error(err).Equal(fetched.Load(), 3)
+}
+
+func TestCacheSource(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	src := NewCacheSource(web.NewCache("iplist_", s.Cache()), "black")
+	list, err := src.Load()
+	a.NotError(err).NotNil(list).Empty(list)
+
+	a.NotError(src.Save("192.168.1.1", "10.1.2.3/8"))
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8"})
+
+	// 其它节点
+	list, err = NewCacheSource(web.NewCache("iplist_", s.Cache()), "black").Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8"})
+
+	a.Error(src.Save("192.168.1"))
+	list, err = src.Load()
+	a.NotError(err).Equal(list, []string{"192.168.1.1", "10.0.0.0/8"})
+}
+
+func TestSync(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	var list []string
+	var loadErr error
+	src := SourceFunc(func() ([]string, error) { return list, loadErr })
+
+	l := NewBlack(nil)
+	a.NotError(l.Set("172.16.0.1"))
+	list = []string{"192.168.1.1"}
+	a.NotError(Sync(s, l, src, 0))
+	a.Equal(l.List(), []string{"192.168.1.1"})
+
+	loadErr = errors.New("load error")
+	a.ErrorIs(Sync(s, l, src, 0), loadErr)
+	a.Equal(l.List(), []string{"192.168.1.1"})
+
+	// 周期性同步
+	loadErr = nil
+	sy := &syncer{l: l, src: src}
+	list = []string{"10.0.0.0/8"}
+	a.NotError(sy.sync(s.Now()))
+	a.Equal(l.List(), []string{"10.0.0.0/8"}).True(l.(*black).match(netip.MustParseAddr("10.1.1.1")))
+
+	// 出错时保留原有名单
+	loadErr = errors.New("load error")
+	a.ErrorIs(sy.sync(s.Now()), loadErr)
+	a.Equal(l.List(), []string{"10.0.0.0/8"})
+
+	loadErr = nil
+	list = []string{"10.0.0.0/8", "invalid"}
+	a.Error(sy.sync(s.Now()))
+	a.Equal(l.List(), []string{"10.0.0.0/8"})
+
+	list = []string{}
+	a.NotError(sy.sync(s.Now()))
+	a.Empty(l.List())
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package iplist
+
+import "net/netip"
+
+// 以二进制位为节点的前缀树
+//
+// 构建完成之后只读，修改时需要重新构建，所以可以在多个 goroutine 中同时查询。
+type trie struct {
+	v4, v6 *node
+}
+
+type node struct {
+	children [2]*node
+	leaf     bool // 从根节点到当前节点的路径是一个完整的前缀
+}
+
+func newTrie(prefixes ...netip.Prefix) *trie {
+	t := &trie{v4: &node{}, v6: &node{}}
+	for _, p := range prefixes {
+		t.insert(p)
+	}
+	return t
+}
+
+func (t *trie) root(addr netip.Addr) (*node, []byte) {
+	if addr.Is4() {
+		a := addr.As4()
+		return t.v4, a[:]
+	}
+	a := addr.As16()
+	return t.v6, a[:]
+}
+
+func (t *trie) insert(p netip.Prefix) {
+	n, bits := t.root(p.Addr())
+	for i := range p.Bits() {
+		if n.leaf { // 已经包含了更短的前缀
+			return
+		}
+
+		b := bit(bits, i)
+		if n.children[b] == nil {
+			n.children[b] = &node{}
+		}
+		n = n.children[b]
+	}
+	n.leaf = true
+	n.children = [2]*node{} // 更长的前缀已经没有意义
+}
+
+// 是否有前缀包含了 addr
+func (t *trie) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	n, bits := t.root(addr)
+	for i := range len(bits) * 8 {
+		if n.leaf {
+			return true
+		}
+
+		if n = n.children[bit(bits, i)]; n == nil {
+			return false
+		}
+	}
+	return n.leaf
+}
+
+func bit(b []byte, i int) int { return int(b[i/8]>>(7-i%8)) & 1 }
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package iplist
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestTrie(t *testing.T) {
+	a := assert.New(t, false)
+
+	tr := newTrie()
+	a.False(tr.contains(netip.MustParseAddr("1.1.1.1"))).
+		False(tr.contains(netip.MustParseAddr("::1")))
+
+	tr = newTrie(
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("10.0.0.0/8"), // 包含了 10.1.0.0/16
+		netip.MustParsePrefix("10.2.0.0/16"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	)
+	a.True(tr.contains(netip.MustParseAddr("10.1.1.1"))).
+		True(tr.contains(netip.MustParseAddr("10.3.1.1"))).
+		True(tr.contains(netip.MustParseAddr("192.168.1.1"))).
+		False(tr.contains(netip.MustParseAddr("192.168.1.2"))).
+		True(tr.contains(netip.MustParseAddr("2001:db8:1::1"))).
+		False(tr.contains(netip.MustParseAddr("2001:db9::1"))).
+		True(tr.contains(netip.MustParseAddr("::ffff:10.1.1.1"))) // IPv4 映射地址
+
+	// 匹配所有
+	tr = newTrie(netip.MustParsePrefix("0.0.0.0/0"))
+	a.True(tr.contains(netip.MustParseAddr("1.2.3.4"))).
+		False(tr.contains(netip.MustParseAddr("::1")))
+}
+
+func BenchmarkTrie_contains(b *testing.B) {
+	prefixes := make([]netip.Prefix, 0, 1000)
+	for i := range 1000 {
+		prefixes = append(prefixes, netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(i >> 8), byte(i), 0, 0}), 16))
+	}
+	tr := newTrie(prefixes...)
+	addr := netip.MustParseAddr("3.231.1.1")
+
+	for b.Loop() {
+		tr.contains(addr)
+	}
+}
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+)
+
+type tokenBucket struct {
+	*locker
+	c        web.Cache
+	capacity uint64
+	rate     time.Duration
+	ttl      time.Duration
+}
+
+// 保存在缓存中的令牌桶状态
+type bucket struct {
+	Tokens uint64
+	Last   int64 // 最后一次发放令牌的时间，单位为纳秒。
+}
+
+type gcra struct {
+	*locker
+	c     web.Cache
+	burst uint64
+	rate  time.Duration
+}
+
+// NewTokenBucket 令牌桶算法
+//
+// capacity 桶的容量；
+// rate 发放令牌的时间间隔；
+func NewTokenBucket(c web.Cache, capacity uint64, rate time.Duration) Limiter {
+	ttl := rate * time.Duration(capacity) // 填满令牌桶的时候，当桶是满的时候，不需要缓存这些数据。
+	if ttl < time.Second {
+		panic("capacity*rate 必须大于 1 秒")
+	}
+
+	return &tokenBucket{locker: newLocker(), c: c, capacity: capacity, rate: rate, ttl: ttl}
+}
+
+// NewGCRA 通用信元速率算法
+//
+// 与令牌桶的效果相同，但是仅需要在缓存中保存一个时间值。
+//
+// burst 允许突发的请求数量；
+// rate 两次请求之间的平均时间间隔；
+func NewGCRA(c web.Cache, burst uint64, rate time.Duration) Limiter {
+	if burst == 0 || rate <= 0 {
+		panic("参数 burst 和 rate 必须大于 0")
+	}
+	return &gcra{locker: newLocker(), c: c, burst: burst, rate: rate}
+}
+
+func (b *tokenBucket) Allow(key string, now time.Time) (*State, error) {
+	key += "_bucket"
+	unlock, err := b.lock(b.c, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var v bucket
+	switch err := b.c.Get(key, &v); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		v = bucket{Tokens: b.capacity, Last: now.UnixNano()}
+	case err != nil:
+		return nil, err
+	}
+
+	// 根据最后一次发放令牌的时间，补发这段时间内需要的令牌数量。
+	last := time.Unix(0, v.Last)
+	if incr := uint64(now.Sub(last) / b.rate); incr > 0 {
+		last = last.Add(time.Duration(incr) * b.rate)
+		v.Tokens = min(b.capacity, v.Tokens+incr)
+	}
+	if v.Tokens == b.capacity {
+		last = now
+	}
+
+	s := &State{Limit: b.capacity, Window: b.ttl}
+	if v.Tokens > 0 {
+		v.Tokens--
+		s.Allowed = true
+	} else {
+		s.RetryAfter = b.rate - now.Sub(last)
+	}
+	s.Remaining = v.Tokens
+	s.Reset = time.Duration(b.capacity-v.Tokens)*b.rate - now.Sub(last)
+
+	v.Last = last.UnixNano()
+	if err := b.c.Set(key, v, b.ttl); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (b *tokenBucket) Cancel(key string, _ time.Time) error {
+	key += "_bucket"
+	unlock, err := b.lock(b.c, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var v bucket
+	switch err := b.c.Get(key, &v); {
+	case errors.Is(err, cache.ErrCacheMiss()): // 已经过期，即令牌桶已满。
+		return nil
+	case err != nil:
+		return err
+	}
+
+	v.Tokens = min(b.capacity, v.Tokens+1)
+	return b.c.Set(key, v, b.ttl)
+}
+
+func (g *gcra) Allow(key string, now time.Time) (*State, error) {
+	key += "_gcra"
+	unlock, err := g.lock(g.c, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// 理论上的下一次请求时间
+	var tat int64
+	switch err := g.c.Get(key, &tat); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		tat = now.UnixNano()
+	case err != nil:
+		return nil, err
+	}
+	t := now
+	if v := time.Unix(0, tat); v.After(now) {
+		t = v
+	}
+
+	next := t.Add(g.rate)
+	allowAt := next.Add(-time.Duration(g.burst) * g.rate)
+	s := &State{Limit: g.burst, Window: time.Duration(g.burst) * g.rate}
+	if now.Before(allowAt) {
+		s.RetryAfter = allowAt.Sub(now)
+		s.Reset = t.Sub(now)
+		return s, nil
+	}
+
+	s.Allowed = true
+	s.Remaining = uint64(now.Sub(allowAt) / g.rate)
+	s.Reset = next.Sub(now)
+	if err := g.c.Set(key, next.UnixNano(), s.Reset); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (g *gcra) Cancel(key string, now time.Time) error {
+	key += "_gcra"
+	unlock, err := g.lock(g.c, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var tat int64
+	switch err := g.c.Get(key, &tat); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	t := time.Unix(0, tat).Add(-g.rate)
+	if !t.After(now) {
+		return g.c.Delete(key)
+	}
+	return g.c.Set(key, t.UnixNano(), t.Sub(now))
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+)
+
+// IETF 草案 [RateLimit header fields for HTTP] 中定义的报头
+//
+// [RateLimit header fields for HTTP]: https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+const (
+	RateLimit       = "RateLimit"
+	RateLimitPolicy = "RateLimit-Policy"
+)
+
+// Limit 请求被拒绝时附加在 [web.Problem] 中的限流信息
+type Limit struct {
+	Policy     string `json:"policy,omitempty" xml:"policy,omitempty" cbor:"policy,omitempty" yaml:"policy,omitempty" comment:"rate limit policy"`
+	Limit      uint64 `json:"limit" xml:"limit" cbor:"limit" yaml:"limit" comment:"rate limit quota"`
+	Window     int64  `json:"window" xml:"window" cbor:"window" yaml:"window" comment:"rate limit window in seconds"`
+	Remaining  uint64 `json:"remaining" xml:"remaining" cbor:"remaining" yaml:"remaining" comment:"rate limit remaining"`
+	Reset      int64  `json:"reset" xml:"reset" cbor:"reset" yaml:"reset" comment:"rate limit reset in seconds"`
+	RetryAfter int64  `json:"retryAfter" xml:"retryAfter" cbor:"retryAfter" yaml:"retryAfter" comment:"retry after seconds"`
+}
+
+// 输出限流相关的报头
+//
+// policy 不为空时，同时输出 IETF 草案中的 RateLimit 和 RateLimit-Policy 报头。
+func setHeader(ctx *web.Context, s *State, policy string) {
+	h := ctx.Header()
+	h.Set(header.XRateLimitLimit, strconv.FormatUint(s.Limit, 10))
+	h.Set(header.XRateLimitRemaining, strconv.FormatUint(s.Remaining, 10))
+	h.Set(header.XRateLimitReset, strconv.FormatInt(ctx.Begin().Add(s.Reset).Unix(), 10))
+
+	if policy != "" {
+		name := strconv.Quote(policy)
+		h.Set(RateLimitPolicy, name+";q="+strconv.FormatUint(s.Limit, 10)+";w="+strconv.FormatInt(seconds(s.Window), 10))
+		h.Set(RateLimit, name+";r="+strconv.FormatUint(s.Remaining, 10)+";t="+strconv.FormatInt(seconds(s.Reset), 10))
+	}
+
+	if !s.Allowed {
+		h.Set(header.RetryAfter, strconv.FormatInt(seconds(s.RetryAfter), 10))
+	}
+}
+
+// 被拒绝时的返回对象
+func tooManyRequests(ctx *web.Context, s *State, policy string) web.Responser {
+	return ctx.Problem(web.ProblemTooManyRequests).WithExtensions(&Limit{
+		Policy:     policy,
+		Limit:      s.Limit,
+		Window:     seconds(s.Window),
+		Remaining:  s.Remaining,
+		Reset:      seconds(s.Reset),
+		RetryAfter: seconds(s.RetryAfter),
+	})
+}
+
+// 向上取整的秒数
+func seconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// 为 o 添加限流的文档
+//
+// 为 o 中已有的返回对象添加限流的报头，并添加 429 的返回对象。
+func operation(o *openapi.Operation, ietf bool) *openapi.Operation {
+	headers := []*openapi.Parameter{
+		{Name: header.XRateLimitLimit, Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+		{Name: header.XRateLimitRemaining, Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+		{Name: header.XRateLimitReset, Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+	}
+	if ietf {
+		headers = append(headers,
+			&openapi.Parameter{Name: RateLimitPolicy, Schema: &openapi.Schema{Type: openapi.TypeString}},
+			&openapi.Parameter{Name: RateLimit, Schema: &openapi.Schema{Type: openapi.TypeString}},
+		)
+	}
+
+	for _, resp := range o.Responses {
+		if resp.Ref == nil { // 引用的对象由 components 中的定义决定
+			resp.Headers = append(resp.Headers, headers...)
+		}
+	}
+
+	status := strconv.Itoa(http.StatusTooManyRequests)
+	return o.Response(status, &web.Problem{Extensions: &Limit{}}, web.Phrase("too many requests"), func(r *openapi.Response) {
+		r.Problem = true
+		r.Headers = slices.Concat(headers, []*openapi.Parameter{
+			{Name: header.RetryAfter, Required: true, Schema: &openapi.Schema{Type: openapi.TypeInteger}},
+		})
+	})
+}
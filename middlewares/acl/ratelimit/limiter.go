@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"errors"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+)
+
+const (
+	lockTTL     = time.Second            // 锁的最长持有时间，防止持有者崩溃之后无法释放。
+	lockTimeout = 100 * time.Millisecond // 获取锁的最长等待时间
+	lockShards  = 64
+)
+
+// 进程内的锁
+//
+// 每个 [Limiter] 实例拥有各自的锁，不同实例之间不会相互阻塞。
+type locker struct {
+	shards [lockShards]sync.Mutex
+	seed   maphash.Seed
+}
+
+// Limiter 限流算法的接口
+//
+// 所有的数据均保存在 [web.Cache] 中，多个节点共用同一个缓存时，限制对所有节点同时生效。
+// 在同一实例内，对同一 key 的操作由互斥锁保证原子性；在多个实例或节点之间，
+// 依赖于缓存的计数器是否为原子操作，比如 redis 和 memcached 的实现都是原子操作，
+// 而内存缓存的计数器并不是，所以内存缓存不应该由多个实例共用同一 key。
+type Limiter interface {
+	// Allow 为 key 消耗一次配额
+	//
+	// now 为请求的时间。
+	Allow(key string, now time.Time) (*State, error)
+
+	// Cancel 返还由 Allow 在 now 时为 key 消耗的配额
+	//
+	// 当多个限制同时生效时，如果之后的限制拒绝了请求，之前已经消耗的配额需要返还。
+	Cancel(key string, now time.Time) error
+}
+
+// State 某次请求之后的配额状态
+type State struct {
+	Allowed   bool          // 是否允许当前请求
+	Limit     uint64        // 配额的上限
+	Window    time.Duration // 配额的统计周期，即配额从零恢复到 Limit 所需的时间。
+	Remaining uint64        // 剩余的配额
+	Reset     time.Duration // 距离配额完全恢复的时间
+
+	// 距离下一次可以请求的时间
+	//
+	// 仅在 Allowed 为 false 时有意义。
+	RetryAfter time.Duration
+}
+
+func newLocker() *locker { return &locker{seed: maphash.MakeSeed()} }
+
+// 在进程内锁定 key
+func (l *locker) localLock(key string) (unlock func()) {
+	m := &l.shards[maphash.String(l.seed, key)%lockShards]
+	m.Lock()
+	return m.Unlock
+}
+
+// 锁定 key
+//
+// 除了进程内的互斥锁，还会通过缓存中的计数器锁定 key，
+// 用于需要先读取再写入的算法。
+//
+// 计数器只增不减，值为 1 表示获得了锁，否则表示锁已经被其它节点持有，
+// 只有持有者才能通过删除计数器释放锁。不能通过减一撤销失败的尝试，
+// 因为在此期间锁可能已经被释放并由其它节点重新创建，减一将作用于新的计数器，使第三者也能获得锁。
+func (l *locker) lock(c web.Cache, key string) (unlock func(), err error) {
+	unlockLocal := l.localLock(key)
+
+	key += "_lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		_, setter, _, err := c.Counter(key, lockTTL)
+		if err != nil {
+			unlockLocal()
+			return nil, err
+		}
+
+		n, err := setter(1)
+		switch {
+		case err == nil && n == 1:
+			return func() {
+				_ = c.Delete(key) // 即使出错，也会在 lockTTL 之后自动释放。
+				unlockLocal()
+			}, nil
+		case err != nil && !errors.Is(err, cache.ErrCacheMiss()): // 锁恰好被释放时，计数器可能已经不存在。
+			unlockLocal()
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			unlockLocal()
+			return nil, web.NewLocaleError("acquire the lock of %s timeout", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 计数器加上 n，返回相加之后的值。
+func incr(c web.Cache, key string, n int, ttl time.Duration) (uint64, error) {
+	_, setter, _, err := c.Counter(key, ttl)
+	if err != nil {
+		return 0, err
+	}
+	return setter(n)
+}
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func newCache(a *assert.Assertion) web.Cache {
+	return cache.Prefix(testserver.New(a).Cache(), "rl-")
+}
+
+// 计数器为原子操作的缓存
+//
+// 内存缓存的计数器仅在同一个 [cache.SetCounterFunc] 内是原子操作，
+// 通过此对象模拟 redis 等由多个节点共用的缓存。
+type atomicCache struct {
+	web.Cache
+	mux  sync.Mutex
+	busy func() // 计数器的值首次大于 1 时调用
+}
+
+func (c *atomicCache) Counter(key string, ttl time.Duration) (uint64, cache.SetCounterFunc, bool, error) {
+	n, setter, exist, err := c.Cache.Counter(key, ttl)
+	if err != nil {
+		return n, setter, exist, err
+	}
+
+	return n, func(n int) (uint64, error) {
+		c.mux.Lock()
+		v, err := setter(n)
+		c.mux.Unlock()
+
+		if f := c.busy; f != nil && err == nil && v > 1 {
+			c.busy = nil
+			f()
+		}
+		return v, err
+	}, exist, nil
+}
+
+func (c *atomicCache) Delete(key string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.Cache.Delete(key)
+}
+
+// 并发请求 n 次，返回通过的次数。
+func allowN(a *assert.Assertion, l Limiter, key string, now time.Time, n int) uint64 {
+	var allowed atomic.Uint64
+	wg := &sync.WaitGroup{}
+	for range n {
+		wg.Go(func() {
+			s, err := l.Allow(key, now)
+			a.NotError(err).NotNil(s)
+			if s.Allowed {
+				allowed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	return allowed.Load()
+}
+
+func TestLimiter_concurrency(t *testing.T) {
+	a := assert.New(t, false)
+	now := time.Now()
+
+	limiters := map[string]func(web.Cache) Limiter{
+		"bucket":  func(c web.Cache) Limiter { return NewTokenBucket(c, 20, time.Second) },
+		"gcra":    func(c web.Cache) Limiter { return NewGCRA(c, 20, time.Second) },
+		"fixed":   func(c web.Cache) Limiter { return NewFixedWindow(c, 20, time.Hour) },
+		"sliding": func(c web.Cache) Limiter { return NewSlidingWindow(c, 20, time.Hour) },
+		"log":     func(c web.Cache) Limiter { return NewSlidingLog(c, 20, time.Hour) },
+	}
+
+	for name, f := range limiters {
+		// 同一实例内的并发，多个节点参考 TestLimiter_nodes。
+		l := f(newCache(a))
+		a.Equal(allowN(a, l, "1", now, 200), 20, name)
+
+		// 不同的 key 互不影响
+		a.Equal(allowN(a, l, "2", now, 30), 20, name)
+	}
+}
+
+func TestLocker_lock(t *testing.T) {
+	a := assert.New(t, false)
+	c := &atomicCache{Cache: newCache(a)}
+	l1, l2, l3 := newLocker(), newLocker(), newLocker()
+
+	unlock1, err := l1.lock(c, "k")
+	a.NotError(err).NotNil(unlock1)
+
+	// l2 获取锁失败之后，l1 释放了锁，l3 重新获得了锁。
+	var unlock3 func()
+	c.busy = func() {
+		unlock1()
+		unlock3, err = l3.lock(c, "k")
+		a.NotError(err).NotNil(unlock3)
+	}
+	unlock2, err := l2.lock(c, "k")
+	a.Error(err).Nil(unlock2).NotNil(unlock3)
+
+	unlock3()
+	unlock2, err = l2.lock(c, "k")
+	a.NotError(err).NotNil(unlock2)
+	unlock2()
+}
+
+func TestLimiter_nodes(t *testing.T) {
+	a := assert.New(t, false)
+	now := time.Now()
+
+	limiters := map[string]func(web.Cache) Limiter{
+		"bucket":  func(c web.Cache) Limiter { return NewTokenBucket(c, 20, time.Second) },
+		"gcra":    func(c web.Cache) Limiter { return NewGCRA(c, 20, time.Second) },
+		"fixed":   func(c web.Cache) Limiter { return NewFixedWindow(c, 20, time.Hour) },
+		"sliding": func(c web.Cache) Limiter { return NewSlidingWindow(c, 20, time.Hour) },
+		"log":     func(c web.Cache) Limiter { return NewSlidingLog(c, 20, time.Hour) },
+	}
+
+	for name, f := range limiters {
+		// 两个实例共用同一个缓存，相当于两个节点。
+		c := &atomicCache{Cache: newCache(a)}
+		l1, l2 := f(c), f(c)
+
+		var allowed atomic.Uint64
+		wg := &sync.WaitGroup{}
+		for i := range 200 {
+			l := l1
+			if i%2 == 0 {
+				l = l2
+			}
+			wg.Go(func() {
+				s, err := l.Allow("1", now)
+				a.NotError(err, name).NotNil(s, name)
+				if s.Allowed {
+					allowed.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		a.Equal(allowed.Load(), 20, name)
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	a := assert.New(t, false)
+	now := time.Now()
+
+	limiters := map[string]Limiter{
+		"bucket":  NewTokenBucket(newCache(a), 2, time.Second),
+		"gcra":    NewGCRA(newCache(a), 2, time.Second),
+		"fixed":   NewFixedWindow(newCache(a), 2, time.Hour),
+		"sliding": NewSlidingWindow(newCache(a), 2, time.Hour),
+		"log":     NewSlidingLog(newCache(a), 2, time.Hour),
+	}
+
+	for name, l := range limiters {
+		a.NotError(l.Cancel("1", now), name) // 不存在的 key
+
+		a.Equal(allowN(a, l, "1", now, 2), 2, name)
+		a.NotError(l.Cancel("1", now), name)
+
+		s, err := l.Allow("1", now)
+		a.NotError(err, name).True(s.Allowed, name)
+		s, err = l.Allow("1", now)
+		a.NotError(err, name).False(s.Allowed, name)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	a := assert.New(t, false)
+	l := NewTokenBucket(newCache(a), 2, time.Second)
+	now := time.Now()
+
+	s, err := l.Allow("1", now)
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 1, Reset: time.Second})
+
+	s, err = l.Allow("1", now.Add(100*time.Millisecond))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 1900 * time.Millisecond})
+
+	s, err = l.Allow("1", now.Add(200*time.Millisecond))
+	a.NotError(err).Equal(s, &State{Allowed: false, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 1800 * time.Millisecond, RetryAfter: 800 * time.Millisecond})
+
+	// 补发了一个令牌
+	s, err = l.Allow("1", now.Add(1100*time.Millisecond))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 1900 * time.Millisecond})
+
+	// 令牌已满
+	s, err = l.Allow("1", now.Add(5*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 1, Reset: time.Second})
+
+	a.PanicString(func() {
+		NewTokenBucket(newCache(a), 2, time.Millisecond)
+	}, "capacity*rate 必须大于 1 秒")
+}
+
+func TestGCRA(t *testing.T) {
+	a := assert.New(t, false)
+	l := NewGCRA(newCache(a), 2, time.Second)
+	now := time.Now()
+
+	s, err := l.Allow("1", now)
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 1, Reset: time.Second})
+
+	s, err = l.Allow("1", now.Add(100*time.Millisecond))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 1900 * time.Millisecond})
+
+	s, err = l.Allow("1", now.Add(200*time.Millisecond))
+	a.NotError(err).Equal(s, &State{Allowed: false, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 1800 * time.Millisecond, RetryAfter: 800 * time.Millisecond})
+
+	s, err = l.Allow("1", now.Add(time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 0, Reset: 2 * time.Second})
+
+	s, err = l.Allow("1", now.Add(10*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: 2 * time.Second, Remaining: 1, Reset: time.Second})
+
+	a.Panic(func() {
+		NewGCRA(newCache(a), 0, time.Second)
+	})
+}
+
+func TestFixedWindow(t *testing.T) {
+	a := assert.New(t, false)
+	l := NewFixedWindow(newCache(a), 2, time.Minute)
+	start := time.Now().Truncate(time.Minute)
+
+	s, err := l.Allow("1", start.Add(10*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 1, Reset: 50 * time.Second})
+
+	s, err = l.Allow("1", start.Add(20*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 0, Reset: 40 * time.Second})
+
+	s, err = l.Allow("1", start.Add(30*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: false, Limit: 2, Window: time.Minute, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 30 * time.Second})
+
+	// 下一个窗口
+	s, err = l.Allow("1", start.Add(time.Minute))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 1, Reset: time.Minute})
+
+	a.Panic(func() {
+		NewFixedWindow(newCache(a), 2, 0)
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	a := assert.New(t, false)
+	l := NewSlidingWindow(newCache(a), 4, time.Minute)
+	start := time.Now().Truncate(time.Minute)
+
+	a.Equal(allowN(a, l, "1", start.Add(50*time.Second), 5), 4)
+
+	// 上一个窗口的 4 次请求，在当前窗口的 15 秒处占比为 0.75，即 3 次。
+	s, err := l.Allow("1", start.Add(75*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 4, Window: time.Minute, Remaining: 0, Reset: 105 * time.Second})
+
+	// 3 + 1 + 1 > 4，需要等到上一个窗口的占比降到 0.5 以下，即 30 秒处。
+	s, err = l.Allow("1", start.Add(80*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: false, Limit: 4, Window: time.Minute, Remaining: 0, Reset: 100 * time.Second, RetryAfter: 10 * time.Second})
+
+	s, err = l.Allow("1", start.Add(91*time.Second))
+	a.NotError(err).True(s.Allowed)
+
+	a.Panic(func() {
+		NewSlidingWindow(newCache(a), 0, time.Minute)
+	})
+}
+
+func TestSlidingLog(t *testing.T) {
+	a := assert.New(t, false)
+	l := NewSlidingLog(newCache(a), 2, time.Minute)
+	now := time.Now()
+
+	s, err := l.Allow("1", now)
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 1, Reset: time.Minute})
+
+	s, err = l.Allow("1", now.Add(10*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 0, Reset: time.Minute})
+
+	s, err = l.Allow("1", now.Add(20*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: false, Limit: 2, Window: time.Minute, Remaining: 0, Reset: 50 * time.Second, RetryAfter: 40 * time.Second})
+
+	// 第一条记录已经过期
+	s, err = l.Allow("1", now.Add(61*time.Second))
+	a.NotError(err).Equal(s, &State{Allowed: true, Limit: 2, Window: time.Minute, Remaining: 0, Reset: time.Minute})
+}
+
+func benchmarkLimiter(b *testing.B, l Limiter) {
+	now := time.Now()
+	var i atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := l.Allow(strconv.FormatInt(i.Add(1)%100, 10), now); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLimiter(b *testing.B) {
+	a := assert.New(b, false)
+
+	b.Run("bucket", func(b *testing.B) { benchmarkLimiter(b, NewTokenBucket(newCache(a), 100, time.Second)) })
+	b.Run("gcra", func(b *testing.B) { benchmarkLimiter(b, NewGCRA(newCache(a), 100, time.Second)) })
+	b.Run("fixed", func(b *testing.B) { benchmarkLimiter(b, NewFixedWindow(newCache(a), 100, time.Second)) })
+	b.Run("sliding", func(b *testing.B) { benchmarkLimiter(b, NewSlidingWindow(newCache(a), 100, time.Second)) })
+	b.Run("log", func(b *testing.B) { benchmarkLimiter(b, NewSlidingLog(newCache(a), 100, time.Second)) })
+}
@@ -0,0 +1,262 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+var errInvalidKey = web.NewLocaleError("invalid api key")
+
+// ErrInvalidKey 无效的 API key
+//
+// [IdentifyAPIKey] 的 tier 在 API key 无效时应该返回此错误，
+// [Policies] 会将其作为 401 返回给客户端，其它错误则作为服务端的错误处理。
+func ErrInvalidKey() error { return errInvalidKey }
+
+// Identity 请求的身份
+type Identity struct {
+	ID   string // 区分配额的唯一 ID
+	Tier string // 用户的等级，为空表示未登录或是没有等级。
+}
+
+// IdentityFunc 获取请求身份的函数
+type IdentityFunc = func(*web.Context) (*Identity, error)
+
+// Rule 限流规则
+type Rule struct {
+	// 规则的名称
+	//
+	// 匹配同一规则的所有路由共用配额，所以在同一个 [Policies] 中不能重复。
+	Name string
+
+	Methods []string // 匹配的请求方法，为空表示所有。
+	Path    string   // 匹配的路由模式，比如 /users/{id}，以 * 结尾表示前缀匹配，为空表示所有。
+	Tiers   []string // 匹配的用户等级，为空表示所有，包括未登录的用户。
+
+	// 同时生效的多个限制
+	//
+	// 比如每秒 10 次和每天 1000 次，只要有一个限制未通过，请求即被拒绝，
+	// 且被拒绝的请求不会消耗其它限制的配额。
+	// 为空表示不作限制。
+	Limiters []Limiter
+}
+
+// Policies 根据规则进行限流的中间件
+type Policies struct {
+	identify IdentityFunc
+	rules    []*Rule
+	ietf     bool
+}
+
+// IdentifyGen 以 gen 生成的值作为身份
+//
+// gen 为空时采用 [acl.GenIP](nil)，生成的身份没有等级。
+func IdentifyGen(gen acl.GenFunc) IdentityFunc {
+	if gen == nil {
+		gen = acl.GenIP(nil)
+	}
+
+	return func(ctx *web.Context) (*Identity, error) {
+		id, err := gen(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{ID: id}, nil
+	}
+}
+
+// IdentifyPrincipal 以当前登录的用户作为身份
+//
+// 用户由 [auth.PrincipalAs] 获取，f 返回用户的唯一 ID 及其等级。
+// 未登录时采用 fallback，fallback 为空时采用 IdentifyGen(nil)。
+func IdentifyPrincipal[T any](f func(T) (id, tier string), fallback IdentityFunc) IdentityFunc {
+	if fallback == nil {
+		fallback = IdentifyGen(nil)
+	}
+
+	return func(ctx *web.Context) (*Identity, error) {
+		v, found := auth.PrincipalAs[T](ctx)
+		if !found {
+			return fallback(ctx)
+		}
+
+		id, tier := f(v)
+		return &Identity{ID: "user:" + id, Tier: tier}, nil
+	}
+}
+
+// IdentifyAPIKey 以报头 h 中的 API key 作为身份
+//
+// tier 根据 API key 获取其等级，如果 API key 无效，应该返回 [ErrInvalidKey]；可以为空。
+// 没有 API key 时采用 fallback，fallback 为空时采用 IdentifyGen(nil)。
+func IdentifyAPIKey(h string, tier func(key string) (string, error), fallback IdentityFunc) IdentityFunc {
+	if fallback == nil {
+		fallback = IdentifyGen(nil)
+	}
+
+	return func(ctx *web.Context) (*Identity, error) {
+		key := ctx.Request().Header.Get(h)
+		if key == "" {
+			return fallback(ctx)
+		}
+
+		id := &Identity{}
+		if tier != nil {
+			t, err := tier(key)
+			if err != nil {
+				return nil, err
+			}
+			id.Tier = t
+		}
+
+		sum := sha256.Sum256([]byte(key)) // 不在缓存中保存原始的 API key
+		id.ID = "key:" + hex.EncodeToString(sum[:16])
+		return id, nil
+	}
+}
+
+// NewPolicies 声明 [Policies] 对象
+//
+// identify 获取请求的身份，默认为 IdentifyGen(nil)；
+// rules 为规则列表，按顺序匹配，仅采用第一个匹配的规则，都不匹配时不作限制。
+// 可以在最后添加一条仅有 Name 和 Limiters 的规则作为默认规则。
+func NewPolicies(identify IdentityFunc, rules ...*Rule) *Policies {
+	if identify == nil {
+		identify = IdentifyGen(nil)
+	}
+
+	for i, r := range rules {
+		if r.Name == "" {
+			panic("规则的名称不能为空")
+		}
+		if slices.ContainsFunc(rules[i+1:], func(v *Rule) bool { return v.Name == r.Name }) {
+			panic("存在同名的规则 " + r.Name)
+		}
+	}
+
+	return &Policies{identify: identify, rules: rules}
+}
+
+func (p *Policies) Middleware(next web.HandlerFunc, method, path, _ string) web.HandlerFunc {
+	rules := make([]*Rule, 0, len(p.rules))
+	for _, r := range p.rules {
+		if r.matchRoute(method, path) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return next
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		id, err := p.identify(ctx)
+		switch {
+		case errors.Is(err, ErrInvalidKey()):
+			return ctx.Problem(web.ProblemUnauthorized)
+		case err != nil:
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		index := slices.IndexFunc(rules, func(r *Rule) bool {
+			return len(r.Tiers) == 0 || slices.Contains(r.Tiers, id.Tier)
+		})
+		if index < 0 {
+			return next(ctx)
+		}
+
+		s, err := rules[index].allow(id.ID, ctx.Begin())
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		if s == nil { // 不作限制
+			return next(ctx)
+		}
+
+		var policy string
+		if p.ietf {
+			policy = rules[index].Name
+		}
+		setHeader(ctx, s, policy)
+		if s.Allowed {
+			return next(ctx)
+		}
+		return tooManyRequests(ctx, s, policy)
+	}
+}
+
+// IETF 输出 IETF 草案中定义的 RateLimit 和 RateLimit-Policy 报头
+//
+// 策略的名称为 [Rule.Name]。需要在作为中间件使用之前调用。
+func (p *Policies) IETF() *Policies {
+	p.ietf = true
+	return p
+}
+
+// OpenAPI 为 o 添加限流相关的文档
+//
+// 用法可参考 [Ratelimit.OpenAPI]。
+func (p *Policies) OpenAPI(o *openapi.Operation) *openapi.Operation {
+	return operation(o, p.ietf)
+}
+
+func (r *Rule) matchRoute(method, path string) bool {
+	if len(r.Methods) > 0 && !slices.ContainsFunc(r.Methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+		return false
+	}
+
+	switch {
+	case r.Path == "":
+		return true
+	case strings.HasSuffix(r.Path, "*"):
+		return strings.HasPrefix(path, r.Path[:len(r.Path)-1])
+	default:
+		return r.Path == path
+	}
+}
+
+// 依次经过所有的限制
+//
+// 返回被拒绝的状态，如果都通过，则返回剩余配额最少的状态。
+// 被拒绝时，会返还之前的限制已经消耗的配额。
+// 没有任何限制时返回 nil。
+func (r *Rule) allow(id string, now time.Time) (*State, error) {
+	var state *State
+	for i, l := range r.Limiters {
+		s, err := l.Allow(r.key(i, id), now)
+		if err != nil {
+			return nil, err
+		}
+
+		if !s.Allowed { // 之后的限制不再消耗配额，之前的限制返还配额。
+			for j := range i {
+				if err := r.Limiters[j].Cancel(r.key(j, id), now); err != nil {
+					return nil, err
+				}
+			}
+			return s, nil
+		}
+		if state == nil || s.Remaining < state.Remaining {
+			state = s
+		}
+	}
+	return state, nil
+}
+
+func (r *Rule) key(index int, id string) string {
+	return r.Name + "_" + strconv.Itoa(index) + "_" + id
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+var _ web.Middleware = &Policies{}
+
+func TestRule_matchRoute(t *testing.T) {
+	a := assert.New(t, false)
+
+	r := &Rule{Name: "r"}
+	a.True(r.matchRoute(http.MethodGet, "/users")).
+		True(r.matchRoute(http.MethodPost, "/"))
+
+	r = &Rule{Name: "r", Methods: []string{"post"}, Path: "/users/*"}
+	a.True(r.matchRoute(http.MethodPost, "/users/{id}")).
+		True(r.matchRoute(http.MethodPost, "/users/")).
+		False(r.matchRoute(http.MethodPost, "/users")).
+		False(r.matchRoute(http.MethodGet, "/users/{id}"))
+
+	r = &Rule{Name: "r", Path: "/users/{id}"}
+	a.True(r.matchRoute(http.MethodGet, "/users/{id}")).
+		False(r.matchRoute(http.MethodGet, "/users/{id}/roles"))
+}
+
+func TestRule_allow(t *testing.T) {
+	a := assert.New(t, false)
+	c := newCache(a)
+	now := time.Now()
+
+	r := &Rule{Name: "r", Limiters: []Limiter{NewFixedWindow(c, 3, time.Hour), NewFixedWindow(c, 1, time.Hour)}}
+	s, err := r.allow("1", now)
+	a.NotError(err).True(s.Allowed).Equal(s.Remaining, 0)
+
+	s, err = r.allow("1", now)
+	a.NotError(err).False(s.Allowed).Equal(s.Limit, 1)
+
+	// 被拒绝的请求不消耗第一个限制的配额
+	s, err = r.Limiters[0].Allow(r.key(0, "1"), now)
+	a.NotError(err).True(s.Allowed).Equal(s.Remaining, 1)
+
+	s, err = (&Rule{Name: "empty"}).allow("1", now)
+	a.NotError(err).Nil(s)
+}
+
+func TestNewPolicies(t *testing.T) {
+	a := assert.New(t, false)
+
+	p := NewPolicies(nil, &Rule{Name: "r1"}, &Rule{Name: "r2"})
+	a.NotNil(p).NotNil(p.identify).Length(p.rules, 2)
+
+	a.PanicString(func() {
+		NewPolicies(nil, &Rule{Name: "r1"}, &Rule{Name: "r1"})
+	}, "存在同名的规则 r1")
+
+	a.PanicString(func() {
+		NewPolicies(nil, &Rule{})
+	}, "规则的名称不能为空")
+}
+
+func TestIdentify(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	newContext := func(key string) *web.Context {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "1.1.1.1:8080"
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		return s.NewContext(httptest.NewRecorder(), r, types.NewContext())
+	}
+
+	id, err := IdentifyGen(nil)(newContext(""))
+	a.NotError(err).Equal(id, &Identity{ID: "1.1.1.1"})
+
+	// IdentifyPrincipal
+	type user struct{ id, tier string }
+	slot := auth.NewSlot[*user]("test")
+	f := IdentifyPrincipal(func(u *user) (string, string) { return u.id, u.tier }, nil)
+	ctx := newContext("")
+	id, err = f(ctx)
+	a.NotError(err).Equal(id, &Identity{ID: "1.1.1.1"})
+	slot.Set(ctx, &user{id: "1", tier: "gold"})
+	id, err = f(ctx)
+	a.NotError(err).Equal(id, &Identity{ID: "user:1", Tier: "gold"})
+
+	// IdentifyAPIKey
+	f = IdentifyAPIKey("X-API-Key", func(key string) (string, error) {
+		if key == "invalid" {
+			return "", ErrInvalidKey()
+		}
+		return "silver", nil
+	}, nil)
+	id, err = f(newContext(""))
+	a.NotError(err).Equal(id, &Identity{ID: "1.1.1.1"})
+	id, err = f(newContext("key1"))
+	a.NotError(err).Equal(id.Tier, "silver").NotContains(id.ID, "key1")
+	id2, err := f(newContext("key1"))
+	a.NotError(err).Equal(id, id2)
+	id, err = f(newContext("invalid"))
+	a.ErrorIs(err, ErrInvalidKey()).Nil(id)
+}
+
+func TestPolicies_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	c := newCache(a)
+
+	identify := IdentifyAPIKey("X-API-Key", func(key string) (string, error) {
+		switch key {
+		case "gold":
+			return "gold", nil
+		case "invalid":
+			return "", ErrInvalidKey()
+		case "error":
+			return "", errors.New("error")
+		}
+		return "", nil
+	}, nil)
+	p := NewPolicies(identify,
+		&Rule{Name: "health", Path: "/health"},
+		&Rule{Name: "post-users", Methods: []string{http.MethodPost}, Path: "/users/*", Limiters: []Limiter{NewFixedWindow(c, 1, time.Hour)}},
+		&Rule{Name: "gold", Tiers: []string{"gold"}, Limiters: []Limiter{
+			NewFixedWindow(c, 3, time.Hour),
+			NewFixedWindow(c, 2, 24*time.Hour),
+		}},
+		&Rule{Name: "default", Limiters: []Limiter{NewFixedWindow(c, 1, time.Hour)}},
+	).IETF()
+
+	router := s.Routers().New("def", nil)
+	router.Use(p)
+	h := func(*web.Context) web.Responser { return web.Created(nil, "") }
+	router.Get("/health", h)
+	router.Get("/users/{id}", h)
+	router.Post("/users/{id}", h)
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	// 不限制
+	for range 3 {
+		servertest.Get(a, "http://localhost:8080/health").Do(nil).
+			Status(http.StatusCreated).
+			Header(header.XRateLimitLimit, "").
+			Header(RateLimit, "")
+	}
+
+	// 默认规则
+	servertest.Get(a, "http://localhost:8080/users/1").Do(nil).
+		Status(http.StatusCreated).
+		Header(header.XRateLimitLimit, "1").
+		Header(header.XRateLimitRemaining, "0")
+	servertest.Get(a, "http://localhost:8080/users/2").Do(nil).
+		Status(http.StatusTooManyRequests)
+
+	// 指定了请求方法的规则，与默认规则的配额相互独立。
+	servertest.NewRequest(a, http.MethodPost, "http://localhost:8080/users/1").Do(nil).
+		Status(http.StatusCreated)
+	servertest.NewRequest(a, http.MethodPost, "http://localhost:8080/users/1").Do(nil).
+		Status(http.StatusTooManyRequests)
+
+	// 等级为 gold 的用户，同时受两个限制。
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "gold").Do(nil).
+		Status(http.StatusCreated).
+		Header(header.XRateLimitLimit, "2").
+		Header(header.XRateLimitRemaining, "1").
+		Header(RateLimitPolicy, `"gold";q=2;w=86400`)
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "gold").Do(nil).
+		Status(http.StatusCreated).
+		Header(header.XRateLimitLimit, "2").
+		Header(header.XRateLimitRemaining, "0")
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "gold").Do(nil).
+		Status(http.StatusTooManyRequests).
+		Header(header.XRateLimitLimit, "2").
+		Header(RateLimitPolicy, `"gold";q=2;w=86400`)
+
+	// 无效的 API key
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "invalid").Do(nil).
+		Status(http.StatusUnauthorized)
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "error").Do(nil).
+		Status(http.StatusInternalServerError)
+
+	// 其它 API key 采用默认规则，且与 IP 的配额相互独立。
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "other").Do(nil).
+		Status(http.StatusCreated)
+	servertest.Get(a, "http://localhost:8080/users/1").Header("X-API-Key", "other").Do(nil).
+		Status(http.StatusTooManyRequests)
+}
@@ -5,65 +5,73 @@
 // Package ratelimit API 限流中间件
 //
 // 这是以用户或是客户端为单位的限流中间件，如果需要按路由进行限流，需为每个路由指定一个实例。
+// 限流的算法由 [Limiter] 决定，目前提供了以下几种：
+//   - [NewTokenBucket] 令牌桶；
+//   - [NewGCRA] 通用信元速率算法；
+//   - [NewFixedWindow] 固定窗口；
+//   - [NewSlidingWindow] 滑动窗口计数器；
+//   - [NewSlidingLog] 滑动窗口日志；
+//
+// 默认输出 X-Rate-Limit-* 报头，也可以通过 IETF 方法同时输出 IETF 草案中定义的
+// RateLimit 和 RateLimit-Policy 报头。请求被拒绝时会输出 Retry-After 报头，
+// 并在返回的 [web.Problem] 中附带 [Limit] 对象。
 //
 // NOTE: 所有数据保存在 [web.Cache] 之中，缓存服务重启后数据也将重置。
 package ratelimit
 
 import (
-	"errors"
 	"slices"
-	"strconv"
 	"time"
 
-	"github.com/issue9/cache"
-	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
 )
 
 // GenFunc 用于生成用户唯一 ID 的函数
 //
-// 用于区分令牌桶所属的用户
-type GenFunc = func(*web.Context) (string, error)
+// Deprecated: 请使用 [acl.GenFunc] 代替。
+type GenFunc = acl.GenFunc
 
 type Ratelimit struct {
-	store    web.Cache
-	capacity uint64
-	rate     time.Duration
-	ttl      time.Duration
-	gen      GenFunc
-	unlimit  []string
+	limiter Limiter
+	gen     acl.GenFunc
+	unlimit []string
+	policy  string
 }
 
-// GenIP 用于生成区分令牌桶的 IP 地址
-func GenIP(ctx *web.Context) (string, error) {
-	if ip := ctx.ClientIP(); ip != "" {
-		return ip, nil
-	}
-	return "", web.NewLocaleError("can not get the ip")
-}
+// GenIP 返回以客户端 IP 区分配额的 [GenFunc]
+//
+// Deprecated: 请使用 [acl.GenIP] 代替。
+//
+// NOTE: 这是一个不兼容的修改，之前的 GenIP 采用 [web.Context.ClientIP]，会读取代理报头；
+// 现在 r 为空时只采用连接的地址。部署在代理之后时，需要通过 [acl.NewResolver] 指定可信的代理，
+// 否则所有的客户端都将被识别为代理的 IP，共用同一份配额。
+func GenIP(r *acl.Resolver) GenFunc { return acl.GenIP(r) }
 
-// New 声明 API 限流的中间件
+// New 声明基于令牌桶算法的 API 限流中间件
 //
 // capacity 桶的容量；
 // rate 发放令牌的时间间隔；
-// gen 为令牌桶名称的产生方法，默认为用户的 IP；
-func New(c web.Cache, capacity uint64, rate time.Duration, gen GenFunc) *Ratelimit {
-	ttl := rate * time.Duration(capacity) // 填满令牌桶的时候，当桶是满的时候，不需要缓存这些数据。
-	if ttl < time.Second {
-		panic("capacity*rate 必须大于 1 秒")
-	}
+// gen 为令牌桶名称的产生方法，默认为不信任任何代理的 [acl.GenIP](nil)，
+// 部署在代理之后时需要自行指定，参考 [GenIP]；
+func New(c web.Cache, capacity uint64, rate time.Duration, gen acl.GenFunc) *Ratelimit {
+	return NewWithLimiter(NewTokenBucket(c, capacity, rate), gen)
+}
 
+// NewWithLimiter 声明采用指定算法的 API 限流中间件
+//
+// gen 为区分配额所属用户的方法，默认为不信任任何代理的 [acl.GenIP](nil)；
+func NewWithLimiter(l Limiter, gen acl.GenFunc) *Ratelimit {
 	if gen == nil {
-		gen = GenIP
+		gen = acl.GenIP(nil)
 	}
 
 	return &Ratelimit{
-		store:    c,
-		capacity: capacity,
-		rate:     rate,
-		ttl:      ttl,
-		gen:      gen,
-		unlimit:  make([]string, 0, 10),
+		limiter: l,
+		gen:     gen,
+		unlimit: make([]string, 0, 10),
 	}
 }
 
@@ -75,19 +83,44 @@ func (rate *Ratelimit) Middleware(next web.HandlerFunc, method, path, router str
 	}
 
 	return func(ctx *web.Context) web.Responser {
-		size, err := rate.allow(ctx)
+		s, err := rate.allow(ctx)
 		if err != nil {
-			return ctx.Problem(web.ProblemInternalServerError)
+			return ctx.Error(err, web.ProblemInternalServerError)
 		}
 
-		rate.setHeader(ctx, size)
-		if size > 0 {
+		setHeader(ctx, s, rate.policy)
+		if s.Allowed {
 			return next(ctx)
 		}
-		return ctx.Problem(web.ProblemTooManyRequests)
+		return tooManyRequests(ctx, s, rate.policy)
 	}
 }
 
+// IETF 输出 IETF 草案中定义的 RateLimit 和 RateLimit-Policy 报头
+//
+// name 为配额策略的名称，不能为空。原有的 X-Rate-Limit-* 报头依然会输出。
+// 需要在作为中间件使用之前调用。
+func (rate *Ratelimit) IETF(name string) *Ratelimit {
+	if name == "" {
+		panic("参数 name 不能为空")
+	}
+	rate.policy = name
+	return rate
+}
+
+// OpenAPI 为 o 添加限流相关的文档
+//
+// 为 o 中已经声明的返回对象添加限流相关的报头，并添加状态码为 429 的返回对象，
+// 所以应该在声明完其它返回对象之后调用。比如：
+//
+//	doc.API(func(o *openapi.Operation) {
+//		o.Response200(&User{})
+//		rate.OpenAPI(o)
+//	})
+func (rate *Ratelimit) OpenAPI(o *openapi.Operation) *openapi.Operation {
+	return operation(o, rate.policy != "")
+}
+
 // Unlimit 返回一个脱离当前限制的中间件
 func (rate *Ratelimit) Unlimit() web.Middleware {
 	return web.MiddlewareFunc(func(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
@@ -97,65 +130,10 @@ func (rate *Ratelimit) Unlimit() web.Middleware {
 }
 
 // 是否允许当前请求
-//
-// 如果允许，则返回当前可用的数量。
-func (rate *Ratelimit) allow(ctx *web.Context) (uint64, error) {
+func (rate *Ratelimit) allow(ctx *web.Context) (*State, error) {
 	name, err := rate.gen(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	_, setter, found, err := rate.store.Counter(name+"_cnt", rate.ttl)
-	if err != nil {
-		return 0, err
-	}
-	if !found {
-		if _, err = setter(int(rate.capacity)); err != nil {
-			return 0, err
-		}
-	}
-
-	size, err := setter(-1) // 先扣点，保证多并发情况下不会有问题。
-	if err != nil {
-		return 0, err
-	}
-
-	// 根据最后一次请求时间，补发这段时间内需要的令牌数量。
-
-	lastName := name + "_last"
-	now := ctx.Begin()
-	var last time.Time
-	switch err = rate.store.Get(lastName, &last); {
-	case errors.Is(err, cache.ErrCacheMiss()):
-		last = now
-	case err != nil:
-		ctx.Logs().ERROR().Error(err)
-		return size, nil // 无法确定最后日期，就以当前的数量为准
-	}
-
-	dur := now.Sub(last)                 // 从上次拿令牌到现在的时间段
-	incr := uint64(dur / rate.rate)      // 计算这段时间内需要增加的令牌
-	incr = min(incr, rate.capacity-size) // 不超过增加的量
-	if incr > 0 {
-		if _, err = setter(int(incr)); err != nil {
-			ctx.Logs().ERROR().Error(err)
-			return size + incr, nil
-		}
-	}
-
-	if err = rate.store.Set(lastName, now, rate.ttl); err != nil {
-		ctx.Logs().ERROR().Error(err)
-		return size + incr, nil
-	}
-
-	return size + incr, nil
-}
-
-func (rate *Ratelimit) setHeader(ctx *web.Context, size uint64) {
-	t := time.Duration(rate.capacity-size) * rate.rate
-	rest := ctx.Begin().Add(t).Unix()
-
-	h := ctx.Header()
-	h.Set(header.XRateLimitLimit, strconv.FormatUint(rate.capacity, 10))
-	h.Set(header.XRateLimitRemaining, strconv.FormatUint(size, 10))
-	h.Set(header.XRateLimitReset, strconv.FormatInt(rest, 10))
+	return rate.limiter.Allow(name, ctx.Begin())
 }
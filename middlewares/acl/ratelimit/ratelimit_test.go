@@ -5,7 +5,9 @@
 package ratelimit
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/issue9/cache"
 	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
 	"github.com/issue9/web/server/servertest"
 
 	"github.com/issue9/webuse/v7/internal/testserver"
@@ -53,14 +56,15 @@ func TestRatelimit_Middleware(t *testing.T) {
 		Header(header.XRateLimitRemaining, "1")
 
 	servertest.Get(a, "http://localhost:8080/test").Do(nil).
-		Status(http.StatusTooManyRequests).
+		Status(http.StatusCreated).
 		Header(header.XRateLimitLimit, "4").
 		Header(header.XRateLimitRemaining, "0")
 
 	servertest.Get(a, "http://localhost:8080/test").Do(nil).
 		Status(http.StatusTooManyRequests).
 		Header(header.XRateLimitLimit, "4").
-		Header(header.XRateLimitRemaining, "0")
+		Header(header.XRateLimitRemaining, "0").
+		Header(header.RetryAfter, "10")
 }
 
 func TestRatelimit_Unlimit(t *testing.T) {
@@ -98,3 +102,68 @@ func TestRatelimit_Unlimit(t *testing.T) {
 		Header(header.XRateLimitLimit, "").
 		Header(header.XRateLimitRemaining, "")
 }
+
+func TestRatelimit_IETF(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	rate := NewWithLimiter(NewFixedWindow(newCache(a), 1, time.Hour), nil).IETF("api")
+	a.PanicString(func() { rate.IETF("") }, "参数 name 不能为空")
+
+	doc := openapi.New(s, web.Phrase("test"))
+	r := s.Routers().New("def", nil)
+	r.Use(rate)
+	r.Get("/test", func(*web.Context) web.Responser {
+		return web.Created(nil, "")
+	}, doc.API(func(o *openapi.Operation) {
+		rate.OpenAPI(o.ResponseEmpty("201"))
+	}))
+	r.Get("/openapi", doc.Handler(), rate.Unlimit())
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	resp := servertest.Get(a, "http://localhost:8080/test").Do(nil).
+		Status(http.StatusCreated).
+		Header(RateLimitPolicy, `"api";q=1;w=3600`).
+		Header(header.RetryAfter, "").
+		Resp()
+	a.True(strings.HasPrefix(resp.Header.Get(RateLimit), `"api";r=0;t=`))
+
+	resp = servertest.Get(a, "http://localhost:8080/test").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusTooManyRequests).
+		Header(RateLimitPolicy, `"api";q=1;w=3600`).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			p := &struct {
+				Extensions *Limit `json:"extensions"`
+			}{}
+			a.NotError(json.Unmarshal(body, p)).
+				NotNil(p.Extensions).
+				Equal(p.Extensions.Policy, "api").
+				Equal(p.Extensions.Limit, 1).
+				Equal(p.Extensions.Window, 3600).
+				Equal(p.Extensions.Remaining, 0).
+				True(p.Extensions.RetryAfter > 0)
+		}).
+		Resp()
+	a.NotEmpty(resp.Header.Get(header.RetryAfter))
+
+	servertest.Get(a, "http://localhost:8080/openapi").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.Contains(string(body), `"429"`).
+				Contains(string(body), header.RetryAfter).
+				Contains(string(body), RateLimitPolicy).
+				Contains(string(body), header.XRateLimitRemaining)
+		})
+}
+
+func TestSeconds(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.Equal(seconds(0), 0).
+		Equal(seconds(-time.Second), 0).
+		Equal(seconds(time.Millisecond), 1).
+		Equal(seconds(time.Second), 1).
+		Equal(seconds(1001*time.Millisecond), 2)
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+)
+
+type window struct {
+	*locker
+	c     web.Cache
+	limit uint64
+	size  time.Duration // 窗口的长度
+}
+
+type fixedWindow struct{ *window }
+
+type slidingWindow struct{ *window }
+
+type slidingLog struct{ *window }
+
+func newWindow(c web.Cache, limit uint64, w time.Duration) *window {
+	if limit == 0 || w <= 0 {
+		panic("参数 limit 和 window 必须大于 0")
+	}
+	return &window{locker: newLocker(), c: c, limit: limit, size: w}
+}
+
+// NewFixedWindow 固定窗口算法
+//
+// 将时间划分为长度为 window 的窗口，每个窗口内最多允许 limit 次请求。
+// 实现简单，但是在窗口的边界处，最多可能会通过 2*limit 次请求。
+func NewFixedWindow(c web.Cache, limit uint64, window time.Duration) Limiter {
+	return &fixedWindow{window: newWindow(c, limit, window)}
+}
+
+// NewSlidingWindow 滑动窗口计数器算法
+//
+// 根据上一个窗口的计数按时间比例估算当前滑动窗口内的请求数量，
+// 解决了固定窗口在边界处的突发问题，且每个 key 仅需要两个计数器。
+func NewSlidingWindow(c web.Cache, limit uint64, window time.Duration) Limiter {
+	return &slidingWindow{window: newWindow(c, limit, window)}
+}
+
+// NewSlidingLog 滑动窗口日志算法
+//
+// 记录窗口内每一次通过的请求时间，是最精确的算法，
+// 但是每个 key 需要保存 limit 个时间值，适用于 limit 较小的场景。
+func NewSlidingLog(c web.Cache, limit uint64, window time.Duration) Limiter {
+	return &slidingLog{window: newWindow(c, limit, window)}
+}
+
+// 返回 now 所在窗口的序号及窗口的起始时间
+func (w *window) current(now time.Time) (int64, time.Time) {
+	index := now.UnixNano() / int64(w.size)
+	return index, time.Unix(0, index*int64(w.size))
+}
+
+func (w *window) key(key string, index int64) string {
+	return key + "_" + strconv.FormatInt(index, 10)
+}
+
+func (w *fixedWindow) Allow(key string, now time.Time) (*State, error) {
+	defer w.localLock(key)()
+
+	index, start := w.current(now)
+	n, err := incr(w.c, w.key(key, index), 1, w.size)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{
+		Allowed:   n <= w.limit,
+		Limit:     w.limit,
+		Window:    w.size,
+		Remaining: w.limit - min(n, w.limit),
+		Reset:     start.Add(w.size).Sub(now),
+	}
+	if !s.Allowed {
+		s.RetryAfter = s.Reset
+	}
+	return s, nil
+}
+
+func (w *fixedWindow) Cancel(key string, now time.Time) error {
+	defer w.localLock(key)()
+
+	index, _ := w.current(now)
+	_, err := incr(w.c, w.key(key, index), -1, w.size)
+	return err
+}
+
+func (w *slidingWindow) Allow(key string, now time.Time) (*State, error) {
+	defer w.localLock(key)()
+
+	index, start := w.current(now)
+	prev, _, _, err := w.c.Counter(w.key(key, index-1), 2*w.size)
+	if err != nil {
+		return nil, err
+	}
+
+	currKey := w.key(key, index)
+	curr, err := incr(w.c, currKey, 1, 2*w.size) // 先计数，保证并发时不会超出限制。
+	if err != nil {
+		return nil, err
+	}
+
+	elapsed := now.Sub(start)
+	weight := float64(w.size-elapsed) / float64(w.size) // 上一个窗口在滑动窗口中的占比
+	count := float64(prev)*weight + float64(curr)
+
+	s := &State{Limit: w.limit, Window: w.size, Reset: start.Add(2 * w.size).Sub(now)}
+	if count <= float64(w.limit) {
+		s.Allowed = true
+		s.Remaining = uint64(float64(w.limit) - count)
+		return s, nil
+	}
+
+	if _, err := incr(w.c, currKey, -1, 2*w.size); err != nil { // 被拒绝的请求不计数
+		return nil, err
+	}
+	curr--
+
+	// 上一个窗口的占比降低到可以容纳当前请求的时间
+	s.RetryAfter = start.Add(w.size).Sub(now)
+	if prev > 0 && curr < w.limit {
+		ratio := float64(w.limit-curr-1) / float64(prev)
+		s.RetryAfter = time.Duration((1-ratio)*float64(w.size)) - elapsed
+	}
+	s.RetryAfter = max(s.RetryAfter, 0)
+	return s, nil
+}
+
+func (w *slidingWindow) Cancel(key string, now time.Time) error {
+	defer w.localLock(key)()
+
+	index, _ := w.current(now)
+	_, err := incr(w.c, w.key(key, index), -1, 2*w.size)
+	return err
+}
+
+func (w *slidingLog) Allow(key string, now time.Time) (*State, error) {
+	key += "_log"
+	unlock, err := w.lock(w.c, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var log []int64 // 窗口内通过的请求时间，单位为纳秒。
+	switch err := w.c.Get(key, &log); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+	case err != nil:
+		return nil, err
+	}
+
+	begin := now.Add(-w.size).UnixNano()
+	log = slices.DeleteFunc(log, func(t int64) bool { return t <= begin })
+
+	s := &State{Limit: w.limit, Window: w.size}
+	if uint64(len(log)) < w.limit {
+		log = append(log, now.UnixNano())
+		s.Allowed = true
+		if err := w.c.Set(key, log, w.size); err != nil {
+			return nil, err
+		}
+	} else {
+		s.RetryAfter = time.Unix(0, log[0]).Add(w.size).Sub(now)
+	}
+
+	s.Remaining = w.limit - uint64(len(log))
+	if len(log) > 0 {
+		s.Reset = time.Unix(0, log[len(log)-1]).Add(w.size).Sub(now)
+	}
+	return s, nil
+}
+
+func (w *slidingLog) Cancel(key string, now time.Time) error {
+	key += "_log"
+	unlock, err := w.lock(w.c, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var log []int64
+	switch err := w.c.Get(key, &log); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if index := slices.Index(log, now.UnixNano()); index >= 0 {
+		log = slices.Delete(log, index, index+1)
+		return w.c.Set(key, log, w.size)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/issue9/web"
+)
+
+// DryRunHeader 试运行模式下输出拒绝原因的报头
+//
+// 参考 [RBAC.SetDryRun]。
+const DryRunHeader = "X-RBAC-Dry-Run"
+
+// Reason 权限判断结果的原因
+type Reason string
+
+const (
+	ReasonSuper      Reason = "super"       // 超级管理员
+	ReasonGranted    Reason = "granted"     // 由角色授予了权限
+	ReasonDenied     Reason = "denied"      // 被角色明确拒绝
+	ReasonNotGranted Reason = "not-granted" // 没有任何角色授予权限
+	ReasonNotFound   Reason = "not-found"   // 资源不存在
+	ReasonCondition  Reason = "condition"   // 不满足资源的附加条件，仅用于试运行模式。
+)
+
+// Explanation 权限判断过程的说明
+type Explanation[T comparable] struct {
+	UID      T      `json:"uid" xml:"uid,attr" yaml:"uid" cbor:"uid" comment:"the id of user"`
+	Tenant   string `json:"tenant,omitempty" xml:"tenant,attr,omitempty" yaml:"tenant,omitempty" cbor:"tenant,omitempty" comment:"the id of tenant"`
+	Resource string `json:"resource" xml:"resource,attr" yaml:"resource" cbor:"resource" comment:"the id of resource"`
+	Allowed  bool   `json:"allowed" xml:"allowed,attr" yaml:"allowed" cbor:"allowed" comment:"whether the access is allowed"`
+	Reason   Reason `json:"reason" xml:"reason,attr" yaml:"reason" cbor:"reason" comment:"the reason of decision"`
+
+	// 资源是否带有附加条件
+	//
+	// 附加条件需要根据请求才能判断，所以不包含在判断结果中。
+	Condition bool `json:"condition,omitempty" xml:"condition,attr,omitempty" yaml:"condition,omitempty" cbor:"condition,omitempty" comment:"whether the resource has a condition"`
+
+	Groups []*GroupExplanation `json:"groups,omitempty" xml:"groups>group,omitempty" yaml:"groups,omitempty" cbor:"groups,omitempty" comment:"the role groups considered"`
+}
+
+// GroupExplanation 在某一角色组中的判断过程
+type GroupExplanation struct {
+	ID      string   `json:"id" xml:"id,attr" yaml:"id" cbor:"id" comment:"the id of role group"`
+	Reason  Reason   `json:"reason" xml:"reason,attr" yaml:"reason" cbor:"reason" comment:"the reason of decision"`
+	Roles   []string `json:"roles,omitempty" xml:"roles>role,omitempty" yaml:"roles,omitempty" cbor:"roles,omitempty" comment:"the roles of user"`
+	Expired []string `json:"expired,omitempty" xml:"expired>role,omitempty" yaml:"expired,omitempty" cbor:"expired,omitempty" comment:"the expired roles of user"`
+
+	// 授予或是拒绝访问的角色以及匹配的资源 ID 或通配符
+	//
+	// 拒绝的资源会作用于子角色，所以 Role 可能是声明拒绝的角色的子角色。
+	Role  string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty" cbor:"role,omitempty" comment:"the role that granted or denied the access"`
+	Match string `json:"match,omitempty" xml:"match,omitempty" yaml:"match,omitempty" cbor:"match,omitempty" comment:"the matched resource or wildcard"`
+}
+
+// SetDryRun 设置试运行模式
+//
+// 在试运行模式下，资源的中间件和 [RBAC.Check] 不会真正拒绝访问，
+// 而是以 WARN 级别记录日志，并通过报头 [DryRunHeader] 输出拒绝的原因，
+// 可用于在启用权限控制之前观察其影响。未登录等非权限相关的错误不受影响。
+func (rbac *RBAC[T]) SetDryRun(dryRun bool) { rbac.dryRun.Store(dryRun) }
+
+// 拒绝用户 uid 访问资源 res
+//
+// 如果是试运行模式，仅记录日志，返回 nil。
+func (rbac *RBAC[T]) forbidden(ctx *web.Context, uid T, tenant, res string, cond bool) web.Responser {
+	if !rbac.dryRun.Load() {
+		return ctx.Problem(web.ProblemForbidden)
+	}
+
+	reason := ReasonCondition
+	if !cond {
+		e, err := rbac.ExplainTenant(uid, tenant, res)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		reason = e.Reason
+	}
+
+	ctx.Header().Set(DryRunHeader, string(reason))
+	rbac.s.Logs().WARN().LocaleString(web.Phrase("dry run: user %v is not allowed to access %s due to %s", uid, res, reason))
+	return nil
+}
+
+// Explain 说明用户 uid 是否可以访问资源 res 及其原因
+//
+// 与未指定租户时的 [RBAC.Check] 相同，会在用户关联的所有角色组中判断。
+func (rbac *RBAC[T]) Explain(uid T, res string) *Explanation[T] {
+	e, _ := rbac.ExplainTenant(uid, "", res) // tenant 为空时不会返回错误
+	return e
+}
+
+// ExplainTenant 说明用户 uid 在租户 tenant 中是否可以访问资源 res 及其原因
+//
+// 如果 tenant 为空，则与 [RBAC.Explain] 相同。
+// 只在已经加载的角色组中判断，不会加载或是创建租户的角色组，
+// 租户的角色组不存在时，返回 [ReasonNotGranted]。
+func (rbac *RBAC[T]) ExplainTenant(uid T, tenant, res string) (*Explanation[T], error) {
+	e := &Explanation[T]{UID: uid, Tenant: tenant, Resource: res}
+
+	g := rbac.resourceGroupOf(res)
+	if g == nil {
+		e.Reason = ReasonNotFound
+		return e, nil
+	}
+	_, e.Condition = g.conds[res]
+
+	var groups []*RoleGroup[T]
+	if tenant == "" {
+		if rbac.isSuper(uid) {
+			e.Allowed = true
+			e.Reason = ReasonSuper
+			return e, nil
+		}
+
+		rbac.indexMux.RLock()
+		for _, rg := range rbac.userGroups[uid] {
+			groups = append(groups, rg)
+		}
+		rbac.indexMux.RUnlock()
+		slices.SortFunc(groups, func(a, b *RoleGroup[T]) int { return cmp.Compare(a.id, b.id) })
+	} else {
+		rg := rbac.roleGroup(tenant)
+		if rg == nil {
+			e.Reason = ReasonNotGranted
+			return e, nil
+		}
+		groups = []*RoleGroup[T]{rg}
+	}
+
+	e.Reason = ReasonNotGranted
+	for _, rg := range groups {
+		ge := rg.Explain(uid, res)
+		e.Groups = append(e.Groups, ge)
+
+		switch ge.Reason {
+		case ReasonGranted, ReasonSuper:
+			e.Allowed = true
+			e.Reason = ge.Reason
+		case ReasonDenied:
+			if !e.Allowed {
+				e.Reason = ReasonDenied
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// Explain 说明用户 uid 在当前角色组中是否可以访问资源 res 及其原因
+func (g *RoleGroup[T]) Explain(uid T, res string) *GroupExplanation {
+	e := &GroupExplanation{ID: g.id}
+	if g.isSuper(uid) {
+		e.Reason = ReasonSuper
+		return e
+	}
+
+	now := g.rbac.s.Now()
+	ids := []string{res, wildcardOf(res)}
+	match := func(m map[string]*Role[T]) (*Role[T], string) {
+		for _, id := range ids {
+			if r, found := m[id]; found && !r.expired(uid, now) {
+				return r, id
+			}
+		}
+		return nil, ""
+	}
+
+	g.rolesMux.RLock()
+	defer g.rolesMux.RUnlock()
+
+	for _, r := range g.userRoles[uid] {
+		if r.expired(uid, now) {
+			e.Expired = append(e.Expired, r.ID)
+		} else {
+			e.Roles = append(e.Roles, r.ID)
+		}
+	}
+	slices.Sort(e.Roles)
+	slices.Sort(e.Expired)
+
+	if r, id := match(g.userDenied[uid]); r != nil { // 拒绝的优先级更高
+		e.Reason, e.Role, e.Match = ReasonDenied, r.ID, id
+	} else if r, id := match(g.userResources[uid]); r != nil {
+		e.Reason, e.Role, e.Match = ReasonGranted, r.ID, id
+	} else {
+		e.Reason = ReasonNotGranted
+	}
+	return e
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func TestRBAC_Explain(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	ga := rbac.NewResourceGroup("a", nil)
+	ga.New("1", nil)
+	ga.New("2", nil)
+	ga.NewPolicy("3", nil, func(*web.Context, string, any) bool { return false }, nil)
+	a1, a2, a3 := joinID("a", "1"), joinID("a", "2"), joinID("a", "3")
+
+	g1, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g1)
+	parent, err := g1.NewRole("parent", "", "")
+	a.NotError(err).NotNil(parent).
+		NotError(parent.SetResources([]string{ga.Wildcard()}, []string{a2}))
+	child, err := g1.NewRole("child", "", parent.ID)
+	a.NotError(err).NotNil(child).NotError(child.Allow(a1, a3))
+	a.NotError(child.Link("u1"))
+
+	g2, err := rbac.NewRoleGroup("g2", "admin2")
+	a.NotError(err).NotNil(g2)
+	r2, err := g2.NewRole("r2", "", "")
+	a.NotError(err).NotNil(r2).NotError(r2.Allow(a2))
+	a.NotError(r2.LinkUntil("u1", time.Now().Add(-time.Second)))
+
+	e := rbac.Explain("u1", a1)
+	a.True(e.Allowed).
+		Equal(e.Reason, ReasonGranted).
+		False(e.Condition).
+		Length(e.Groups, 2)
+	a.Equal(e.Groups[0], &GroupExplanation{ID: "g1", Reason: ReasonGranted, Roles: []string{child.ID}, Role: child.ID, Match: a1}).
+		Equal(e.Groups[1], &GroupExplanation{ID: "g2", Reason: ReasonNotGranted, Expired: []string{r2.ID}})
+
+	// 父角色中拒绝
+	e = rbac.Explain("u1", a2)
+	a.False(e.Allowed).Equal(e.Reason, ReasonDenied).
+		Equal(e.Groups[0], &GroupExplanation{ID: "g1", Reason: ReasonDenied, Roles: []string{child.ID}, Role: child.ID, Match: a2})
+
+	e = rbac.Explain("u1", a3)
+	a.True(e.Allowed).True(e.Condition)
+
+	e = rbac.Explain("u1", "not-exists")
+	a.False(e.Allowed).Equal(e.Reason, ReasonNotFound).Empty(e.Groups)
+
+	e = rbac.Explain("admin2", a1)
+	a.True(e.Allowed).Equal(e.Reason, ReasonSuper)
+
+	e = rbac.Explain("u2", a1)
+	a.False(e.Allowed).Equal(e.Reason, ReasonNotGranted).Empty(e.Groups)
+
+	e, err = rbac.ExplainTenant("u1", "g2", a1)
+	a.NotError(err).False(e.Allowed).Equal(e.Tenant, "g2").Length(e.Groups, 1)
+
+	// 不存在的租户不会被创建
+	e, err = rbac.ExplainTenant("u1", "t1", a1)
+	a.NotError(err).False(e.Allowed).Equal(e.Reason, ReasonNotGranted).Empty(e.Groups).
+		Nil(rbac.roleGroup("t1"))
+
+	// 其它角色组的超级管理员在租户中没有权限
+	e, err = rbac.ExplainTenant("admin", "g2", a1)
+	a.NotError(err).False(e.Allowed)
+}
+
+func TestRBAC_SetDryRun(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(ctx *web.Context) (string, string, web.Responser) {
+		uid := ctx.Request().Header.Get("x-uid")
+		if uid == "" {
+			return "", "", ctx.Problem(web.ProblemUnauthorized)
+		}
+		return uid, "", nil
+	})
+	ga := rbac.NewResourceGroup("a", nil)
+	m1 := ga.New("1", nil)
+	m2 := ga.NewPolicy("2", nil, func(*web.Context, string, any) bool { return false }, nil)
+	g, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g)
+	r, err := g.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r).NotError(r.Allow(ga.Wildcard())).NotError(r.Link("u1"))
+
+	router := s.Routers().New("def", nil)
+	router.Get("/a/1", func(*web.Context) web.Responser { return web.NoContent() }, m1)
+	router.Get("/a/2", func(*web.Context) web.Responser { return web.NoContent() }, m2)
+	router.Get("/not-exists", func(ctx *web.Context) web.Responser {
+		if resp := rbac.Check(ctx, "not-exists", nil); resp != nil {
+			return resp
+		}
+		return web.NoContent()
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "u2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	rbac.SetDryRun(true)
+
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "u2").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header(DryRunHeader, string(ReasonNotGranted))
+
+	servertest.Get(a, "http://localhost:8080/a/2").
+		Header("x-uid", "u1").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header(DryRunHeader, string(ReasonCondition))
+
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "u1").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header(DryRunHeader, "")
+
+	// 未注册的资源
+	servertest.Get(a, "http://localhost:8080/not-exists").
+		Header("x-uid", "u1").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header(DryRunHeader, string(ReasonNotFound))
+
+	// 未登录依然返回错误
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Do(nil).
+		Status(http.StatusUnauthorized)
+}
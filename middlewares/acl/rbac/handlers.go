@@ -0,0 +1,493 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/issue9/web"
+	"github.com/issue9/web/filter"
+	"github.com/issue9/web/openapi"
+
+	"github.com/issue9/webuse/v7/filters/validator"
+)
+
+// RoleInfo 角色的详细信息
+type RoleInfo[T comparable] struct {
+	ID        string   `json:"id" xml:"id,attr" yaml:"id" cbor:"id" comment:"the id of role"`
+	Parent    string   `json:"parent,omitempty" xml:"parent,attr,omitempty" yaml:"parent,omitempty" cbor:"parent,omitempty" comment:"the parent id of role"`
+	Name      string   `json:"name" xml:"name" yaml:"name" cbor:"name" comment:"the name of role"`
+	Desc      string   `json:"desc,omitempty" xml:"desc,omitempty" yaml:"desc,omitempty" cbor:"desc,omitempty" comment:"the description of role"`
+	Resources []string `json:"resources,omitempty" xml:"resources>resource,omitempty" yaml:"resources,omitempty" cbor:"resources,omitempty" comment:"resources of current role"`
+	Denied    []string `json:"denied,omitempty" xml:"denied>resource,omitempty" yaml:"denied,omitempty" cbor:"denied,omitempty" comment:"denied resources of current role"`
+	Users     []T      `json:"users,omitempty" xml:"users>user,omitempty" yaml:"users,omitempty" cbor:"users,omitempty" comment:"users of current role"`
+
+	Memberships []*Membership[T] `json:"memberships,omitempty" xml:"memberships>membership,omitempty" yaml:"memberships,omitempty" cbor:"memberships,omitempty" comment:"temporary or delegated users of current role"`
+}
+
+// RoleRequest 添加或是修改角色时提交的数据
+type RoleRequest struct {
+	Name   string `json:"name" xml:"name" yaml:"name" cbor:"name" comment:"the name of role"`
+	Desc   string `json:"desc,omitempty" xml:"desc,omitempty" yaml:"desc,omitempty" cbor:"desc,omitempty" comment:"the description of role"`
+	Parent string `json:"parent,omitempty" xml:"parent,attr,omitempty" yaml:"parent,omitempty" cbor:"parent,omitempty" comment:"the parent id of role, only used when creating"`
+}
+
+// ResourcesRequest 设置角色的资源时提交的数据
+type ResourcesRequest struct {
+	Resources []string `json:"resources" xml:"resources>resource" yaml:"resources" cbor:"resources" comment:"resources of current role"`
+	Denied    []string `json:"denied,omitempty" xml:"denied>resource,omitempty" yaml:"denied,omitempty" cbor:"denied,omitempty" comment:"denied resources of current role"`
+}
+
+type handlers[T comparable] struct {
+	rbac     *RBAC[T]
+	parseUID func(string) (T, error)
+
+	// 各接口对应的资源 ID
+	viewID, roleID, resID, userID string
+}
+
+func (r *RoleRequest) Filter(v *web.FilterContext) {
+	v.Add(filter.New("name", &r.Name, validator.V(validator.Not(validator.Empty), web.Phrase("can not be empty"))))
+}
+
+func (r *ResourcesRequest) Filter(v *web.FilterContext) {
+	v.Add(filter.New("resources", &r.Resources, validator.SV[[]string](validator.Not(validator.Empty), web.Phrase("can not be empty")))).
+		Add(filter.New("resources", &r.Resources, filter.V(unique, web.Phrase("duplicate resource")))).
+		Add(filter.New("denied", &r.Denied, validator.SV[[]string](validator.Not(validator.Empty), web.Phrase("can not be empty")))).
+		Add(filter.New("denied", &r.Denied, filter.V(unique, web.Phrase("duplicate resource"))))
+}
+
+func unique(res []string) bool {
+	return len(slices.Compact(slices.Sorted(slices.Values(res)))) == len(res)
+}
+
+func newRoleInfo[T comparable](r *Role[T]) *RoleInfo[T] {
+	return &RoleInfo[T]{
+		ID:        r.ID,
+		Parent:    r.Parent,
+		Name:      r.Name,
+		Desc:      r.Desc,
+		Resources: slices.Clone(r.Resources),
+		Denied:    slices.Clone(r.Denied),
+		Users:     slices.Clone(r.Users),
+
+		Memberships: cloneMemberships(r.Memberships),
+	}
+}
+
+// RegisterHandlers 在 r 上注册 RBAC 的管理接口
+//
+// prefix 为所有接口的路径前缀；
+// id 和 title 用于声明一个 [ResourceGroup]，这些接口本身也受到该资源组的权限控制，
+// 包含了 view、role、resource 和 user 四个资源，分别对应查看、编辑角色、设置角色的资源以及关联用户；
+// doc 如果不为空，会为每个接口生成 openapi 文档；
+//
+// 只能管理已经加载的角色组，不存在的角色组返回 404。
+// 用户还需要在被管理的角色组中拥有相应的资源，如果当前用户指定了租户，则只能管理该租户的角色组，
+// explain 也只能查询该租户，tenant 参数为空时默认为当前用户的租户。
+//
+// 注册的接口如下：
+//
+//	GET    {prefix}/resources                                 所有的资源
+//	GET    {prefix}/groups/{group}/roles                      角色列表
+//	POST   {prefix}/groups/{group}/roles                      添加角色
+//	GET    {prefix}/groups/{group}/roles/{role}               角色详情
+//	PUT    {prefix}/groups/{group}/roles/{role}               修改角色
+//	DELETE {prefix}/groups/{group}/roles/{role}               删除角色
+//	GET    {prefix}/groups/{group}/roles/{role}/resources     角色的资源
+//	PUT    {prefix}/groups/{group}/roles/{role}/resources     设置角色的资源
+//	PUT    {prefix}/groups/{group}/roles/{role}/users/{uid}   关联用户
+//	DELETE {prefix}/groups/{group}/roles/{role}/users/{uid}   取消关联用户
+//	GET    {prefix}/groups/{group}/users/{uid}/roles          用户的角色
+//	GET    {prefix}/explain?uid={uid}&resource={res}&tenant={tenant} 说明用户是否可以访问资源，参考 [RBAC.ExplainTenant]
+//
+// NOTE: T 只能是字符串或是整数类型，否则会 panic。
+func (rbac *RBAC[T]) RegisterHandlers(r *web.Router, prefix, id string, title web.LocaleStringer, doc *openapi.Document) {
+	h := &handlers[T]{
+		rbac:     rbac,
+		parseUID: uidParser[T](),
+		viewID:   joinID(id, "view"),
+		roleID:   joinID(id, "role"),
+		resID:    joinID(id, "resource"),
+		userID:   joinID(id, "user"),
+	}
+
+	g := rbac.NewResourceGroup(id, title)
+	view := g.New("view", web.Phrase("view rbac"))
+	role := g.New("role", web.Phrase("edit roles"))
+	res := g.New("resource", web.Phrase("edit role resources"))
+	user := g.New("user", web.Phrase("link users"))
+
+	uidType := openapi.TypeString
+	if kind := reflect.TypeFor[T]().Kind(); kind != reflect.String {
+		uidType = openapi.TypeInteger
+	}
+
+	api := func(m web.Middleware, f func(o *openapi.Operation)) []web.Middleware {
+		if doc == nil {
+			return []web.Middleware{m}
+		}
+		return []web.Middleware{m, doc.API(func(o *openapi.Operation) {
+			o.Tag("rbac")
+			f(o)
+		})}
+	}
+	group := func(o *openapi.Operation) *openapi.Operation {
+		return o.Path("group", openapi.TypeString, web.Phrase("the id of role group"), nil)
+	}
+	groupRole := func(o *openapi.Operation) *openapi.Operation {
+		return group(o).Path("role", openapi.TypeString, web.Phrase("the id of role"), nil)
+	}
+
+	p := r.Prefix(prefix)
+
+	p.Get("/resources", h.getResources, api(view, func(o *openapi.Operation) {
+		o.Desc(web.Phrase("get all resources"), nil).
+			Response200([]*Resources{})
+	})...)
+
+	p.Get("/groups/{group}/roles", h.getRoles, api(view, func(o *openapi.Operation) {
+		group(o).Desc(web.Phrase("get roles of group"), nil).
+			Response200([]*RoleInfo[T]{})
+	})...)
+
+	p.Post("/groups/{group}/roles", h.postRole, api(role, func(o *openapi.Operation) {
+		group(o).Desc(web.Phrase("add role"), nil).
+			Body(&RoleRequest{}, false, nil, nil).
+			Response("201", &RoleInfo[T]{}, nil, nil)
+	})...)
+
+	p.Get("/groups/{group}/roles/{role}", h.getRole, api(view, func(o *openapi.Operation) {
+		groupRole(o).Desc(web.Phrase("get role"), nil).
+			Response200(&RoleInfo[T]{})
+	})...)
+
+	p.Put("/groups/{group}/roles/{role}", h.putRole, api(role, func(o *openapi.Operation) {
+		groupRole(o).Desc(web.Phrase("edit role"), nil).
+			Body(&RoleRequest{}, false, nil, nil).
+			ResponseEmpty("204")
+	})...)
+
+	p.Delete("/groups/{group}/roles/{role}", h.deleteRole, api(role, func(o *openapi.Operation) {
+		groupRole(o).Desc(web.Phrase("delete role"), nil).
+			ResponseEmpty("204")
+	})...)
+
+	p.Get("/groups/{group}/roles/{role}/resources", h.getRoleResources, api(view, func(o *openapi.Operation) {
+		groupRole(o).Desc(web.Phrase("get resources of role"), nil).
+			Response200(&RoleResources{})
+	})...)
+
+	p.Put("/groups/{group}/roles/{role}/resources", h.putRoleResources, api(res, func(o *openapi.Operation) {
+		groupRole(o).Desc(web.Phrase("set resources of role"), nil).
+			Body(&ResourcesRequest{}, false, nil, nil).
+			ResponseEmpty("204")
+	})...)
+
+	p.Put("/groups/{group}/roles/{role}/users/{uid}", h.linkUser, api(user, func(o *openapi.Operation) {
+		groupRole(o).Path("uid", uidType, web.Phrase("the id of user"), nil).
+			Desc(web.Phrase("link user to role"), nil).
+			ResponseEmpty("204")
+	})...)
+
+	p.Delete("/groups/{group}/roles/{role}/users/{uid}", h.unlinkUser, api(user, func(o *openapi.Operation) {
+		groupRole(o).Path("uid", uidType, web.Phrase("the id of user"), nil).
+			Desc(web.Phrase("unlink user from role"), nil).
+			ResponseEmpty("204")
+	})...)
+
+	p.Get("/groups/{group}/users/{uid}/roles", h.getUserRoles, api(view, func(o *openapi.Operation) {
+		group(o).Path("uid", uidType, web.Phrase("the id of user"), nil).
+			Desc(web.Phrase("get roles of user"), nil).
+			Response200([]*RoleInfo[T]{})
+	})...)
+
+	p.Get("/explain", h.explain, api(view, func(o *openapi.Operation) {
+		o.Query("uid", uidType, web.Phrase("the id of user"), nil).
+			Query("resource", openapi.TypeString, web.Phrase("the id of resource"), nil).
+			Query("tenant", openapi.TypeString, web.Phrase("the id of tenant"), nil).
+			Desc(web.Phrase("explain the access of user"), nil).
+			Response200(&Explanation[T]{})
+	})...)
+}
+
+// 根据 T 的类型返回将字符串转换为 T 的函数
+func uidParser[T comparable]() func(string) (T, error) {
+	t := reflect.TypeFor[T]()
+	switch t.Kind() {
+	case reflect.String:
+		return func(s string) (uid T, err error) {
+			reflect.ValueOf(&uid).Elem().SetString(s)
+			return uid, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(s string) (uid T, err error) {
+			v, err := strconv.ParseInt(s, 10, t.Bits())
+			if err != nil {
+				return uid, err
+			}
+			reflect.ValueOf(&uid).Elem().SetInt(v)
+			return uid, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(s string) (uid T, err error) {
+			v, err := strconv.ParseUint(s, 10, t.Bits())
+			if err != nil {
+				return uid, err
+			}
+			reflect.ValueOf(&uid).Elem().SetUint(v)
+			return uid, nil
+		}
+	default:
+		panic(fmt.Sprintf("不支持的用户 ID 类型 %s", t))
+	}
+}
+
+// 返回路由参数 group 指定的角色组
+//
+// res 为当前接口对应的资源，用户需要在该角色组中拥有此资源。
+func (h *handlers[T]) group(ctx *web.Context, res string) (*RoleGroup[T], web.Responser) {
+	id, resp := ctx.PathString("group", web.ProblemBadRequest)
+	if resp != nil {
+		return nil, resp
+	}
+
+	g := h.rbac.roleGroup(id)
+	if g == nil {
+		return nil, ctx.NotFound()
+	}
+
+	uid, tenant, resp := h.rbac.getUID(ctx)
+	if resp != nil {
+		return nil, resp
+	}
+	if (tenant != "" && tenant != id) || !g.isAllow(uid, res) {
+		return nil, ctx.Problem(web.ProblemForbidden)
+	}
+	return g, nil
+}
+
+func (h *handlers[T]) role(ctx *web.Context, res string) (*Role[T], web.Responser) {
+	g, resp := h.group(ctx, res)
+	if resp != nil {
+		return nil, resp
+	}
+
+	id, resp := ctx.PathString("role", web.ProblemBadRequest)
+	if resp != nil {
+		return nil, resp
+	}
+
+	r := g.Role(id)
+	if r == nil {
+		return nil, ctx.NotFound()
+	}
+	return r, nil
+}
+
+func (h *handlers[T]) uid(ctx *web.Context) (T, web.Responser) {
+	str, resp := ctx.PathString("uid", web.ProblemBadRequest)
+	if resp != nil {
+		var zero T
+		return zero, resp
+	}
+
+	uid, err := h.parseUID(str)
+	if err != nil {
+		return uid, ctx.Problem(web.ProblemBadRequest).WithParam("uid", err.Error())
+	}
+	return uid, nil
+}
+
+func (h *handlers[T]) getResources(ctx *web.Context) web.Responser {
+	return web.OK(h.rbac.Resources(ctx.LocalePrinter()))
+}
+
+func (h *handlers[T]) getRoles(ctx *web.Context) web.Responser {
+	g, resp := h.group(ctx, h.viewID)
+	if resp != nil {
+		return resp
+	}
+
+	roles := make([]*RoleInfo[T], 0, 10)
+	for r := range g.Roles() {
+		roles = append(roles, newRoleInfo(r))
+	}
+	slices.SortFunc(roles, func(a, b *RoleInfo[T]) int { return cmp.Compare(a.ID, b.ID) })
+	return web.OK(roles)
+}
+
+func (h *handlers[T]) postRole(ctx *web.Context) web.Responser {
+	g, resp := h.group(ctx, h.roleID)
+	if resp != nil {
+		return resp
+	}
+
+	data := &RoleRequest{}
+	if resp := ctx.Read(true, data, web.ProblemUnprocessableEntity); resp != nil {
+		return resp
+	}
+	if data.Parent != "" && g.Role(data.Parent) == nil {
+		return ctx.Problem(web.ProblemUnprocessableEntity).
+			WithParam("parent", web.Phrase("not found role %s", data.Parent).LocaleString(ctx.LocalePrinter()))
+	}
+
+	r, err := g.NewRole(data.Name, data.Desc, data.Parent)
+	if err != nil {
+		return ctx.Error(err, web.ProblemInternalServerError)
+	}
+	return web.Created(newRoleInfo(r), "")
+}
+
+func (h *handlers[T]) getRole(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.viewID)
+	if resp != nil {
+		return resp
+	}
+	return web.OK(newRoleInfo(r))
+}
+
+func (h *handlers[T]) putRole(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.roleID)
+	if resp != nil {
+		return resp
+	}
+
+	data := &RoleRequest{}
+	if resp := ctx.Read(true, data, web.ProblemUnprocessableEntity); resp != nil {
+		return resp
+	}
+
+	if err := r.Set(data.Name, data.Desc); err != nil {
+		return ctx.Error(err, web.ProblemInternalServerError)
+	}
+	return web.NoContent()
+}
+
+func (h *handlers[T]) deleteRole(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.roleID)
+	if resp != nil {
+		return resp
+	}
+
+	if err := r.Del(); err != nil {
+		return ctx.Error(err, web.ProblemConflict)
+	}
+	return web.NoContent()
+}
+
+func (h *handlers[T]) getRoleResources(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.viewID)
+	if resp != nil {
+		return resp
+	}
+	return web.OK(r.Resource())
+}
+
+func (h *handlers[T]) putRoleResources(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.resID)
+	if resp != nil {
+		return resp
+	}
+
+	data := &ResourcesRequest{}
+	if resp := ctx.Read(true, data, web.ProblemUnprocessableEntity); resp != nil {
+		return resp
+	}
+
+	if err := r.SetResources(data.Resources, data.Denied); err != nil {
+		return ctx.Error(err, web.ProblemUnprocessableEntity)
+	}
+	return web.NoContent()
+}
+
+func (h *handlers[T]) linkUser(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.userID)
+	if resp != nil {
+		return resp
+	}
+
+	uid, resp := h.uid(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	if err := r.Link(uid); err != nil {
+		return ctx.Error(err, web.ProblemConflict)
+	}
+	return web.NoContent()
+}
+
+func (h *handlers[T]) unlinkUser(ctx *web.Context) web.Responser {
+	r, resp := h.role(ctx, h.userID)
+	if resp != nil {
+		return resp
+	}
+
+	uid, resp := h.uid(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	if err := r.Unlink(uid); err != nil {
+		return ctx.Error(err, web.ProblemInternalServerError)
+	}
+	return web.NoContent()
+}
+
+func (h *handlers[T]) getUserRoles(ctx *web.Context) web.Responser {
+	g, resp := h.group(ctx, h.viewID)
+	if resp != nil {
+		return resp
+	}
+
+	uid, resp := h.uid(ctx)
+	if resp != nil {
+		return resp
+	}
+
+	roles := make([]*RoleInfo[T], 0, 5)
+	for _, r := range g.UserRoles(uid) {
+		roles = append(roles, newRoleInfo(r))
+	}
+	slices.SortFunc(roles, func(a, b *RoleInfo[T]) int { return cmp.Compare(a.ID, b.ID) })
+	return web.OK(roles)
+}
+
+func (h *handlers[T]) explain(ctx *web.Context) web.Responser {
+	q, err := ctx.Queries(true)
+	if err != nil {
+		return ctx.Error(err, web.ProblemBadRequest)
+	}
+
+	uid, err := h.parseUID(q.String("uid", ""))
+	if err != nil {
+		return ctx.Problem(web.ProblemBadRequest).WithParam("uid", err.Error())
+	}
+
+	res := q.String("resource", "")
+	if res == "" {
+		return ctx.Problem(web.ProblemBadRequest).WithParam("resource", web.Phrase("can not be empty").LocaleString(ctx.LocalePrinter()))
+	}
+
+	_, current, resp := h.rbac.getUID(ctx)
+	if resp != nil {
+		return resp
+	}
+	tenant := q.String("tenant", current)
+	if current != "" && tenant != current {
+		return ctx.Problem(web.ProblemForbidden)
+	}
+
+	e, err := h.rbac.ExplainTenant(uid, tenant, res)
+	if err != nil {
+		return ctx.Error(err, web.ProblemInternalServerError)
+	}
+	return web.OK(e)
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/openapi"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func TestRBAC_RegisterHandlers(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[int64](s, "c_"), func(ctx *web.Context) (int64, string, web.Responser) {
+		uid, err := strconv.ParseInt(ctx.Request().Header.Get("x-uid"), 10, 64)
+		if err != nil {
+			return 0, "", ctx.Problem(web.ProblemUnauthorized)
+		}
+		return uid, ctx.Request().Header.Get("x-tenant"), nil
+	})
+	g, err := rbac.NewRoleGroup("g1", 1)
+	a.NotError(err).NotNil(g)
+
+	doc := openapi.New(s, web.Phrase("test"))
+	r := s.Routers().New("def", nil)
+	rbac.RegisterHandlers(r, "/rbac", "rbac", web.Phrase("rbac"), doc)
+	r.Get("/openapi", doc.Handler())
+	a.PanicString(func() { (&RBAC[float64]{}).RegisterHandlers(r, "/rbac2", "rbac2", nil, nil) }, "不支持的用户 ID 类型 float64")
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/openapi").
+		Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.Contains(string(body), "/rbac/groups/{group}/roles/{role}/users/{uid}")
+		})
+
+	const prefix = "http://localhost:8080/rbac"
+
+	// 非超级管理员，没有权限
+	servertest.Get(a, prefix+"/resources").
+		Header("x-uid", "2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Get(a, prefix+"/resources").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			res := []*Resources{}
+			a.NotError(json.Unmarshal(body, &res)).Length(res, 1).Length(res[0].Items, 4)
+		})
+
+	servertest.Get(a, prefix+"/groups/not-exists/roles").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusNotFound)
+	_, found := rbac.roleGroups["not-exists"]
+	a.False(found)
+
+	// 租户的管理员只能管理自己的租户
+
+	t1, err := rbac.TenantRoleGroup("t1")
+	a.NotError(err).NotNil(t1)
+	t2, err := rbac.TenantRoleGroup("t2")
+	a.NotError(err).NotNil(t2)
+	admin, err := t1.NewRole("admin", "", "")
+	a.NotError(err).
+		NotError(admin.Allow(joinID("rbac", wildcard))).
+		NotError(admin.Link(3))
+
+	servertest.Get(a, prefix+"/groups/t1/roles").
+		Header("x-uid", "3").
+		Header("x-tenant", "t1").
+		Do(nil).
+		Status(http.StatusOK)
+
+	servertest.Post(a, prefix+"/groups/t2/roles", []byte(`{"name":"r1"}`)).
+		Header("x-uid", "3").
+		Header("x-tenant", "t1").
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Post(a, prefix+"/groups/t2/roles", []byte(`{"name":"r1"}`)).
+		Header("x-uid", "3").
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 其它角色组的超级管理员
+	servertest.Get(a, prefix+"/groups/t2/roles").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusForbidden)
+	a.Empty(slices.Collect(t2.Roles()))
+
+	// 添加角色
+
+	servertest.Post(a, prefix+"/groups/g1/roles", []byte(`{"name":""}`)).
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity)
+
+	servertest.Post(a, prefix+"/groups/g1/roles", []byte(`{"name":"r1","parent":"not-exists"}`)).
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity)
+
+	var r1 *RoleInfo[int64]
+	servertest.Post(a, prefix+"/groups/g1/roles", []byte(`{"name":"r1","desc":"desc"}`)).
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		Do(nil).
+		Status(http.StatusCreated).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.NotError(json.Unmarshal(body, &r1)).Equal(r1.Name, "r1").NotEmpty(r1.ID)
+		})
+	role := prefix + "/groups/g1/roles/" + r1.ID
+
+	servertest.NewRequest(a, http.MethodPut, role).
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		StringBody(`{"name":"r2","desc":"desc2"}`).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, role).
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			info := &RoleInfo[int64]{}
+			a.NotError(json.Unmarshal(body, info)).Equal(info.Name, "r2").Equal(info.Desc, "desc2")
+		})
+
+	servertest.Get(a, prefix+"/groups/g1/roles/not-exists").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	// 资源
+
+	servertest.NewRequest(a, http.MethodPut, role+"/resources").
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		StringBody(`{"resources":["rbac_view","rbac_view"]}`).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity)
+
+	servertest.NewRequest(a, http.MethodPut, role+"/resources").
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		StringBody(`{"resources":["not-exists"]}`).
+		Do(nil).
+		Status(http.StatusUnprocessableEntity)
+
+	servertest.NewRequest(a, http.MethodPut, role+"/resources").
+		Header("x-uid", "1").
+		Header(header.ContentType, header.JSON).
+		StringBody(`{"resources":["rbac_view"]}`).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, role+"/resources").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			res := &RoleResources{}
+			a.NotError(json.Unmarshal(body, res)).Equal(res.Current, []string{"rbac_view"})
+		})
+
+	// 用户
+
+	servertest.NewRequest(a, http.MethodPut, role+"/users/abc").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	servertest.NewRequest(a, http.MethodPut, role+"/users/2").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, prefix+"/groups/g1/users/2/roles").
+		Header("x-uid", "2"). // 已经拥有 view 权限
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			roles := []*RoleInfo[int64]{}
+			a.NotError(json.Unmarshal(body, &roles)).Length(roles, 1).Equal(roles[0].Users, []int64{2})
+		})
+
+	servertest.NewRequest(a, http.MethodPut, role+"/users/3").
+		Header("x-uid", "2"). // 没有 user 权限
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Delete(a, role).
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusConflict) // 还有关联的用户
+
+	servertest.Delete(a, role+"/users/2").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Delete(a, role).
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, prefix+"/groups/g1/roles").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody("[]")
+
+	// explain
+
+	servertest.Get(a, prefix+"/explain?uid=2&resource=rbac_view").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			e := &Explanation[int64]{}
+			a.NotError(json.Unmarshal(body, e)).
+				Equal(e.UID, 2).
+				False(e.Allowed).
+				Equal(e.Reason, ReasonNotGranted)
+		})
+
+	// 租户的管理员只能查询自己的租户
+	servertest.Get(a, prefix+"/explain?uid=3&resource=rbac_view").
+		Header("x-uid", "3").
+		Header("x-tenant", "t1").
+		Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			e := &Explanation[int64]{}
+			a.NotError(json.Unmarshal(body, e)).
+				Equal(e.Tenant, "t1").
+				True(e.Allowed)
+		})
+
+	servertest.Get(a, prefix+"/explain?uid=3&resource=rbac_view&tenant=t2").
+		Header("x-uid", "3").
+		Header("x-tenant", "t1").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Get(a, prefix+"/explain?uid=x&resource=rbac_view").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	servertest.Get(a, prefix+"/explain?uid=2").
+		Header("x-uid", "1").
+		Do(nil).
+		Status(http.StatusBadRequest)
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import "github.com/issue9/web"
+
+type contextType int
+
+const objectKey contextType = 1
+
+type (
+	// Condition 访问资源时的附加条件
+	//
+	// uid 为当前用户的 ID；obj 为需要访问的对象，可能为 nil；
+	// 路由参数等请求相关的数据可以通过 ctx 获取。
+	Condition[T comparable] func(ctx *web.Context, uid T, obj any) bool
+
+	// LoadFunc 加载需要访问的对象
+	//
+	// 比如根据路由参数从数据库中加载文章，找不到时可以返回 [web.ProblemNotFound] 等错误。
+	LoadFunc func(*web.Context) (any, web.Responser)
+)
+
+// Check 判断当前用户是否可以访问资源 res 中的对象 obj
+//
+// 除了判断用户是否拥有资源 res 的权限之外，
+// 如果资源是通过 [ResourceGroup.NewPolicy] 声明的，还会判断 obj 是否满足其条件。
+// 返回 nil 表示可以访问，否则返回的是需要输出给客户端的错误信息。
+//
+// 一般用于在处理函数中判断，比如列表中的每一项是否可编辑。
+func (rbac *RBAC[T]) Check(ctx *web.Context, res string, obj any) web.Responser {
+	uid, tenant, skip, resp := rbac.checkResource(ctx, res)
+	if resp != nil || skip {
+		return resp
+	}
+	return rbac.checkCondition(ctx, uid, tenant, res, obj)
+}
+
+// 判断当前用户是否拥有资源 res 的权限，不包含附加条件。
+//
+// skip 表示是否跳过附加条件的判断。超级管理员不受附加条件的限制，
+// 指定了租户时，只有该租户角色组的超级管理员才算；
+// 在 dry-run 模式下，无权限时 resp 为 nil，此时也不再需要判断附加条件。
+func (rbac *RBAC[T]) checkResource(ctx *web.Context, res string) (uid T, tenant string, skip bool, resp web.Responser) {
+	uid, tenant, resp = rbac.getUID(ctx)
+	if resp != nil {
+		return uid, tenant, false, resp
+	}
+
+	if rbac.resourceGroupOf(res) == nil {
+		return uid, tenant, true, rbac.forbidden(ctx, uid, tenant, res, false)
+	}
+
+	if tenant == "" {
+		if !rbac.isAllow(uid, res) {
+			return uid, tenant, true, rbac.forbidden(ctx, uid, tenant, res, false)
+		}
+		return uid, tenant, rbac.isSuper(uid), nil
+	}
+
+	rg, err := rbac.TenantRoleGroup(tenant)
+	if err != nil {
+		return uid, tenant, false, ctx.Error(err, web.ProblemInternalServerError)
+	}
+	if !rg.isAllow(uid, res) {
+		return uid, tenant, true, rbac.forbidden(ctx, uid, tenant, res, false)
+	}
+	return uid, tenant, rg.isSuper(uid), nil
+}
+
+// 判断对象 obj 是否满足资源 res 的附加条件
+func (rbac *RBAC[T]) checkCondition(ctx *web.Context, uid T, tenant string, res string, obj any) web.Responser {
+	g := rbac.resourceGroupOf(res)
+	if g == nil {
+		return nil
+	}
+	if cond, found := g.conds[res]; found && !cond(ctx, uid, obj) {
+		return rbac.forbidden(ctx, uid, tenant, res, true)
+	}
+	return nil
+}
+
+// Object 获取由 [LoadFunc] 加载的对象
+func Object[O any](ctx *web.Context) (obj O, found bool) {
+	if v, found := ctx.GetVar(objectKey); found {
+		obj, found = v.(O)
+		return obj, found
+	}
+	return obj, false
+}
+
+// Owner 要求用户为对象的所有者
+//
+// owner 返回对象 obj 的所有者 ID，如果对象的类型不为 O，则不满足条件。
+func Owner[T comparable, O any](owner func(obj O) T) Condition[T] {
+	return func(_ *web.Context, uid T, obj any) bool {
+		o, ok := obj.(O)
+		return ok && owner(o) == uid
+	}
+}
+
+// Param 要求路由参数 name 的值与用户的属性相同
+//
+// attr 返回用户的属性值，比如用户所在的租户 ID，路由参数不存在时不满足条件。
+func Param[T comparable](name string, attr func(ctx *web.Context, uid T) string) Condition[T] {
+	return func(ctx *web.Context, uid T, _ any) bool {
+		v, found := ctx.Route().Params().Get(name)
+		return found && v == attr(ctx, uid)
+	}
+}
+
+// And 需要满足所有的条件
+func And[T comparable](cond ...Condition[T]) Condition[T] {
+	return func(ctx *web.Context, uid T, obj any) bool {
+		for _, c := range cond {
+			if !c(ctx, uid, obj) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or 只需要满足其中一个条件
+func Or[T comparable](cond ...Condition[T]) Condition[T] {
+	return func(ctx *web.Context, uid T, obj any) bool {
+		for _, c := range cond {
+			if c(ctx, uid, obj) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not 不满足条件 cond
+func Not[T comparable](cond Condition[T]) Condition[T] {
+	return func(ctx *web.Context, uid T, obj any) bool { return !cond(ctx, uid, obj) }
+}
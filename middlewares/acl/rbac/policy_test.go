@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+type article struct {
+	id     string
+	author string
+}
+
+func TestResourceGroup_NewPolicy(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	articles := map[string]*article{
+		"1": {id: "1", author: "u1"},
+		"2": {id: "2", author: "u2"},
+	}
+	tenants := map[string]string{"u1": "t1", "u2": "t2"}
+	var loaded int
+
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(ctx *web.Context) (string, string, web.Responser) {
+		q, err := ctx.Queries(true)
+		if err != nil {
+			return "", "", ctx.Error(err, "")
+		}
+		return q.String("uid", ""), "", nil
+	})
+	rg, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(rg)
+
+	g := rbac.NewResourceGroup("articles", web.Phrase("articles"))
+	edit := g.NewPolicy("edit", web.Phrase("edit"), Owner(func(obj *article) string { return obj.author }), func(ctx *web.Context) (any, web.Responser) {
+		loaded++
+		id, resp := ctx.PathString("id", web.ProblemBadRequest)
+		if resp != nil {
+			return nil, resp
+		}
+		if art, found := articles[id]; found {
+			return art, nil
+		}
+		return nil, ctx.NotFound()
+	})
+	view := g.NewPolicy("view", web.Phrase("view"), Param("tenant", func(_ *web.Context, uid string) string { return tenants[uid] }), nil)
+	list := g.NewPolicy("list", web.Phrase("list"), Or(
+		Param("tenant", func(_ *web.Context, uid string) string { return tenants[uid] }),
+		And(
+			func(_ *web.Context, uid string, _ any) bool { return uid == "u2" },
+			Not(Owner(func(obj *article) string { return obj.author })), // obj 为空
+		),
+	), nil)
+
+	r, err := rg.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r)
+	a.NotError(r.Allow(joinID("articles", "edit"), joinID("articles", "view"), joinID("articles", "list"))).
+		NotError(r.Link("u1")).
+		NotError(r.Link("u2"))
+
+	router := s.Routers().New("def", nil)
+	router.Put("/articles/{id}", func(ctx *web.Context) web.Responser {
+		art, found := Object[*article](ctx)
+		a.True(found).NotNil(art)
+		_, found = Object[string](ctx)
+		a.False(found)
+
+		ctx.Header().Set("x-id", art.id)
+		return web.NoContent()
+	}, edit)
+	router.Get("/tenants/{tenant}/articles", func(ctx *web.Context) web.Responser {
+		_, found := Object[*article](ctx)
+		a.False(found)
+
+		// Check 用于处理函数之中
+		if resp := rbac.Check(ctx, joinID("articles", "edit"), articles["1"]); resp != nil {
+			ctx.Header().Set("x-edit", "false")
+		} else {
+			ctx.Header().Set("x-edit", "true")
+		}
+		return web.OK(nil)
+	}, view)
+	router.Get("/tenants/{tenant}/list", func(ctx *web.Context) web.Responser { return web.OK(nil) }, list)
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/1?uid=u1").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header("x-id", "1")
+
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/1?uid=u2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 没有角色
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/1?uid=u3").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 没有权限的用户不会加载对象，也无法通过状态码判断对象是否存在
+	loaded = 0
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/3?uid=u3").
+		Do(nil).
+		Status(http.StatusForbidden)
+	a.Equal(loaded, 0)
+
+	// 超级管理员不受条件限制
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/2?uid=admin").
+		Do(nil).
+		Status(http.StatusNoContent).
+		Header("x-id", "2")
+
+	servertest.NewRequest(a, http.MethodPut, "http://localhost:8080/articles/3?uid=u1").
+		Do(nil).
+		Status(http.StatusNotFound)
+
+	servertest.Get(a, "http://localhost:8080/tenants/t1/articles?uid=u1").
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-edit", "true")
+
+	servertest.Get(a, "http://localhost:8080/tenants/t2/articles?uid=u2").
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-edit", "false")
+
+	servertest.Get(a, "http://localhost:8080/tenants/t2/articles?uid=u1").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Get(a, "http://localhost:8080/tenants/t2/list?uid=u1").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Get(a, "http://localhost:8080/tenants/t1/list?uid=u1").
+		Do(nil).
+		Status(http.StatusOK)
+
+	servertest.Get(a, "http://localhost:8080/tenants/t1/list?uid=u2").
+		Do(nil).
+		Status(http.StatusOK)
+}
@@ -16,9 +16,22 @@
 //	router.Delete("/users/{id}", del(func(*web.Context)web.Responser{
 //	    // do somthing
 //	}))
+//
+// 如果还需要根据访问的对象进行判断，可以使用 [ResourceGroup.NewPolicy]：
+//
+//	edit := group.NewPolicy("edit", web.Phrase("edit article"), rbac.Owner(func(a *Article) int64 {
+//	    return a.Author
+//	}), loadArticle)
 package rbac
 
-import "github.com/issue9/web"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issue9/web"
+)
 
 // RBAC RBAC 实现
 //
@@ -31,18 +44,38 @@ type RBAC[T comparable] struct {
 	resources      []string // 缓存的所有资源项
 	resourceGroups map[string]*ResourceGroup[T]
 
+	groupsMux  *sync.RWMutex
 	roleGroups map[string]*RoleGroup[T]
+	loading    map[string]*groupLoader[T] // 正在加载的租户
+
+	// 用户至其所在的角色组的索引，以及所有的超级管理员。
+	// 这样在判断权限时，只需要查找用户真正关联的角色组。
+	indexMux   *sync.RWMutex
+	userGroups map[T]map[string]*RoleGroup[T]
+	supers     map[T]struct{}
+
+	dryRun *atomic.Bool
 }
 
 // GetUIDFunc 从 [web.Context] 获得当前的登录用户 ID
-type GetUIDFunc[T comparable] func(*web.Context) (T, web.Responser)
+//
+// tenant 表示用户当前所在的租户，如果不为空，则只在该租户的角色组中判断权限，
+// 参考 [RBAC.TenantRoleGroup]；否则在所有已经加载的角色组中判断。
+type GetUIDFunc[T comparable] func(*web.Context) (uid T, tenant string, resp web.Responser)
+
+type groupLoader[T comparable] struct {
+	once sync.Once
+	g    *RoleGroup[T]
+	err  error
+}
 
 // New 声明 [RBAC]
 //
 // getUID 参考 [GetUIDFunc]；
-// loadInterval 如果大于 0，表示以该频率从 [Store] 加载数据；
+//
+// 如果 store 实现了 [Watcher]，会在其它节点修改数据之后自动重新加载相应的角色组。
 func New[T comparable](s web.Server, store Store[T], getUID GetUIDFunc[T]) *RBAC[T] {
-	return &RBAC[T]{
+	rbac := &RBAC[T]{
 		s:      s,
 		store:  store,
 		getUID: getUID,
@@ -50,6 +83,94 @@ func New[T comparable](s web.Server, store Store[T], getUID GetUIDFunc[T]) *RBAC
 		resources:      make([]string, 0, 100),
 		resourceGroups: make(map[string]*ResourceGroup[T], 50),
 
+		groupsMux:  &sync.RWMutex{},
 		roleGroups: make(map[string]*RoleGroup[T], 50),
+		loading:    make(map[string]*groupLoader[T], 10),
+
+		indexMux:   &sync.RWMutex{},
+		userGroups: make(map[T]map[string]*RoleGroup[T], 100),
+		supers:     make(map[T]struct{}, 50),
+
+		dryRun: &atomic.Bool{},
+	}
+
+	if w, ok := store.(Watcher); ok {
+		s.Services().AddFunc(web.Phrase("watch rbac changes"), func(ctx context.Context) error {
+			return w.Watch(ctx, rbac.reload)
+		})
+	}
+
+	return rbac
+}
+
+// ScheduleCleanup 每隔 interval 执行一次 [RBAC.CleanExpired]
+//
+// 通过 [web.Server.Services] 注册为定时任务，随服务一起启动和停止。
+// 多节点部署时，只需要在其中一个节点上调用。
+func (rbac *RBAC[T]) ScheduleCleanup(interval time.Duration) {
+	if interval <= 0 {
+		panic("参数 interval 必须大于 0")
+	}
+	rbac.s.Services().AddTicker(web.Phrase("clean expired rbac memberships"), rbac.CleanExpired, interval, false, false)
+}
+
+// 重新加载被其它节点修改的角色组
+func (rbac *RBAC[T]) reload(gid string, ver uint64) {
+	g := rbac.roleGroup(gid)
+	if g == nil || g.Version() >= ver {
+		return
+	}
+
+	if err := g.Load(); err != nil {
+		rbac.s.Logs().ERROR().Error(err)
+	}
+}
+
+// 用户 uid 是否为某一角色组的超级管理员
+func (rbac *RBAC[T]) isSuper(uid T) bool {
+	rbac.indexMux.RLock()
+	defer rbac.indexMux.RUnlock()
+	_, found := rbac.supers[uid]
+	return found
+}
+
+// 用户 uid 是否可访问资源 res
+func (rbac *RBAC[T]) isAllow(uid T, res string) bool {
+	rbac.indexMux.RLock()
+	_, super := rbac.supers[uid]
+	groups := make([]*RoleGroup[T], 0, len(rbac.userGroups[uid]))
+	for _, g := range rbac.userGroups[uid] {
+		groups = append(groups, g)
+	}
+	rbac.indexMux.RUnlock()
+
+	if super {
+		return true
+	}
+
+	for _, g := range groups {
+		if g.isAllow(uid, res) {
+			return true
+		}
+	}
+	return false
+}
+
+// 更新用户 uid 在角色组 g 中的索引
+func (rbac *RBAC[T]) setUserGroup(uid T, g *RoleGroup[T], exists bool) {
+	rbac.indexMux.Lock()
+	defer rbac.indexMux.Unlock()
+
+	groups, found := rbac.userGroups[uid]
+	switch {
+	case exists && !found:
+		rbac.userGroups[uid] = map[string]*RoleGroup[T]{g.id: g}
+	case exists:
+		groups[g.id] = g
+	case found:
+		delete(groups, g.id)
+		if len(groups) == 0 {
+			delete(rbac.userGroups, uid)
+		}
 	}
 }
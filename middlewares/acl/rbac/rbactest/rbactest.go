@@ -36,6 +36,8 @@ func Test[T comparable](a *assert.Assertion, s rbac.Store[T]) {
 	// Set
 
 	r1.Name = "name"
+	r1.Resources = []string{"res_1", "res_*"}
+	r1.Denied = []string{"res_2"}
 	a.NotError(s.Set("g1", r1))
 
 	// Del
@@ -49,5 +51,8 @@ func Test[T comparable](a *assert.Assertion, s rbac.Store[T]) {
 	all, err := s.Load("g1")
 	a.NotError(err).
 		Length(all, 3)
-	a.Equal(all[r1.ID].Name, "name") // 在 set 中被修改
+	// 在 set 中被修改
+	a.Equal(all[r1.ID].Name, "name").
+		Equal(all[r1.ID].Denied, []string{"res_2"}).
+		Length(all[r1.ID].Resources, 2)
 }
@@ -7,6 +7,7 @@ package rbac
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"net/http"
 	"slices"
 	"strings"
@@ -15,7 +16,10 @@ import (
 	"golang.org/x/text/message"
 )
 
-const idSeparator = '_'
+const (
+	idSeparator = '_'
+	wildcard    = "*" // 表示资源组中的所有资源
+)
 
 // ResourceGroup 资源组
 type ResourceGroup[T comparable] struct {
@@ -23,6 +27,7 @@ type ResourceGroup[T comparable] struct {
 	id    string
 	title web.LocaleStringer
 	items map[string]web.LocaleStringer
+	conds map[string]Condition[T] // 资源的附加条件
 }
 
 // Resources 资源
@@ -48,22 +53,56 @@ type RoleResources struct {
 	// 必然也是当前角色所能访问的最大资源列表。
 	//
 	// Parent 肯定是包含了 Current 的所有值。
+	//
+	// 如果是顶级角色，还会包含各个资源组的通配符，比如 group_*。
 	Parent []string `json:"parent" xml:"parent" yaml:"parent" cbor:"parent" comment:"resources of role parent"`
+
+	// Denied 当前角色明确拒绝访问的资源
+	//
+	// 优先级高于 Current，同时也会作用于所有的子角色。
+	Denied []string `json:"denied,omitempty" xml:"denied,omitempty" yaml:"denied,omitempty" cbor:"denied,omitempty" comment:"denied resources of current role"`
 }
 
 // resourceExists 指定的资源 ID 是否存在
+//
+// 资源组的通配符也被当作是存在的资源。
 func (rbac *RBAC[T]) resourceExists(id string) bool {
+	if gid, ok := strings.CutSuffix(id, string(idSeparator)+wildcard); ok {
+		_, found := rbac.resourceGroups[gid]
+		return found
+	}
+	return rbac.resourceGroupOf(id) != nil
+}
+
+// 返回资源 id 所在资源组的通配符
+//
+// 如果 id 本身就是通配符，返回其本身。
+func wildcardOf(id string) string {
+	if index := strings.IndexByte(id, idSeparator); index >= 0 {
+		return joinID(id[:index], wildcard)
+	}
+	return id
+}
+
+// list 中是否包含了资源 id，包括通配符的匹配。
+func containsResource(list []string, id string) bool {
+	return slices.Contains(list, id) || slices.Contains(list, wildcardOf(id))
+}
+
+// 返回资源 id 所在的资源组，如果资源不存在，返回 nil。
+func (rbac *RBAC[T]) resourceGroupOf(id string) *ResourceGroup[T] {
 	index := strings.IndexByte(id, idSeparator)
 	if index < 0 {
-		return false
+		return nil
 	}
 
 	gid := id[:index]
 	if g, found := rbac.resourceGroups[gid]; found {
-		_, f := g.items[id]
-		return f
+		if _, f := g.items[id]; f {
+			return g
+		}
 	}
-	return false
+	return nil
 }
 
 // NewResourceGroup 声明一组资源
@@ -80,6 +119,7 @@ func (rbac *RBAC[T]) NewResourceGroup(id string, title web.LocaleStringer) *Reso
 		id:    id,
 		title: title,
 		items: make(map[string]web.LocaleStringer, 10),
+		conds: make(map[string]Condition[T], 10),
 	}
 	rbac.resourceGroups[id] = res
 	return res
@@ -91,16 +131,35 @@ func joinID(gid, id string) string { return gid + string(idSeparator) + id }
 
 func (g *ResourceGroup[T]) RBAC() *RBAC[T] { return g.rbac }
 
+// Wildcard 表示当前资源组中所有资源的 ID
+//
+// 可以作为 [Role.Allow] 和 [Role.Deny] 的参数。
+func (g *ResourceGroup[T]) Wildcard() string { return joinID(g.id, wildcard) }
+
 // New 添加新的资源
 //
 // 返回的是用于判断是否拥有当前资源权限的中间件。
 func (g *ResourceGroup[T]) New(id string, desc web.LocaleStringer) web.MiddlewareFunc {
+	return g.NewPolicy(id, desc, nil, nil)
+}
+
+// NewPolicy 添加带附加条件的资源
+//
+// 用户除了需要拥有该资源的权限之外，还需要满足 cond 的条件，超级管理员不受 cond 的限制；
+// load 如果不为空，会在确认用户拥有该资源的权限之后加载需要访问的对象，并作为参数传递给 cond，
+// 之后也可以在处理函数中通过 [Object] 获取该对象。
+//
+// 返回的是用于判断是否拥有当前资源权限的中间件。
+func (g *ResourceGroup[T]) NewPolicy(id string, desc web.LocaleStringer, cond Condition[T], load LoadFunc) web.MiddlewareFunc {
 	id = joinID(g.id, id)
 
 	if _, found := g.items[id]; found {
 		panic(fmt.Sprintf("已经存在同名的资源 %s", id))
 	}
 	g.items[id] = desc
+	if cond != nil {
+		g.conds[id] = cond
+	}
 	g.RBAC().resources = append(g.rbac.resources, id)
 
 	return func(next web.HandlerFunc, method, _, _ string) web.HandlerFunc {
@@ -109,18 +168,28 @@ func (g *ResourceGroup[T]) New(id string, desc web.LocaleStringer) web.Middlewar
 		}
 
 		return func(ctx *web.Context) web.Responser {
-			uid, resp := g.rbac.getUID(ctx)
+			// 先判断资源的权限，避免无权限的用户加载对象，以及通过 404 和 403 的区别判断对象是否存在。
+			uid, tenant, skip, resp := g.rbac.checkResource(ctx, id)
 			if resp != nil {
 				return resp
 			}
 
-			for _, roleG := range g.rbac.roleGroups {
-				if roleG.isAllow(uid, id) {
-					return next(ctx)
+			var obj any
+			if load != nil {
+				o, resp := load(ctx)
+				if resp != nil {
+					return resp
 				}
+				ctx.SetVar(objectKey, o)
+				obj = o
 			}
 
-			return ctx.Problem(web.ProblemForbidden)
+			if !skip {
+				if resp := g.rbac.checkCondition(ctx, uid, tenant, id, obj); resp != nil {
+					return resp
+				}
+			}
+			return next(ctx)
 		}
 	}
 }
@@ -149,18 +218,26 @@ func (rbac *RBAC[T]) Resources(p *message.Printer) []*Resources {
 func (role *Role[T]) Resource() *RoleResources {
 	var parent []string
 	if role.parent == nil {
-		parent = role.group.rbac.resources
+		rbac := role.group.rbac
+		parent = slices.Clone(rbac.resources)
+		for _, gid := range slices.Sorted(maps.Keys(rbac.resourceGroups)) {
+			parent = append(parent, rbac.resourceGroups[gid].Wildcard())
+		}
 	} else {
-		parent = role.parent.Resources
+		parent = slices.Clone(role.parent.Resources)
 	}
 
-	var current []string
+	var current, denied []string
 	if len(role.Resources) > 0 {
-		current = role.Resources
+		current = slices.Clone(role.Resources)
+	}
+	if len(role.Denied) > 0 {
+		denied = slices.Clone(role.Denied)
 	}
 
 	return &RoleResources{
-		Current: slices.Clone(current),
-		Parent:  slices.Clone(parent),
+		Current: current,
+		Parent:  parent,
+		Denied:  denied,
 	}
 }
@@ -21,7 +21,7 @@ func TestRBAC_NewResourceGroup(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
 
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 
 	group := rbac.NewResourceGroup("id", web.Phrase("test"))
@@ -34,7 +34,7 @@ func TestRBAC_NewResourceGroup(t *testing.T) {
 func RBAC_resourceExists(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 
 	g1 := rbac.NewResourceGroup("g1", nil)
@@ -52,12 +52,12 @@ func TestResources_New(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
 
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(ctx *web.Context) (string, web.Responser) {
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(ctx *web.Context) (string, string, web.Responser) {
 		q, err := ctx.Queries(true)
 		if err != nil {
-			return "", ctx.Error(err, "")
+			return "", "", ctx.Error(err, "")
 		}
-		return q.String("id", ""), nil
+		return q.String("id", ""), "", nil
 	})
 	a.NotNil(rbac)
 
@@ -92,7 +92,7 @@ func TestResources_New(t *testing.T) {
 func TestRBAC_Resources(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 
 	g1 := rbac.NewResourceGroup("g1", web.Phrase("test"))
@@ -108,7 +108,7 @@ func TestRBAC_Resources(t *testing.T) {
 func TestRole_Resource(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -134,6 +134,8 @@ func TestRole_Resource(t *testing.T) {
 			joinID(g1.id, "id2"),
 			joinID(g2.id, "id1"),
 			joinID(g2.id, "id2"),
+			g1.Wildcard(),
+			g2.Wildcard(),
 		},
 	})
 
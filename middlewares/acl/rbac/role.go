@@ -7,8 +7,11 @@ package rbac
 import (
 	"fmt"
 	"iter"
+	"maps"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/issue9/web"
 )
@@ -18,12 +21,23 @@ import (
 // 当一个用户系统有多个独立的权限模块时，可以很好地用 [RoleGroup] 进行表示，
 // 比如商家系统，每个商家拥有自己的操作人员，可为每个商家创建 [RoleGroup]。
 type RoleGroup[T comparable] struct {
-	rbac    *RBAC[T]
-	id      string
-	superID T
+	rbac     *RBAC[T]
+	id       string
+	superID  T
+	hasSuper bool
+	version  *atomic.Uint64
 
 	roles    map[string]*Role[T]
 	rolesMux *sync.RWMutex
+
+	// 用户至角色以及可访问资源的索引，由 rolesMux 保护。
+	//
+	// 拒绝的资源会作用于所有子角色，所以 userDenied 包含了父角色中拒绝的资源。
+	// 当多个角色都授予或拒绝了同一资源时，键值为关联时间最长的角色，
+	// 这样在判断时，只要该角色的关联已经过期，那么所有角色的关联都已经过期。
+	userRoles     map[T][]*Role[T]
+	userResources map[T]map[string]*Role[T] // 键名为资源 ID 或通配符，键值为授予该资源的角色。
+	userDenied    map[T]map[string]*Role[T] // 键名为资源 ID 或通配符，键值为拒绝该资源的角色。
 }
 
 // Role 角色信息
@@ -36,7 +50,20 @@ type Role[T comparable] struct {
 	Name      string
 	Desc      string
 	Resources []string // 当前角色关联的资源
+	Denied    []string // 当前角色明确拒绝的资源
 	Users     []T      // 当前角色关联的用户
+
+	// 关联用户的附加信息
+	//
+	// 只包含了 Users 中有时间限制或是由他人委托的用户，其它用户为永久关联。
+	Memberships []*Membership[T]
+}
+
+// Membership 用户与角色关联的附加信息
+type Membership[T comparable] struct {
+	UID       T         `json:"uid" xml:"uid,attr" yaml:"uid" cbor:"uid" comment:"the id of user"`
+	Expires   time.Time `json:"expires,omitzero" xml:"expires,attr,omitempty" yaml:"expires,omitempty" cbor:"expires,omitzero" comment:"the expiration time of membership"`
+	GrantedBy T         `json:"grantedBy,omitzero" xml:"grantedBy,attr,omitempty" yaml:"grantedBy,omitempty" cbor:"grantedBy,omitzero" comment:"the id of user who granted the membership"`
 }
 
 // NewRoleGroup 声明 [RoleGroup]
@@ -44,41 +71,131 @@ type Role[T comparable] struct {
 // id 表示当前角色组的唯一 ID；
 // superID 表示超级管理员的 ID；
 func (rbac *RBAC[T]) NewRoleGroup(id string, superID T) (*RoleGroup[T], error) {
-	if _, found := rbac.roleGroups[id]; found {
+	rbac.groupsMux.RLock()
+	_, found := rbac.roleGroups[id]
+	rbac.groupsMux.RUnlock()
+	if found {
 		panic(fmt.Sprintf("%s 已经存在", id))
 	}
 
-	g := &RoleGroup[T]{
+	g := rbac.newRoleGroup(id)
+	g.superID = superID
+	g.hasSuper = true
+	if err := g.Load(); err != nil {
+		return nil, err
+	}
+
+	rbac.groupsMux.Lock()
+	rbac.roleGroups[id] = g
+	rbac.groupsMux.Unlock()
+
+	rbac.indexMux.Lock()
+	rbac.supers[superID] = struct{}{}
+	rbac.indexMux.Unlock()
+
+	return g, nil
+}
+
+func (rbac *RBAC[T]) newRoleGroup(id string) *RoleGroup[T] {
+	return &RoleGroup[T]{
 		rbac:    rbac,
 		id:      id,
-		superID: superID,
+		version: &atomic.Uint64{},
 
 		roles:    make(map[string]*Role[T], 10),
 		rolesMux: &sync.RWMutex{},
 	}
-	if err := g.Load(); err != nil {
-		return nil, err
+}
+
+// TenantRoleGroup 返回租户 tenant 的角色组
+//
+// 在第一次访问时才会从 [Store] 中加载数据，之后会一直保存在内存中。
+// 租户的角色组没有超级管理员，可以创建一个拥有所有资源组通配符的角色代替。
+//
+// 如果 tenant 与 [RBAC.NewRoleGroup] 创建的角色组 ID 相同，返回的是该角色组。
+// 此方法是并发安全的，同一租户同一时间只会加载一次。
+func (rbac *RBAC[T]) TenantRoleGroup(tenant string) (*RoleGroup[T], error) {
+	rbac.groupsMux.RLock()
+	g, found := rbac.roleGroups[tenant]
+	rbac.groupsMux.RUnlock()
+	if found {
+		return g, nil
 	}
-	rbac.roleGroups[id] = g
 
-	return g, nil
+	rbac.groupsMux.Lock()
+	l, found := rbac.loading[tenant]
+	if !found {
+		l = &groupLoader[T]{}
+		rbac.loading[tenant] = l
+	}
+	rbac.groupsMux.Unlock()
+
+	l.once.Do(func() {
+		g := rbac.newRoleGroup(tenant)
+		l.err = g.Load()
+
+		rbac.groupsMux.Lock()
+		defer rbac.groupsMux.Unlock()
+		delete(rbac.loading, tenant) // 加载失败时，下次访问可以重新加载。
+		if l.err != nil {
+			return
+		}
+		if exists, found := rbac.roleGroups[tenant]; found {
+			l.g = exists
+			return
+		}
+		rbac.roleGroups[tenant] = g
+		l.g = g
+	})
+	return l.g, l.err
+}
+
+// 返回已经加载的角色组，不存在时返回 nil
+//
+// 与 [RBAC.TenantRoleGroup] 不同，不会从 [Store] 中加载或是创建角色组。
+func (rbac *RBAC[T]) roleGroup(id string) *RoleGroup[T] {
+	rbac.groupsMux.RLock()
+	defer rbac.groupsMux.RUnlock()
+	return rbac.roleGroups[id]
 }
 
 // UserRoles 用户 uid 关联的角色列表
 func (g *RoleGroup[T]) UserRoles(uid T) []*Role[T] {
-	roles := make([]*Role[T], 0, 5)
-	for _, r := range g.roles {
-		if slices.Index(r.Users, uid) >= 0 {
-			roles = append(roles, r)
-		}
-	}
-	return roles
+	g.rolesMux.RLock()
+	defer g.rolesMux.RUnlock()
+	return slices.Clone(g.userRoles[uid])
 }
 
 func (g *RoleGroup[T]) RBAC() *RBAC[T] { return g.rbac }
 
+// Version 当前内存中数据的版本号
+//
+// 如果 [Store] 实现了 [Watcher]，在加载时会被设置为 [Watcher.Version] 的值，
+// 否则在加载时重置为 0。之后每次通过当前对象修改数据，都会加 1。
+// 可用于比较各个节点的数据是否一致。
+func (g *RoleGroup[T]) Version() uint64 { return g.version.Load() }
+
+// 写入 [Store] 成功之后更新版本号
+func (g *RoleGroup[T]) saved(err error) error {
+	if err == nil {
+		g.version.Add(1)
+	}
+	return err
+}
+
 // Load 加载数据
+//
+// 加载之后，之前通过 [RoleGroup.Role] 等方法获得的 [Role] 对象将不再有效。
 func (g *RoleGroup[T]) Load() error {
+	var ver uint64
+	if w, ok := g.rbac.store.(Watcher); ok {
+		v, err := w.Version(g.id)
+		if err != nil {
+			return err
+		}
+		ver = v
+	}
+
 	roles, err := g.RBAC().store.Load(g.id)
 	if err != nil {
 		return err
@@ -89,12 +206,102 @@ func (g *RoleGroup[T]) Load() error {
 	}
 
 	g.rolesMux.Lock()
+	defer g.rolesMux.Unlock()
+
+	old := g.userRoles
+	g.version.Store(ver)
 	g.roles = roles
-	g.rolesMux.Unlock()
+	g.userRoles = make(map[T][]*Role[T], len(old))
+	g.userResources = make(map[T]map[string]*Role[T], len(old))
+	g.userDenied = make(map[T]map[string]*Role[T], len(old))
+	for _, role := range roles {
+		for _, uid := range role.Users {
+			g.userRoles[uid] = append(g.userRoles[uid], role)
+		}
+	}
+	for uid := range g.userRoles {
+		g.buildUserResources(uid)
+		g.rbac.setUserGroup(uid, g, true)
+	}
+	for uid := range old {
+		if _, found := g.userRoles[uid]; !found {
+			g.rbac.setUserGroup(uid, g, false)
+		}
+	}
 
 	return nil
 }
 
+// 重建用户 uid 的索引
+//
+// 调用方需要持有 rolesMux 的写锁。
+func (g *RoleGroup[T]) indexUser(uid T) {
+	roles := make([]*Role[T], 0, len(g.userRoles[uid]))
+	for _, role := range g.roles {
+		if slices.Index(role.Users, uid) >= 0 {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		delete(g.userRoles, uid)
+		delete(g.userResources, uid)
+		delete(g.userDenied, uid)
+		g.rbac.setUserGroup(uid, g, false)
+		return
+	}
+
+	g.userRoles[uid] = roles
+	g.buildUserResources(uid)
+	g.rbac.setUserGroup(uid, g, true)
+}
+
+func (g *RoleGroup[T]) buildUserResources(uid T) {
+	res := make(map[string]*Role[T], 10)
+	denied := make(map[string]*Role[T], 10)
+	for _, role := range g.userRoles[uid] {
+		for _, id := range role.Resources {
+			if role.parent == nil || role.parent.allows(id) {
+				setLonger(res, id, role, uid)
+			}
+		}
+
+		for r := role; r != nil; r = r.parent {
+			for _, id := range r.Denied {
+				setLonger(denied, id, role, uid)
+			}
+		}
+	}
+	g.userResources[uid] = res
+	g.userDenied[uid] = denied
+}
+
+// 如果 role 与 uid 的关联时间比 m[id] 更长，则替换 m[id]。
+func setLonger[T comparable](m map[string]*Role[T], id string, role *Role[T], uid T) {
+	old, found := m[id]
+	if !found {
+		m[id] = role
+		return
+	}
+
+	if e := old.expires(uid); !e.IsZero() { // 零值表示永不过期
+		if ne := role.expires(uid); ne.IsZero() || ne.After(e) {
+			m[id] = role
+		}
+	}
+}
+
+// 重建角色 role 及其子角色下所有用户的索引
+//
+// 调用方需要持有 rolesMux 的写锁。
+func (g *RoleGroup[T]) indexRole(role *Role[T]) {
+	for _, r := range append(g.descendants(role, true), role) {
+		for _, uid := range r.Users {
+			g.buildUserResources(uid)
+		}
+	}
+}
+
 // NewRole 添加角色信息
 func (g *RoleGroup[T]) NewRole(name, desc, parent string) (*Role[T], error) {
 	role := &Role[T]{
@@ -109,11 +316,11 @@ func (g *RoleGroup[T]) NewRole(name, desc, parent string) (*Role[T], error) {
 		Users:     make([]T, 0, 10),
 	}
 
-	g.rolesMux.RLock()
+	g.rolesMux.Lock()
 	g.roles[role.ID] = role
-	g.rolesMux.RUnlock()
+	g.rolesMux.Unlock()
 
-	if err := g.rbac.store.Add(g.id, role); err != nil {
+	if err := g.saved(g.rbac.store.Add(g.id, role)); err != nil {
 		return nil, err
 	}
 	return role, nil
@@ -142,61 +349,111 @@ func (g *RoleGroup[T]) Role(id string) *Role[T] {
 	return r
 }
 
+// 用户 uid 是否为当前角色组的超级管理员
+func (g *RoleGroup[T]) isSuper(uid T) bool { return g.hasSuper && uid == g.superID }
+
 // 用户 uid 是否可访问资源 res
 func (g *RoleGroup[T]) isAllow(uid T, res string) bool {
-	if uid == g.superID {
+	if g.isSuper(uid) {
 		return true
 	}
 
-	g.rolesMux.RLock()
-	defer g.rolesMux.RUnlock()
+	wild := wildcardOf(res)
+	now := g.rbac.s.Now()
+	valid := func(m map[string]*Role[T], id string) (*Role[T], bool) {
+		if r, found := m[id]; found && !r.expired(uid, now) {
+			return r, true
+		}
+		return nil, false
+	}
 
-	for _, role := range g.roles {
-		if slices.Index(role.Users, uid) >= 0 && slices.Index(role.Resources, res) >= 0 {
-			msg := web.Phrase("user %v obtained access to %s due to %s:%s", uid, res, role.ID, g.id)
-			g.rbac.s.Logs().INFO().LocaleString(msg)
-			return true
+	g.rolesMux.RLock()
+	denied := g.userDenied[uid]
+	_, d1 := valid(denied, res)
+	_, d2 := valid(denied, wild)
+	var role *Role[T]
+	var found bool
+	if !d1 && !d2 { // 拒绝的优先级更高
+		if role, found = valid(g.userResources[uid], res); !found {
+			role, found = valid(g.userResources[uid], wild)
 		}
 	}
+	g.rolesMux.RUnlock()
 
-	return false
+	if found {
+		msg := web.Phrase("user %v obtained access to %s due to %s:%s", uid, res, role.ID, g.id)
+		g.rbac.s.Logs().INFO().LocaleString(msg)
+	}
+	return found
 }
 
 // Allow 关联角色与资源
 //
-// 替换之前关联的资源。如果传递空值，将直接清空 [Role.Resources]
-func (role *Role[T]) Allow(res ...string) error {
-	if role.parent == nil {
-		for _, resID := range res { // res 是否真实存在
-			if !role.group.rbac.resourceExists(resID) {
+// 替换之前关联的资源。如果传递空值，将直接清空 [Role.Resources]。
+// res 也可以是资源组的通配符，参考 [ResourceGroup.Wildcard]。
+func (role *Role[T]) Allow(res ...string) error { return role.SetResources(res, role.Denied) }
+
+// Deny 明确拒绝角色访问的资源
+//
+// 替换之前拒绝的资源。如果传递空值，将直接清空 [Role.Denied]。
+// 拒绝的优先级高于 [Role.Allow]，且会作用于所有的子角色。
+// res 也可以是资源组的通配符，参考 [ResourceGroup.Wildcard]。
+func (role *Role[T]) Deny(res ...string) error { return role.SetResources(role.Resources, res) }
+
+// SetResources 同时设置角色允许和拒绝访问的资源
+func (role *Role[T]) SetResources(allow, deny []string) error {
+	for _, resID := range allow {
+		if role.parent == nil {
+			if !role.group.rbac.resourceExists(resID) { // res 是否真实存在
 				return web.NewLocaleError("not found resource %s", resID)
 			}
+		} else if !role.parent.allows(resID) {
+			return web.NewLocaleError("not found resource %s", resID)
 		}
-	} else {
-		for _, resID := range res {
-			if slices.Index(role.parent.Resources, resID) < 0 {
-				return web.NewLocaleError("not found resource %s", resID)
-			}
+	}
+
+	for _, resID := range deny {
+		if !role.group.rbac.resourceExists(resID) {
+			return web.NewLocaleError("not found resource %s", resID)
+		}
+		if slices.Contains(allow, resID) {
+			return web.NewLocaleError("resource %s is both allowed and denied", resID)
 		}
 	}
 
+	g := role.group
+	g.rolesMux.Lock()
+	defer g.rolesMux.Unlock()
+
 	// 判断子角色中的资源是否都在 res 之中
-	role.group.rolesMux.RLock()
-	for _, child := range role.group.roles {
+	for _, child := range g.roles {
 		if child.Parent != role.ID {
 			continue
 		}
 
 		for _, childRes := range child.Resources {
-			if slices.Index(res, childRes) < 0 {
+			if !containsResource(allow, childRes) {
 				return web.NewLocaleError("child role has resource %s can not be deleted", childRes)
 			}
 		}
 	}
-	role.group.rolesMux.RUnlock()
 
-	role.Resources = res
-	return role.group.rbac.store.Set(role.group.id, role)
+	role.Resources = allow
+	role.Denied = deny
+	g.indexRole(role)
+	return g.saved(g.rbac.store.Set(g.id, role))
+}
+
+// 当前角色是否允许访问资源 id
+//
+// 需要当前角色及其所有的父角色都允许访问且未拒绝访问。
+func (role *Role[T]) allows(id string) bool {
+	for r := role; r != nil; r = r.parent {
+		if containsResource(r.Denied, id) || !containsResource(r.Resources, id) {
+			return false
+		}
+	}
+	return true
 }
 
 // Set 修改指定的角色信息
@@ -207,7 +464,7 @@ func (role *Role[T]) Set(name, desc string) error {
 
 	role.Name = name
 	role.Desc = desc
-	return role.group.rbac.store.Set(role.group.id, role)
+	return role.group.saved(role.group.rbac.store.Set(role.group.id, role))
 }
 
 // Del 删除当前角色
@@ -229,11 +486,49 @@ func (role *Role[T]) Del() error {
 	delete(role.group.roles, role.ID)
 	role.group.rolesMux.Unlock()
 
-	return role.group.rbac.store.Del(role.group.id, role.ID)
+	return role.group.saved(role.group.rbac.store.Del(role.group.id, role.ID))
 }
 
-func (role *Role[T]) Link(uid T) error {
-	if slices.Index(role.Users, uid) >= 0 { // 已经存在于当前用户
+// Link 将用户 uid 永久关联到当前角色
+//
+// 如果用户已经以有时间限制的方式关联，会变为永久关联。
+func (role *Role[T]) Link(uid T) error { return role.link(uid, nil) }
+
+// LinkUntil 将用户 uid 关联到当前角色直到 expires
+//
+// 过期之后，关联将不再起作用，可由 [RBAC.CleanExpired] 或 [RBAC.ScheduleCleanup] 删除。
+// 如果用户已经关联，会替换之前的关联方式。
+func (role *Role[T]) LinkUntil(uid T, expires time.Time) error {
+	return role.link(uid, &Membership[T]{UID: uid, Expires: expires})
+}
+
+// Delegate 由 grantor 将当前角色授予用户 uid
+//
+// grantor 必须关联了当前角色或是其父角色，即只能委托自己所拥有的权限。
+// 如果 grantor 的关联有时间限制，那么 expires 不能超过该时间，超过的部分将被截断。
+// expires 为零值表示与 grantor 的关联时间相同。
+// grantor 失去该角色或是关联过期之后，委托的关联也随之失效。
+func (role *Role[T]) Delegate(grantor, uid T, expires time.Time) error {
+	now := role.group.rbac.s.Now()
+	var found bool
+	for r := role; r != nil; r = r.parent {
+		if slices.Contains(r.Users, grantor) && !r.expired(grantor, now) {
+			if e := r.expires(grantor); !e.IsZero() && (expires.IsZero() || expires.After(e)) {
+				expires = e
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return web.NewLocaleError("user %v can not delegate role %s", grantor, role.ID)
+	}
+
+	return role.link(uid, &Membership[T]{UID: uid, Expires: expires, GrantedBy: grantor})
+}
+
+func (role *Role[T]) link(uid T, m *Membership[T]) error {
+	if slices.Index(role.Users, uid) >= 0 && m == nil && role.membership(uid) == nil { // 已经永久关联于当前角色
 		return nil
 	}
 
@@ -245,16 +540,126 @@ func (role *Role[T]) Link(uid T) error {
 		parent = parent.parent
 	}
 
-	role.Users = append(role.Users, uid)
-	return role.group.rbac.store.Set(role.group.id, role)
+	g := role.group
+	g.rolesMux.Lock()
+	defer g.rolesMux.Unlock()
+
+	if slices.Index(role.Users, uid) < 0 {
+		role.Users = append(role.Users, uid)
+	}
+	role.Memberships = slices.DeleteFunc(role.Memberships, func(m *Membership[T]) bool { return m.UID == uid })
+	if m != nil {
+		role.Memberships = append(role.Memberships, m)
+	}
+	g.indexUser(uid)
+	return g.saved(g.rbac.store.Set(g.id, role))
 }
 
 func (role *Role[T]) Unlink(uid T) error {
+	g := role.group
+	g.rolesMux.Lock()
+	defer g.rolesMux.Unlock()
+
 	if index := slices.Index(role.Users, uid); index >= 0 {
 		role.Users = slices.Delete(role.Users, index, index+1)
-		return role.group.rbac.store.Set(role.group.id, role)
+		role.Memberships = slices.DeleteFunc(role.Memberships, func(m *Membership[T]) bool { return m.UID == uid })
+		g.indexUser(uid)
+		return g.saved(g.rbac.store.Set(g.id, role))
+	}
+
+	return nil
+}
+
+// 用户 uid 与当前角色关联的附加信息，如果是永久关联，返回 nil。
+func (role *Role[T]) membership(uid T) *Membership[T] {
+	if index := slices.IndexFunc(role.Memberships, func(m *Membership[T]) bool { return m.UID == uid }); index >= 0 {
+		return role.Memberships[index]
+	}
+	return nil
+}
+
+// 用户 uid 与当前角色关联的过期时间，零值表示永不过期。
+func (role *Role[T]) expires(uid T) time.Time {
+	if m := role.membership(uid); m != nil {
+		return m.Expires
+	}
+	return time.Time{}
+}
+
+// 用户 uid 与当前角色的关联在 now 时是否已经失效
+//
+// 除了过期之外，委托的关联在委托人失去当前角色或其父角色之后也将失效。
+func (role *Role[T]) expired(uid T, now time.Time) bool { return role.expiredBy(uid, now, nil) }
+
+// visited 为委托链上已经检测过的用户，用于防止循环委托。
+func (role *Role[T]) expiredBy(uid T, now time.Time, visited []T) bool {
+	m := role.membership(uid)
+	if m == nil {
+		return false
+	}
+	if !m.Expires.IsZero() && !now.Before(m.Expires) {
+		return true
 	}
 
+	var zero T
+	if m.GrantedBy == zero {
+		return false
+	}
+	if slices.Contains(visited, uid) {
+		return true
+	}
+	visited = append(visited, uid)
+
+	for r := role; r != nil; r = r.parent {
+		if slices.Contains(r.Users, m.GrantedBy) && !r.expiredBy(m.GrantedBy, now, visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// CleanExpired 删除在 now 之前已经过期的用户关联
+//
+// 过期或是委托人已经失去角色的关联在权限判断时已经被忽略，此方法只是从 [Store] 中删除这些数据。
+// 如果需要定时执行，可以使用 [RBAC.ScheduleCleanup]。
+func (rbac *RBAC[T]) CleanExpired(now time.Time) error {
+	rbac.groupsMux.RLock()
+	groups := slices.Collect(maps.Values(rbac.roleGroups))
+	rbac.groupsMux.RUnlock()
+
+	for _, g := range groups {
+		if err := g.cleanExpired(now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *RoleGroup[T]) cleanExpired(now time.Time) error {
+	g.rolesMux.Lock()
+	defer g.rolesMux.Unlock()
+
+	for _, role := range g.roles {
+		var uids []T
+		for _, m := range role.Memberships {
+			if role.expired(m.UID, now) {
+				uids = append(uids, m.UID)
+			}
+		}
+		if len(uids) == 0 {
+			continue
+		}
+
+		role.Memberships = slices.DeleteFunc(role.Memberships, func(m *Membership[T]) bool { return slices.Contains(uids, m.UID) })
+
+		role.Users = slices.DeleteFunc(role.Users, func(uid T) bool { return slices.Contains(uids, uid) })
+		for _, uid := range uids {
+			g.indexUser(uid)
+		}
+		if err := g.saved(g.rbac.store.Set(g.id, role)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -280,12 +685,16 @@ func (role *Role[T]) IsDescendant(rid string) bool {
 //
 // all 表示是否包含间接继承的角色
 func (role *Role[T]) Descendants(all bool) []*Role[T] {
-	roles := make([]*Role[T], 0, 10)
-
 	role.group.rolesMux.RLock()
 	defer role.group.rolesMux.RUnlock()
+	return role.group.descendants(role, all)
+}
 
-	for _, r := range role.group.roles {
+// 调用方需要持有 rolesMux 的锁
+func (g *RoleGroup[T]) descendants(role *Role[T], all bool) []*Role[T] {
+	roles := make([]*Role[T], 0, 10)
+
+	for _, r := range g.roles {
 		if r.Parent == role.ID {
 			roles = append(roles, r)
 		}
@@ -300,7 +709,7 @@ func (role *Role[T]) Descendants(all bool) []*Role[T] {
 		ids := rolesID(s)
 		rs := make([]*Role[T], 0, 10)
 
-		for _, v := range role.group.roles {
+		for _, v := range g.roles {
 			if slices.Index(ids, v.Parent) >= 0 {
 				rs = append(rs, v)
 			}
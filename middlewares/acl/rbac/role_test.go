@@ -5,11 +5,20 @@
 package rbac
 
 import (
+	"net/http"
 	"slices"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/logs/v7"
 	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+	"golang.org/x/text/language"
 
 	"github.com/issue9/webuse/v7/internal/testserver"
 )
@@ -17,7 +26,7 @@ import (
 func TestRoleGroup_New(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	g, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(g)
@@ -39,7 +48,7 @@ func TestRoleGroup_New(t *testing.T) {
 func TestRole_Allow(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -77,7 +86,7 @@ func TestRole_Allow(t *testing.T) {
 func TestRole_Del(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -99,7 +108,7 @@ func TestRole_Del(t *testing.T) {
 func TestRole_Link(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -125,7 +134,7 @@ func TestRole_Link(t *testing.T) {
 func TestRole_Set(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -142,7 +151,7 @@ func TestRole_Set(t *testing.T) {
 func TestRole_Descendants(t *testing.T) {
 	a := assert.New(t, false)
 	s := testserver.New(a)
-	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, web.Responser) { return "1", nil })
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
 	a.NotNil(rbac)
 	rg1, err := rbac.NewRoleGroup("g1", "")
 	a.NotError(err).NotNil(rg1)
@@ -168,3 +177,346 @@ func TestRole_Descendants(t *testing.T) {
 		Equal(roles[0].ID, r2.ID).
 		Equal(roles[1].ID, r3.ID)
 }
+
+func TestRoleGroup_index(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	res := rbac.NewResourceGroup("res", nil)
+	res.New("1", nil)
+	res.New("2", nil)
+	res1 := joinID("res", "1")
+	res2 := joinID("res", "2")
+
+	rg1, err := rbac.NewRoleGroup("g1", "super")
+	a.NotError(err).NotNil(rg1)
+	r1, err := rg1.NewRole("r1", "r1 desc", "")
+	a.NotError(err).NotNil(r1)
+	a.NotError(r1.Allow(res1)).NotError(r1.Link("u1"))
+
+	a.True(rbac.isAllow("u1", res1)).
+		False(rbac.isAllow("u1", res2)).
+		False(rbac.isAllow("u2", res1)).
+		True(rbac.isAllow("super", res2)).
+		Length(rbac.userGroups["u1"], 1)
+
+	// Allow 更新用户的资源
+	a.NotError(r1.Allow(res1, res2))
+	a.True(rbac.isAllow("u1", res2))
+
+	// 重新加载
+	rg1.roles = map[string]*Role[string]{}
+	rg1.userRoles = map[string][]*Role[string]{}
+	rg1.userResources = map[string]map[string]*Role[string]{}
+	a.False(rg1.isAllow("u1", res1))
+	a.NotError(rg1.Load())
+	a.True(rg1.isAllow("u1", res1)).
+		Length(rg1.UserRoles("u1"), 1)
+
+	// 多个角色组
+	rg2, err := rbac.NewRoleGroup("g2", "super2")
+	a.NotError(err).NotNil(rg2)
+	r2, err := rg2.NewRole("r2", "r2 desc", "")
+	a.NotError(err).NotNil(r2)
+	a.NotError(r2.Allow(res2)).NotError(r2.Link("u2")).NotError(r2.Link("u1"))
+	a.True(rbac.isAllow("u2", res2)).
+		False(rbac.isAllow("u2", res1)).
+		Length(rbac.userGroups["u1"], 2)
+
+	a.NotError(r2.Unlink("u2"))
+	a.False(rbac.isAllow("u2", res2)).
+		Empty(rbac.userGroups["u2"]).
+		Empty(rg2.UserRoles("u2"))
+}
+
+func benchmarkRBAC(b *testing.B, users int) {
+	a := assert.New(b, true)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		Language:   language.SimplifiedChinese,
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, json.ProblemMimetype, true, true),
+		Logs:       logs.New(logs.NewNopHandler()), // 防止 isAllow 输出大量日志
+	})
+	a.NotError(err).NotNil(s)
+
+	rbac := New(s, NewCacheStore[int](s, "c_"), func(*web.Context) (int, string, web.Responser) { return 1, "", nil })
+	res := rbac.NewResourceGroup("res", nil)
+	for i := range 100 {
+		res.New(strconv.Itoa(i), nil)
+	}
+
+	g, err := rbac.NewRoleGroup("g1", -1)
+	a.NotError(err).NotNil(g)
+	for i := range 100 {
+		r, err := g.NewRole(strconv.Itoa(i), "", "")
+		a.NotError(err).NotNil(r)
+		a.NotError(r.Allow(joinID("res", strconv.Itoa(i))))
+		for uid := i; uid < users; uid += 100 {
+			a.NotError(r.Link(uid))
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; b.Loop(); i++ {
+		uid := i % users
+		rbac.isAllow(uid, joinID("res", strconv.Itoa(uid%100)))
+	}
+}
+
+func BenchmarkRBAC_isAllow_1000(b *testing.B) { benchmarkRBAC(b, 1000) }
+
+func BenchmarkRBAC_isAllow_100000(b *testing.B) { benchmarkRBAC(b, 100000) }
+
+func TestRole_Deny(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	ga := rbac.NewResourceGroup("a", nil)
+	ga.New("1", nil)
+	ga.New("2", nil)
+	ga.New("3", nil)
+	gb := rbac.NewResourceGroup("b", nil)
+	gb.New("1", nil)
+	a1, a2, a3, b1 := joinID("a", "1"), joinID("a", "2"), joinID("a", "3"), joinID("b", "1")
+
+	rg, err := rbac.NewRoleGroup("g1", "")
+	a.NotError(err).NotNil(rg)
+
+	r1, err := rg.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r1)
+	a.NotError(r1.Allow(ga.Wildcard())).NotError(r1.Link("u1"))
+	a.True(rg.isAllow("u1", a1)).
+		True(rg.isAllow("u1", a2)).
+		False(rg.isAllow("u1", b1))
+
+	a.NotError(r1.Deny(a2))
+	a.True(rg.isAllow("u1", a1)).
+		False(rg.isAllow("u1", a2)).
+		Equal(r1.Resource().Denied, []string{a2})
+
+	a.Equal(r1.Deny("not-exists"), web.NewLocaleError("not found resource %s", "not-exists")).
+		Equal(r1.SetResources([]string{a1}, []string{a1}), web.NewLocaleError("resource %s is both allowed and denied", a1)).
+		Equal(r1.Resources, []string{ga.Wildcard()}).
+		Equal(r1.Denied, []string{a2})
+
+	// 子角色
+
+	r2, err := rg.NewRole("r2", "", r1.ID)
+	a.NotError(err).NotNil(r2)
+	a.Equal(r2.Allow(b1), web.NewLocaleError("not found resource %s", b1)).
+		Equal(r2.Allow(a2), web.NewLocaleError("not found resource %s", a2)). // 父角色已经拒绝
+		NotError(r2.Allow(ga.Wildcard())).
+		NotError(r2.Link("u2"))
+	a.True(rg.isAllow("u2", a1)).
+		True(rg.isAllow("u2", a3)).
+		False(rg.isAllow("u2", a2)) // 继承自父角色的拒绝
+
+	a.NotError(r1.Deny(a2, a3))
+	a.False(rg.isAllow("u2", a3)).
+		True(rg.isAllow("u2", a1))
+
+	// 子角色依然拥有 a_*
+	a.Equal(r1.Allow(a1), web.NewLocaleError("child role has resource %s can not be deleted", ga.Wildcard()))
+
+	// 拒绝的优先级高于其它角色的允许
+	r3, err := rg.NewRole("r3", "", "")
+	a.NotError(err).NotNil(r3)
+	a.NotError(r3.Allow(a3, b1)).
+		NotError(r3.Link("u3")).
+		NotError(r1.Link("u3"))
+	a.False(rg.isAllow("u3", a3)).
+		True(rg.isAllow("u3", b1)).
+		True(rg.isAllow("u3", a1))
+
+	// 重新加载
+	a.NotError(rg.Load())
+	a.False(rg.isAllow("u3", a3)).
+		True(rg.isAllow("u3", b1)).
+		False(rg.isAllow("u2", a2)).
+		True(rg.isAllow("u2", a1))
+}
+
+func TestRBAC_TenantRoleGroup(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	store := NewCacheStore[string](s, "c_")
+	getUID := func(ctx *web.Context) (string, string, web.Responser) {
+		return ctx.Request().Header.Get("x-uid"), ctx.Request().Header.Get("x-tenant"), nil
+	}
+
+	// 通过第一个 RBAC 写入数据
+	rbac := New(s, store, getUID)
+	rbac.NewResourceGroup("a", nil).New("1", nil)
+	g1, err := rbac.TenantRoleGroup("t1")
+	a.NotError(err).NotNil(g1)
+	r1, err := g1.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r1).
+		NotError(r1.Allow(joinID("a", "1"))).
+		NotError(r1.Link("u1"))
+
+	// 第二个 RBAC 按需加载
+	rbac = New(s, store, getUID)
+	res := rbac.NewResourceGroup("a", nil).New("1", nil)
+	superGroup, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(superGroup)
+
+	groups := make(chan *RoleGroup[string], 10)
+	wg := &sync.WaitGroup{}
+	for range 10 {
+		wg.Go(func() {
+			g, err := rbac.TenantRoleGroup("t1")
+			a.NotError(err)
+			groups <- g
+		})
+	}
+	wg.Wait()
+	close(groups)
+	g := <-groups
+	a.NotNil(g).Length(slices.Collect(g.Roles()), 1)
+	for gg := range groups {
+		a.Equal(gg, g)
+	}
+	a.Empty(rbac.loading).
+		False(g.isAllow("", joinID("a", "1"))) // 租户角色组没有超级管理员
+
+	g2, err := rbac.TenantRoleGroup("g1")
+	a.NotError(err).Equal(g2, superGroup)
+
+	router := s.Routers().New("def", nil)
+	router.Get("/a/1", func(*web.Context) web.Responser { return web.NoContent() }, res)
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "u1").
+		Header("x-tenant", "t1").
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	// 不属于租户 t2
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "u1").
+		Header("x-tenant", "t2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 其它角色组的超级管理员不能访问租户的资源
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "admin").
+		Header("x-tenant", "t2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 角色组自身的超级管理员
+	servertest.Get(a, "http://localhost:8080/a/1").
+		Header("x-uid", "admin").
+		Header("x-tenant", "g1").
+		Do(nil).
+		Status(http.StatusNoContent)
+}
+
+func TestRole_LinkUntil(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	res := rbac.NewResourceGroup("a", nil)
+	res.New("1", nil)
+	res.New("2", nil)
+	a1, a2 := joinID("a", "1"), joinID("a", "2")
+	rg, err := rbac.NewRoleGroup("g1", "")
+	a.NotError(err).NotNil(rg)
+
+	r1, err := rg.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r1).NotError(r1.Allow(a1, a2))
+	r2, err := rg.NewRole("r2", "", "")
+	a.NotError(err).NotNil(r2).NotError(r2.Allow(a1))
+
+	// 已经过期
+	a.NotError(r1.LinkUntil("u1", time.Now().Add(-time.Second)))
+	a.False(rg.isAllow("u1", a1)).Length(r1.Memberships, 1)
+
+	// 多个角色授予同一资源时，以时间最长的为准。
+	a.NotError(r2.Link("u1"))
+	a.True(rg.isAllow("u1", a1)).False(rg.isAllow("u1", a2))
+
+	// 改为永久关联
+	a.NotError(r1.Link("u1"))
+	a.True(rg.isAllow("u1", a2)).Empty(r1.Memberships)
+
+	a.NotError(r1.LinkUntil("u2", time.Now().Add(time.Hour)))
+	a.True(rg.isAllow("u2", a1))
+
+	// 清除过期的关联
+	a.NotError(r1.LinkUntil("u1", time.Now().Add(-time.Second)))
+	a.NotError(rbac.CleanExpired(time.Now()))
+	a.Equal(r1.Users, []string{"u2"}).
+		Length(r1.Memberships, 1).
+		Length(rg.UserRoles("u1"), 1).
+		True(rg.isAllow("u1", a1)) // 依然拥有 r2
+
+	a.NotError(rbac.CleanExpired(time.Now().Add(2 * time.Hour)))
+	a.Empty(r1.Users).Empty(r1.Memberships).Empty(rg.UserRoles("u2"))
+}
+
+func TestRole_Delegate(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	res := rbac.NewResourceGroup("a", nil)
+	res.New("1", nil)
+	res.New("2", nil)
+	a1, a2 := joinID("a", "1"), joinID("a", "2")
+	rg, err := rbac.NewRoleGroup("g1", "")
+	a.NotError(err).NotNil(rg)
+
+	manager, err := rg.NewRole("manager", "", "")
+	a.NotError(err).NotNil(manager).NotError(manager.Allow(a1, a2))
+	staff, err := rg.NewRole("staff", "", manager.ID)
+	a.NotError(err).NotNil(staff).NotError(staff.Allow(a1))
+	other, err := rg.NewRole("other", "", "")
+	a.NotError(err).NotNil(other)
+
+	expires := time.Now().Add(time.Hour)
+	a.NotError(manager.LinkUntil("m1", expires))
+
+	// 委托子角色，过期时间不能超过自身的关联。
+	a.NotError(staff.Delegate("m1", "u1", time.Time{}))
+	m := staff.membership("u1")
+	a.NotNil(m).Equal(m.GrantedBy, "m1").True(m.Expires.Equal(expires))
+	a.True(rg.isAllow("u1", a1)).False(rg.isAllow("u1", a2))
+
+	a.NotError(staff.Delegate("m1", "u2", expires.Add(-time.Minute)))
+	a.True(staff.membership("u2").Expires.Equal(expires.Add(-time.Minute)))
+
+	// 未拥有的角色
+	a.Error(other.Delegate("m1", "u3", time.Time{}))
+	a.Error(manager.Delegate("u1", "u3", time.Time{}))
+
+	// 已经过期的关联无法委托
+	a.NotError(manager.LinkUntil("m2", time.Now().Add(-time.Second)))
+	a.Error(staff.Delegate("m2", "u3", time.Time{}))
+
+	// 委托人被取消关联
+	a.NotError(manager.Unlink("m1"))
+	a.False(rg.isAllow("u1", a1)).False(rg.isAllow("u2", a1))
+	a.NotError(rbac.CleanExpired(time.Now()))
+	a.Nil(staff.membership("u1")).Nil(staff.membership("u2")).NotContains(staff.Users, "u1")
+
+	// 委托人的关联过期
+	a.NotError(manager.Link("m3"))
+	a.NotError(staff.Delegate("m3", "u4", time.Time{}))
+	a.True(rg.isAllow("u4", a1)).True(staff.membership("u4").Expires.IsZero())
+	a.NotError(manager.LinkUntil("m3", time.Now().Add(-time.Second)))
+	a.False(rg.isAllow("u4", a1))
+}
+
+func TestRBAC_ScheduleCleanup(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+
+	a.PanicString(func() {
+		rbac.ScheduleCleanup(0)
+	}, "参数 interval 必须大于 0")
+}
@@ -0,0 +1,343 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/issue9/web"
+)
+
+// Snapshot 角色组的完整数据
+//
+// 包含了角色组中所有的角色及其父角色、资源和关联的用户，
+// 可以序列化为 YAML 或是 JSON，用于导入和导出。
+type Snapshot[T comparable] struct {
+	Group string         `json:"group" xml:"group,attr" yaml:"group" cbor:"group" comment:"the id of role group"`
+	Roles []*RoleInfo[T] `json:"roles" xml:"roles>role" yaml:"roles" cbor:"roles" comment:"roles of group"`
+}
+
+// Diff 导入的数据与 [Store] 中数据的差异
+type Diff[T comparable] struct {
+	Group   string         `json:"group" xml:"group,attr" yaml:"group" cbor:"group" comment:"the id of role group"`
+	Added   []*RoleInfo[T] `json:"added,omitempty" xml:"added>role,omitempty" yaml:"added,omitempty" cbor:"added,omitempty" comment:"roles to be added"`
+	Changed []*RoleInfo[T] `json:"changed,omitempty" xml:"changed>role,omitempty" yaml:"changed,omitempty" cbor:"changed,omitempty" comment:"roles to be changed"`
+	Deleted []*RoleInfo[T] `json:"deleted,omitempty" xml:"deleted>role,omitempty" yaml:"deleted,omitempty" cbor:"deleted,omitempty" comment:"roles to be deleted"`
+}
+
+// Empty 是否没有任何差异
+func (d *Diff[T]) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Deleted) == 0
+}
+
+// Export 导出角色组的数据
+func (g *RoleGroup[T]) Export() *Snapshot[T] {
+	roles := make([]*RoleInfo[T], 0, 10)
+	for r := range g.Roles() {
+		roles = append(roles, newRoleInfo(r))
+	}
+	slices.SortFunc(roles, func(a, b *RoleInfo[T]) int { return cmp.Compare(a.ID, b.ID) })
+
+	return &Snapshot[T]{Group: g.id, Roles: roles}
+}
+
+// Validate 验证 roles 是否可以作为角色组的完整数据
+//
+// 会检测角色 ID 的唯一性、父角色是否存在、资源是否已经注册，
+// 以及与 [Role.SetResources] 和 [Role.Link] 相同的约束。
+func (g *RoleGroup[T]) Validate(roles []*RoleInfo[T]) error {
+	ms := make(map[string]*Role[T], len(roles))
+	for _, info := range roles {
+		if info.ID == "" || info.Name == "" {
+			return web.NewLocaleError("the id and name of role can not be empty")
+		}
+		if _, found := ms[info.ID]; found {
+			return web.NewLocaleError("duplicate role %s", info.ID)
+		}
+		ms[info.ID] = info.role()
+	}
+
+	for _, r := range ms {
+		if r.Parent == "" {
+			continue
+		}
+
+		p, found := ms[r.Parent]
+		if !found {
+			return web.NewLocaleError("not found role %s", r.Parent)
+		}
+		r.parent = p
+	}
+
+	for _, r := range ms { // 需要在 allows 之前检测，否则可能死循环。
+		depth := 0
+		for p := r.parent; p != nil; p = p.parent {
+			if depth++; depth > len(ms) {
+				return web.NewLocaleError("role %s has a circular parent", r.ID)
+			}
+		}
+	}
+
+	for _, r := range ms {
+		for _, res := range r.Resources {
+			if !g.rbac.resourceExists(res) || (r.parent != nil && !r.parent.allows(res)) {
+				return web.NewLocaleError("not found resource %s", res)
+			}
+		}
+
+		for _, res := range r.Denied {
+			if !g.rbac.resourceExists(res) {
+				return web.NewLocaleError("not found resource %s", res)
+			}
+			if slices.Contains(r.Resources, res) {
+				return web.NewLocaleError("resource %s is both allowed and denied", res)
+			}
+		}
+
+		for i, m := range r.Memberships {
+			if !slices.Contains(r.Users, m.UID) || slices.ContainsFunc(r.Memberships[i+1:], func(v *Membership[T]) bool { return v.UID == m.UID }) {
+				return web.NewLocaleError("invalid membership of user %v", m.UID)
+			}
+		}
+
+		for p := r.parent; p != nil; p = p.parent {
+			for _, uid := range r.Users {
+				if slices.Contains(p.Users, uid) {
+					return web.NewLocaleError("user %v in the parent role %s", uid, p.ID)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Diff 比较 roles 与 [Store] 中数据的差异
+//
+// 与 [RoleGroup.Import] 相同，但是不会真正写入数据，可用于预览导入的结果。
+func (g *RoleGroup[T]) Diff(roles []*RoleInfo[T]) (*Diff[T], error) {
+	if err := g.Validate(roles); err != nil {
+		return nil, err
+	}
+
+	current, err := g.rbac.store.Load(g.id)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Diff[T]{Group: g.id}
+	for _, info := range roles {
+		r, found := current[info.ID]
+		switch {
+		case !found:
+			d.Added = append(d.Added, info)
+		case !sameRole(newRoleInfo(r), info):
+			d.Changed = append(d.Changed, info)
+		}
+	}
+
+	for _, r := range current {
+		if !slices.ContainsFunc(roles, func(info *RoleInfo[T]) bool { return info.ID == r.ID }) {
+			d.Deleted = append(d.Deleted, newRoleInfo(r))
+		}
+	}
+	slices.SortFunc(d.Deleted, func(a, b *RoleInfo[T]) int { return cmp.Compare(a.ID, b.ID) })
+
+	return d, nil
+}
+
+// Import 以 roles 替换角色组的所有数据
+//
+// roles 中的角色 ID 会原样写入 [Store]，不存在于 roles 中的角色将被删除。
+// 写入完成之后会重新加载当前角色组。
+//
+// NOTE: 数据是逐条写入 [Store] 的，中途出错时，已经写入的数据不会回滚。
+func (g *RoleGroup[T]) Import(roles []*RoleInfo[T]) (*Diff[T], error) {
+	d, err := g.Diff(roles)
+	if err != nil || d.Empty() {
+		return d, err
+	}
+
+	store := g.rbac.store
+	for _, info := range sortByParent(d.Added) {
+		if err := store.Add(g.id, info.role()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, info := range d.Changed {
+		if err := store.Set(g.id, info.role()); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := sortByParent(d.Deleted)
+	slices.Reverse(deleted) // 先删除子角色
+	for _, info := range deleted {
+		if err := store.Del(g.id, info.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.Load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ApplyFile 将文件中声明的角色组数据导入
+//
+// 文件的内容为 [Snapshot] 的数组，根据扩展名采用 YAML 或是 JSON 进行解码。
+// 角色组由 [RBAC.TenantRoleGroup] 获取，所以也可以是租户的角色组。
+// dryRun 为 true 时，只返回与 [Store] 中数据的差异，而不真正写入数据。
+//
+// 一般在启动时调用，以声明式的方式初始化角色数据。
+// 在写入之前会验证所有角色组的数据，只要有一个不合法，就不会写入任何数据。
+func (rbac *RBAC[T]) ApplyFile(fsys fs.FS, name string, dryRun bool) ([]*Diff[T], error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot[T], 0, 5)
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &snapshots)
+	case ".json":
+		err = json.Unmarshal(data, &snapshots)
+	default:
+		return nil, web.NewLocaleError("unsupported file type %s", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*RoleGroup[T], 0, len(snapshots))
+	diffs := make([]*Diff[T], 0, len(snapshots))
+	for _, s := range snapshots {
+		g, err := rbac.TenantRoleGroup(s.Group)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := g.Diff(s.Roles)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+		diffs = append(diffs, d)
+	}
+
+	if dryRun {
+		return diffs, nil
+	}
+
+	for i, g := range groups {
+		d, err := g.Import(snapshots[i].Roles)
+		if err != nil {
+			return nil, err
+		}
+		diffs[i] = d
+	}
+	return diffs, nil
+}
+
+func (info *RoleInfo[T]) role() *Role[T] {
+	return &Role[T]{
+		ID:        info.ID,
+		Parent:    info.Parent,
+		Name:      info.Name,
+		Desc:      info.Desc,
+		Resources: slices.Clone(info.Resources),
+		Denied:    slices.Clone(info.Denied),
+		Users:     slices.Clone(info.Users),
+
+		Memberships: cloneMemberships(info.Memberships),
+	}
+}
+
+// 按父角色在前的顺序排列 roles
+//
+// 父角色不在 roles 中的，当作顶层角色处理。
+func sortByParent[T comparable](roles []*RoleInfo[T]) []*RoleInfo[T] {
+	sorted := make([]*RoleInfo[T], 0, len(roles))
+	done := make(map[string]struct{}, len(roles))
+	in := func(id string) bool {
+		return slices.ContainsFunc(roles, func(r *RoleInfo[T]) bool { return r.ID == id })
+	}
+
+	for len(sorted) < len(roles) {
+		n := len(sorted)
+		for _, r := range roles {
+			if _, found := done[r.ID]; found {
+				continue
+			}
+
+			if _, found := done[r.Parent]; found || r.Parent == "" || !in(r.Parent) {
+				sorted = append(sorted, r)
+				done[r.ID] = struct{}{}
+			}
+		}
+
+		if n == len(sorted) { // 存在循环引用，由 Validate 保证不会出现。
+			break
+		}
+	}
+	return sorted
+}
+
+func sameRole[T comparable](a, b *RoleInfo[T]) bool {
+	return a.Parent == b.Parent && a.Name == b.Name && a.Desc == b.Desc &&
+		sameSet(a.Resources, b.Resources) && sameSet(a.Denied, b.Denied) && sameSet(a.Users, b.Users) &&
+		slices.EqualFunc(sortMemberships(a.Memberships), sortMemberships(b.Memberships), func(x, y *Membership[T]) bool {
+			return x.UID == y.UID && x.GrantedBy == y.GrantedBy && x.Expires.Equal(y.Expires)
+		})
+}
+
+func cloneMemberships[T comparable](ms []*Membership[T]) []*Membership[T] {
+	if len(ms) == 0 {
+		return nil
+	}
+
+	cloned := make([]*Membership[T], 0, len(ms))
+	for _, m := range ms {
+		v := *m
+		cloned = append(cloned, &v)
+	}
+	return cloned
+}
+
+// 按 UID 排序 ms，UID 无法比较大小，所以采用其字符串形式。
+func sortMemberships[T comparable](ms []*Membership[T]) []*Membership[T] {
+	return slices.SortedFunc(slices.Values(ms), func(x, y *Membership[T]) int {
+		return cmp.Compare(fmt.Sprint(x.UID), fmt.Sprint(y.UID))
+	})
+}
+
+// a 与 b 是否包含相同的元素，不考虑顺序，但重复元素的数量也需要相同。
+func sameSet[E comparable](a, b []E) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := make(map[E]int, len(a))
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		if counts[v] == 0 {
+			return false
+		}
+		counts[v]--
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func newSnapshotRBAC(a *assert.Assertion) *RBAC[string] {
+	s := testserver.New(a)
+	rbac := New(s, NewCacheStore[string](s, "c_"), func(*web.Context) (string, string, web.Responser) { return "1", "", nil })
+	ga := rbac.NewResourceGroup("a", nil)
+	ga.New("1", nil)
+	ga.New("2", nil)
+	rbac.NewResourceGroup("b", nil).New("1", nil)
+	return rbac
+}
+
+func TestRoleGroup_Validate(t *testing.T) {
+	a := assert.New(t, false)
+	rbac := newSnapshotRBAC(a)
+	g, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g)
+
+	a.NotError(g.Validate([]*RoleInfo[string]{
+		{ID: "r1", Name: "r1", Resources: []string{"a_*", "b_1"}, Users: []string{"u1"}},
+		{ID: "r2", Name: "r2", Parent: "r1", Resources: []string{"a_1"}, Denied: []string{"a_2"}, Users: []string{"u2"}},
+	}))
+
+	a.Error(g.Validate([]*RoleInfo[string]{{ID: "r1"}}))
+	a.Error(g.Validate([]*RoleInfo[string]{{ID: "r1", Name: "r1"}, {ID: "r1", Name: "r1"}}))
+	a.Error(g.Validate([]*RoleInfo[string]{{ID: "r1", Name: "r1", Parent: "not-exists"}}))
+	a.Error(g.Validate([]*RoleInfo[string]{{ID: "r1", Name: "r1", Resources: []string{"c_1"}}}))
+	a.Error(g.Validate([]*RoleInfo[string]{{ID: "r1", Name: "r1", Resources: []string{"a_1"}, Denied: []string{"a_1"}}}))
+
+	// 循环引用
+	a.Error(g.Validate([]*RoleInfo[string]{
+		{ID: "r1", Name: "r1", Parent: "r2"},
+		{ID: "r2", Name: "r2", Parent: "r1"},
+	}))
+
+	// 子角色的资源不在父角色中
+	a.Error(g.Validate([]*RoleInfo[string]{
+		{ID: "r1", Name: "r1", Resources: []string{"a_1"}},
+		{ID: "r2", Name: "r2", Parent: "r1", Resources: []string{"a_2"}},
+	}))
+
+	// 用户已经在父角色中
+	a.Error(g.Validate([]*RoleInfo[string]{
+		{ID: "r1", Name: "r1", Users: []string{"u1"}},
+		{ID: "r2", Name: "r2", Parent: "r1", Users: []string{"u1"}},
+	}))
+}
+
+func TestRoleGroup_Import(t *testing.T) {
+	a := assert.New(t, false)
+	rbac := newSnapshotRBAC(a)
+	g, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g)
+
+	old, err := g.NewRole("old", "", "")
+	a.NotError(err).NotNil(old)
+
+	roles := []*RoleInfo[string]{
+		{ID: "r2", Name: "r2", Parent: "r1", Resources: []string{"a_1"}, Users: []string{"u2"}},
+		{ID: "r1", Name: "r1", Resources: []string{"a_*"}, Denied: []string{"a_2"}, Users: []string{"u1"}},
+	}
+
+	d, err := g.Diff(roles)
+	a.NotError(err).NotNil(d).
+		Length(d.Added, 2).
+		Empty(d.Changed).
+		Length(d.Deleted, 1).
+		Equal(d.Deleted[0].ID, old.ID)
+	a.NotNil(g.Role(old.ID)).Nil(g.Role("r1")) // 未真正写入
+
+	d, err = g.Import(roles)
+	a.NotError(err).NotNil(d).Length(d.Added, 2)
+	a.Nil(g.Role(old.ID)).
+		NotNil(g.Role("r1")).
+		Equal(g.Role("r2").parent, g.Role("r1")).
+		True(g.isAllow("u1", "a_1")).
+		False(g.isAllow("u1", "a_2")).
+		True(g.isAllow("u2", "a_1"))
+
+	// 导出的数据再导入，没有差异。
+	s := g.Export()
+	a.Equal(s.Group, "g1").Length(s.Roles, 2).Equal(s.Roles[0].ID, "r1")
+	d, err = g.Diff(s.Roles)
+	a.NotError(err).True(d.Empty())
+
+	// 修改
+	roles[0].Users = []string{"u3"}
+	d, err = g.Import(roles)
+	a.NotError(err).Length(d.Changed, 1).Empty(d.Added).Empty(d.Deleted)
+	a.False(g.isAllow("u2", "a_1")).True(g.isAllow("u3", "a_1"))
+
+	// 不合法的数据
+	d, err = g.Import([]*RoleInfo[string]{{ID: "r1", Name: "r1", Resources: []string{"c_1"}}})
+	a.Error(err).Nil(d).NotNil(g.Role("r2"))
+}
+
+func TestRBAC_ApplyFile(t *testing.T) {
+	a := assert.New(t, false)
+	rbac := newSnapshotRBAC(a)
+	g, err := rbac.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g)
+
+	fsys := fstest.MapFS{
+		"rbac.yaml": &fstest.MapFile{Data: []byte(`
+- group: g1
+  roles:
+    - id: r1
+      name: r1
+      resources: ["a_*"]
+      users: ["u1"]
+- group: t1
+  roles:
+    - id: r1
+      name: r1
+      resources: ["b_1"]
+      users: ["u2"]
+`)},
+		"rbac.json": &fstest.MapFile{Data: []byte(`[{"group":"g1","roles":[{"id":"r1","name":"r1","resources":["a_1"]}]}]`)},
+		"invalid.json": &fstest.MapFile{Data: []byte(`[
+			{"group":"g1","roles":[]},
+			{"group":"t1","roles":[{"id":"r1","name":"r1","resources":["c_1"]}]}
+		]`)},
+		"rbac.toml": &fstest.MapFile{Data: []byte(``)},
+	}
+
+	diffs, err := rbac.ApplyFile(fsys, "rbac.yaml", true)
+	a.NotError(err).Length(diffs, 2).
+		Length(diffs[0].Added, 1).
+		Length(diffs[1].Added, 1).
+		Nil(g.Role("r1"))
+
+	diffs, err = rbac.ApplyFile(fsys, "rbac.yaml", false)
+	a.NotError(err).Length(diffs, 2)
+	t1, err := rbac.TenantRoleGroup("t1")
+	a.NotError(err).
+		True(g.isAllow("u1", "a_2")).
+		True(t1.isAllow("u2", "b_1")).
+		False(t1.isAllow("u1", "a_2"))
+
+	diffs, err = rbac.ApplyFile(fsys, "rbac.json", false)
+	a.NotError(err).Length(diffs, 1).Length(diffs[0].Changed, 1)
+	a.False(g.isAllow("u1", "a_1")).Empty(g.Role("r1").Users)
+
+	// 有一个不合法，所有的都不会写入。
+	diffs, err = rbac.ApplyFile(fsys, "invalid.json", false)
+	a.Error(err).Nil(diffs).NotNil(g.Role("r1"))
+
+	diffs, err = rbac.ApplyFile(fsys, "rbac.toml", false)
+	a.Error(err).Nil(diffs)
+}
+
+func TestSameSet(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.True(sameSet([]string{"a", "b"}, []string{"b", "a"})).
+		True(sameSet[string](nil, []string{})).
+		False(sameSet([]string{"a", "b"}, []string{"a", "c"})).
+		False(sameSet([]string{"a"}, []string{"a", "a"})).
+		False(sameSet([]string{"a", "a", "b"}, []string{"a", "b", "b"}))
+}
@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/issue9/web"
+)
+
+// 数据表的变更记录
+//
+// 每一个元素表示一个版本，已经发布的版本不应该再修改，只能在末尾追加。
+// 语句中的 {prefix} 会被替换为表名前缀，{uid} 会被替换为用户 ID 的字段类型。
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE {prefix}rbac_roles (
+			gid VARCHAR(100) NOT NULL,
+			id VARCHAR(100) NOT NULL,
+			parent VARCHAR(100) NOT NULL,
+			name VARCHAR(200) NOT NULL,
+			description TEXT NOT NULL,
+			PRIMARY KEY (gid, id)
+		)`,
+		`CREATE TABLE {prefix}rbac_role_resources (
+			gid VARCHAR(100) NOT NULL,
+			role_id VARCHAR(100) NOT NULL,
+			resource VARCHAR(200) NOT NULL,
+			PRIMARY KEY (gid, role_id, resource)
+		)`,
+		`CREATE TABLE {prefix}rbac_role_users (
+			gid VARCHAR(100) NOT NULL,
+			role_id VARCHAR(100) NOT NULL,
+			uid {uid} NOT NULL,
+			PRIMARY KEY (gid, role_id, uid)
+		)`,
+	},
+	{
+		`ALTER TABLE {prefix}rbac_role_resources ADD COLUMN denied SMALLINT NOT NULL DEFAULT 0`,
+	},
+	{
+		`ALTER TABLE {prefix}rbac_role_users ADD COLUMN expires BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE {prefix}rbac_role_users ADD COLUMN granted_by {uid} NULL`,
+	},
+}
+
+type sqlStore[T comparable] struct {
+	db     *sql.DB
+	prefix string
+}
+
+// NewSQLStore 声明基于 [database/sql] 的 [Store] 实现
+//
+// prefix 为表名前缀，数据表需要先通过 [MigrateSQLStore] 创建。
+// SQL 语句以 ? 作为占位符，适用于 SQLite、MySQL 等数据库。
+func NewSQLStore[T comparable](db *sql.DB, prefix string) Store[T] {
+	return &sqlStore[T]{db: db, prefix: prefix}
+}
+
+// MigrateSQLStore 创建或是升级 [NewSQLStore] 所需要的数据表
+//
+// 数据表的版本号保存在 prefix+rbac_versions 表中，多次调用只会执行未执行过的变更。
+// T 为用户 ID 的类型，整数类型对应 BIGINT，其它类型对应 VARCHAR(100)。
+func MigrateSQLStore[T comparable](db *sql.DB, prefix string) error {
+	uid := "VARCHAR(100)"
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uid = "BIGINT"
+	}
+	replacer := strings.NewReplacer("{prefix}", prefix, "{uid}", uid)
+
+	return withTx(db, func(tx *sql.Tx) error {
+		versions := prefix + "rbac_versions"
+		if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS " + versions + " (version INTEGER NOT NULL)"); err != nil {
+			return err
+		}
+
+		var ver int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM " + versions).Scan(&ver); err != nil {
+			return err
+		}
+
+		for ; ver < len(sqlMigrations); ver++ {
+			for _, query := range sqlMigrations[ver] {
+				if _, err := tx.Exec(replacer.Replace(query)); err != nil {
+					return err
+				}
+			}
+
+			if _, err := tx.Exec("INSERT INTO "+versions+" (version) VALUES (?)", ver+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func withTx(db *sql.DB, f func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if msg := recover(); msg != nil {
+			tx.Rollback()
+			panic(msg)
+		}
+	}()
+
+	if err := f(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore[T]) table(name string) string { return s.prefix + "rbac_" + name }
+
+func (s *sqlStore[T]) Load(gid string) (map[string]*Role[T], error) {
+	roles := make(map[string]*Role[T], 10)
+
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id,parent,name,description FROM "+s.table("roles")+" WHERE gid=?", gid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			r := &Role[T]{Resources: make([]string, 0, 10), Users: make([]T, 0, 10)}
+			if err := rows.Scan(&r.ID, &r.Parent, &r.Name, &r.Desc); err != nil {
+				return err
+			}
+			roles[r.ID] = r
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query("SELECT role_id,resource,denied FROM "+s.table("role_resources")+" WHERE gid=?", gid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, res string
+			var denied bool
+			if err := rows.Scan(&id, &res, &denied); err != nil {
+				return err
+			}
+
+			r, found := roles[id]
+			switch {
+			case !found:
+			case denied:
+				r.Denied = append(r.Denied, res)
+			default:
+				r.Resources = append(r.Resources, res)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query("SELECT role_id,uid,expires,granted_by FROM "+s.table("role_users")+" WHERE gid=?", gid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			var uid T
+			var expires int64 // 毫秒，0 表示永不过期。
+			var grantedBy sql.Null[T]
+			if err := rows.Scan(&id, &uid, &expires, &grantedBy); err != nil {
+				return err
+			}
+
+			r, found := roles[id]
+			if !found {
+				continue
+			}
+			r.Users = append(r.Users, uid)
+			if expires > 0 || grantedBy.Valid {
+				m := &Membership[T]{UID: uid, GrantedBy: grantedBy.V}
+				if expires > 0 {
+					m.Expires = time.UnixMilli(expires)
+				}
+				r.Memberships = append(r.Memberships, m)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 确定 parent 关系
+	for _, role := range roles {
+		if role.Parent != "" {
+			role.parent = roles[role.Parent]
+		}
+	}
+
+	return roles, nil
+}
+
+func (s *sqlStore[T]) Del(gid, id string) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		for _, table := range []string{"role_users", "role_resources"} {
+			if _, err := tx.Exec("DELETE FROM "+s.table(table)+" WHERE gid=? AND role_id=?", gid, id); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("DELETE FROM "+s.table("roles")+" WHERE gid=? AND id=?", gid, id)
+		return err
+	})
+}
+
+func (s *sqlStore[T]) Set(gid string, role *Role[T]) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		// 角色可能已经被其它节点删除，此时不能再写入关联数据。
+		var cnt int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM "+s.table("roles")+" WHERE gid=? AND id=?", gid, role.ID).Scan(&cnt); err != nil {
+			return err
+		}
+		if cnt == 0 {
+			return web.NewLocaleError("not found role %s", role.ID)
+		}
+
+		_, err := tx.Exec("UPDATE "+s.table("roles")+" SET parent=?,name=?,description=? WHERE gid=? AND id=?",
+			role.Parent, role.Name, role.Desc, gid, role.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{"role_users", "role_resources"} {
+			if _, err := tx.Exec("DELETE FROM "+s.table(table)+" WHERE gid=? AND role_id=?", gid, role.ID); err != nil {
+				return err
+			}
+		}
+		return s.insertRelations(tx, gid, role)
+	})
+}
+
+func (s *sqlStore[T]) Add(gid string, role *Role[T]) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		var cnt int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM "+s.table("roles")+" WHERE gid=? AND id=?", gid, role.ID).Scan(&cnt); err != nil {
+			return err
+		}
+		if cnt > 0 {
+			// 由 server.UniqueID() 保证 role.ID 唯一性，如果不唯一，肯定是代码级别的错误。
+			panic(fmt.Sprintf("角色 %s 已经存在", role.ID))
+		}
+
+		_, err := tx.Exec("INSERT INTO "+s.table("roles")+" (gid,id,parent,name,description) VALUES (?,?,?,?,?)",
+			gid, role.ID, role.Parent, role.Name, role.Desc)
+		if err != nil {
+			return err
+		}
+		return s.insertRelations(tx, gid, role)
+	})
+}
+
+func (s *sqlStore[T]) insertRelations(tx *sql.Tx, gid string, role *Role[T]) error {
+	for _, res := range role.Resources {
+		if _, err := tx.Exec("INSERT INTO "+s.table("role_resources")+" (gid,role_id,resource,denied) VALUES (?,?,?,0)", gid, role.ID, res); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range role.Denied {
+		if _, err := tx.Exec("INSERT INTO "+s.table("role_resources")+" (gid,role_id,resource,denied) VALUES (?,?,?,1)", gid, role.ID, res); err != nil {
+			return err
+		}
+	}
+
+	for _, uid := range role.Users {
+		var expires int64
+		var grantedBy sql.Null[T]
+		if m := role.membership(uid); m != nil {
+			if !m.Expires.IsZero() {
+				expires = m.Expires.UnixMilli()
+			}
+			var zero T
+			grantedBy = sql.Null[T]{V: m.GrantedBy, Valid: m.GrantedBy != zero}
+		}
+
+		_, err := tx.Exec("INSERT INTO "+s.table("role_users")+" (gid,role_id,uid,expires,granted_by) VALUES (?,?,?,?,?)",
+			gid, role.ID, uid, expires, grantedBy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	_ "modernc.org/sqlite"
+
+	"github.com/issue9/webuse/v7/middlewares/acl/rbac"
+	"github.com/issue9/webuse/v7/middlewares/acl/rbac/rbactest"
+)
+
+func newDB(a *assert.Assertion) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	a.NotError(err).NotNil(db)
+	db.SetMaxOpenConns(1) // 每个连接都是独立的内存数据库
+	a.TB().Cleanup(func() { a.NotError(db.Close()) })
+	return db
+}
+
+func TestMigrateSQLStore(t *testing.T) {
+	a := assert.New(t, false)
+	db := newDB(a)
+
+	a.NotError(rbac.MigrateSQLStore[int64](db, "p_"))
+	a.NotError(rbac.MigrateSQLStore[int64](db, "p_")) // 重复执行
+
+	var ver int
+	a.NotError(db.QueryRow("SELECT MAX(version) FROM p_rbac_versions").Scan(&ver)).Equal(ver, 3)
+}
+
+func TestSQLStore(t *testing.T) {
+	a := assert.New(t, false)
+	db := newDB(a)
+
+	a.NotError(rbac.MigrateSQLStore[int64](db, "int64_"))
+	rbactest.Test(a, rbac.NewSQLStore[int64](db, "int64_"))
+
+	a.NotError(rbac.MigrateSQLStore[string](db, "string_"))
+	rbactest.Test(a, rbac.NewSQLStore[string](db, "string_"))
+
+	// 关联的资源和用户
+
+	s := rbac.NewSQLStore[int64](db, "int64_")
+	parent := &rbac.Role[int64]{ID: "p", Name: "parent", Resources: []string{"r1", "r2"}}
+	child := &rbac.Role[int64]{ID: "c", Parent: "p", Name: "child", Resources: []string{"r1"}, Users: []int64{1, 2}}
+	a.NotError(s.Add("g2", parent)).NotError(s.Add("g2", child))
+
+	child.Users = []int64{2, 3}
+	child.Resources = nil
+	child.Denied = []string{"r2"}
+	a.NotError(s.Set("g2", child))
+
+	roles, err := s.Load("g2")
+	a.NotError(err).Length(roles, 2)
+	a.Equal(roles["p"].Resources, []string{"r1", "r2"}).
+		Empty(roles["p"].Users).
+		Empty(roles["c"].Resources).
+		Equal(roles["c"].Denied, []string{"r2"}).
+		Equal(roles["c"].Users, []int64{2, 3})
+
+	// 关联的附加信息
+	expires := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	child.Memberships = []*rbac.Membership[int64]{{UID: 2, Expires: expires}, {UID: 3, GrantedBy: 2}}
+	a.NotError(s.Set("g2", child))
+	roles, err = s.Load("g2")
+	a.NotError(err).Length(roles["c"].Memberships, 2)
+	for _, m := range roles["c"].Memberships {
+		switch m.UID {
+		case 2:
+			a.True(m.Expires.Equal(expires)).Zero(m.GrantedBy)
+		case 3:
+			a.True(m.Expires.IsZero()).Equal(m.GrantedBy, 2)
+		}
+	}
+
+	a.NotError(s.Del("g2", "c"))
+	roles, err = s.Load("g2")
+	a.NotError(err).Length(roles, 1)
+	var cnt int
+	a.NotError(db.QueryRow("SELECT COUNT(*) FROM int64_rbac_role_users WHERE gid='g2'").Scan(&cnt)).Zero(cnt)
+
+	// 已经删除的角色不能再更新，也不会留下关联数据。
+	a.Error(s.Set("g2", child))
+	a.NotError(db.QueryRow("SELECT COUNT(*) FROM int64_rbac_role_users WHERE gid='g2'").Scan(&cnt)).Zero(cnt)
+	a.NotError(db.QueryRow("SELECT COUNT(*) FROM int64_rbac_role_resources WHERE gid='g2' AND role_id='c'").Scan(&cnt)).Zero(cnt)
+
+	// 重复添加时 panic，且不影响之后的操作。
+	a.Panic(func() { s.Add("g2", parent) })
+	a.NotError(s.Add("g2", child))
+}
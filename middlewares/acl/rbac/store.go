@@ -5,9 +5,12 @@
 package rbac
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/issue9/cache"
 	"github.com/issue9/web"
@@ -34,11 +37,40 @@ type Store[T comparable] interface {
 	Add(gid string, r *Role[T]) error
 }
 
+// Watcher 可监视数据变化的 [Store]
+//
+// 当多个节点共用同一份数据时，某一节点对角色的修改需要通知到其它节点。
+// 如果 [Store] 实现了此接口，[RBAC] 会在数据变化时重新加载对应的角色组。
+type Watcher interface {
+	// Version 角色组 gid 的数据版本号
+	//
+	// 通过 [Store] 对 gid 的每一次修改，都会使该值加 1。
+	Version(gid string) (uint64, error)
+
+	// Watch 监视由其它节点对数据的修改
+	//
+	// 当角色组的数据被其它节点修改时调用 f，gid 为角色组的 ID，ver 为修改之后的版本号。
+	// 只需要监视通过当前对象的 Load 加载过的角色组。
+	//
+	// 此方法会作为服务运行，直到 ctx 被取消才返回。
+	Watch(ctx context.Context, f func(gid string, ver uint64)) error
+}
+
 type cacheStore[T comparable] struct {
 	s web.Server
 	c web.Cache
 }
 
+type watchCacheStore[T comparable] struct {
+	*cacheStore[T]
+	ver      web.Cache
+	interval time.Duration
+
+	// 已经加载的角色组及其版本号
+	versions    map[string]uint64
+	versionsMux sync.Mutex
+}
+
 // NewCacheStore 声明基于 [web.Cache] 的 [Store] 实现
 //
 // NOTE: 缓存是易失性的，不太具备实用性，可用于测试。
@@ -52,6 +84,22 @@ func NewCacheStore[T comparable](s web.Server, prefix string) Store[T] {
 	}
 }
 
+// NewWatchCacheStore 声明基于 [web.Cache] 且实现了 [Watcher] 的 [Store]
+//
+// 与 [NewCacheStore] 相同，但会以 interval 的频率检测数据的版本号，以发现其它节点对数据的修改。
+func NewWatchCacheStore[T comparable](s web.Server, prefix string, interval time.Duration) Store[T] {
+	if interval <= 0 {
+		panic("参数 interval 必须大于 0")
+	}
+
+	return &watchCacheStore[T]{
+		cacheStore: NewCacheStore[T](s, prefix).(*cacheStore[T]),
+		ver:        web.NewCache(prefix+"version:", s.Cache()),
+		interval:   interval,
+		versions:   make(map[string]uint64, 10),
+	}
+}
+
 func (s *cacheStore[T]) getRoleIDs(gid string) ([]string, error) {
 	ids := []string{}
 	err := s.c.Get(gid, &ids)
@@ -134,3 +182,99 @@ func (s *cacheStore[T]) Add(gid string, role *Role[T]) error {
 
 	return s.setRoleIDs(gid, append(ids, role.ID))
 }
+
+func (s *watchCacheStore[T]) Version(gid string) (uint64, error) {
+	n, _, _, err := s.ver.Counter(gid, cache.Forever)
+	return n, err
+}
+
+func (s *watchCacheStore[T]) Watch(ctx context.Context, f func(string, uint64)) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.versionsMux.Lock()
+			gids := make(map[string]uint64, len(s.versions))
+			for gid := range s.versions {
+				ver, err := s.Version(gid)
+				if err != nil {
+					s.s.Logs().ERROR().Error(err)
+					continue
+				}
+				if ver != s.versions[gid] {
+					s.versions[gid] = ver
+					gids[gid] = ver
+				}
+			}
+			s.versionsMux.Unlock()
+
+			for gid, ver := range gids {
+				f(gid, ver)
+			}
+		}
+	}
+}
+
+func (s *watchCacheStore[T]) Load(gid string) (map[string]*Role[T], error) {
+	ver, err := s.Version(gid) // 先于数据读取，保证之后的修改都能被发现。
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.cacheStore.Load(gid)
+	if err != nil {
+		return nil, err
+	}
+
+	s.versionsMux.Lock()
+	s.versions[gid] = ver
+	s.versionsMux.Unlock()
+	return roles, nil
+}
+
+func (s *watchCacheStore[T]) Del(gid, id string) error {
+	if err := s.cacheStore.Del(gid, id); err != nil {
+		return err
+	}
+	return s.incr(gid)
+}
+
+func (s *watchCacheStore[T]) Set(gid string, role *Role[T]) error {
+	if err := s.cacheStore.Set(gid, role); err != nil {
+		return err
+	}
+	return s.incr(gid)
+}
+
+func (s *watchCacheStore[T]) Add(gid string, role *Role[T]) error {
+	if err := s.cacheStore.Add(gid, role); err != nil {
+		return err
+	}
+	return s.incr(gid)
+}
+
+// 增加 gid 的版本号
+//
+// 如果中间没有其它节点的修改，那么同时更新本地记录的版本号，
+// 否则保留原来的值，由 Watch 通知重新加载。
+func (s *watchCacheStore[T]) incr(gid string) error {
+	_, f, _, err := s.ver.Counter(gid, cache.Forever)
+	if err != nil {
+		return err
+	}
+	ver, err := f(1)
+	if err != nil {
+		return err
+	}
+
+	s.versionsMux.Lock()
+	if old, found := s.versions[gid]; found && old+1 == ver {
+		s.versions[gid] = ver
+	}
+	s.versionsMux.Unlock()
+	return nil
+}
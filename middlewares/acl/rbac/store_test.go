@@ -5,9 +5,12 @@
 package rbac_test
 
 import (
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert/v4"
+	"github.com/issue9/web"
 
 	"github.com/issue9/webuse/v7/internal/testserver"
 	"github.com/issue9/webuse/v7/middlewares/acl/rbac"
@@ -20,4 +23,45 @@ func TestCacheStore(t *testing.T) {
 
 	rbactest.Test(a, rbac.NewCacheStore[int64](s, "int64_"))
 	rbactest.Test(a, rbac.NewCacheStore[string](s, "string_"))
+	rbactest.Test(a, rbac.NewWatchCacheStore[string](s, "watch_", time.Second))
+
+	a.PanicString(func() { rbac.NewWatchCacheStore[string](s, "watch_", 0) }, "参数 interval 必须大于 0")
+}
+
+func TestCacheStore_Watch(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	getUID := func(*web.Context) (string, string, web.Responser) { return "1", "", nil }
+
+	// 两个节点共用同一个缓存
+	store1 := rbac.NewWatchCacheStore[string](s, "c_", 50*time.Millisecond)
+	_, ok := store1.(rbac.Watcher)
+	a.True(ok)
+	rbac1 := rbac.New(s, store1, getUID)
+	rbac1.NewResourceGroup("a", nil).New("1", nil)
+	g1, err := rbac1.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g1).Equal(g1.Version(), 0)
+
+	rbac2 := rbac.New(s, rbac.NewWatchCacheStore[string](s, "c_", 50*time.Millisecond), getUID)
+	rbac2.NewResourceGroup("a", nil).New("1", nil)
+	g2, err := rbac2.NewRoleGroup("g1", "admin")
+	a.NotError(err).NotNil(g2).Empty(slices.Collect(g2.Roles()))
+
+	r1, err := g1.NewRole("r1", "", "")
+	a.NotError(err).NotNil(r1).
+		NotError(r1.Link("u1")).
+		Equal(g1.Version(), 2)
+
+	time.Sleep(200 * time.Millisecond)
+	a.Equal(g2.Version(), 2).
+		Length(slices.Collect(g2.Roles()), 1).
+		Length(g2.UserRoles("u1"), 1)
+
+	// 本节点的修改不会触发重新加载
+	a.Equal(g1.Role(r1.ID), r1)
+
+	r2 := g2.Role(r1.ID)
+	a.NotError(r2.Unlink("u1")).Equal(g2.Version(), 3)
+	time.Sleep(200 * time.Millisecond)
+	a.Equal(g1.Version(), 3).Empty(g1.UserRoles("u1"))
 }
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"reflect"
+
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+// UIDer 可以返回用户 ID 的对象
+//
+// 如果 [auth.Auth] 附加在 [web.Context] 上的数据实现了此接口，
+// 那么可以由 [AuthUID] 和 [PrincipalUID] 自动生成 [GetUIDFunc]。
+type UIDer[T comparable] interface {
+	GetUID() T
+}
+
+// Tenanter 可以返回租户 ID 的对象
+//
+// 如果 [UIDer] 的实现者同时实现了此接口，
+// 那么由 [AuthUID] 和 [PrincipalUID] 生成的 [GetUIDFunc] 也会返回租户 ID。
+type Tenanter interface {
+	GetTenant() string
+}
+
+// AuthUID 从 [auth.Auth] 生成 [GetUIDFunc]
+//
+// U 要么与 T 的类型相同，要么实现了 [UIDer] 接口，否则会 panic。
+// 如果 a 中找不到登录信息，返回 [web.ProblemUnauthorized]。
+func AuthUID[T comparable, U any](a auth.Auth[U]) GetUIDFunc[T] {
+	if ut := reflect.TypeFor[U](); ut != reflect.TypeFor[T]() && !ut.Implements(reflect.TypeFor[UIDer[T]]()) {
+		panic("U 必须是 T 类型或是实现了 UIDer[T] 接口")
+	}
+
+	return func(ctx *web.Context) (T, string, web.Responser) {
+		if v, found := a.GetInfo(ctx); found {
+			if uid, ok := toUID[T](v); ok {
+				return uid, toTenant(v), nil
+			}
+		}
+
+		var zero T
+		return zero, "", ctx.Problem(web.ProblemUnauthorized)
+	}
+}
+
+// PrincipalUID 从 [auth.Principal] 生成 [GetUIDFunc]
+//
+// 与 [AuthUID] 不同，此函数不绑定具体的验证方式，
+// 而是采用最后写入的类型为 T 或是实现了 [UIDer] 接口的主体。
+func PrincipalUID[T comparable]() GetUIDFunc[T] {
+	return func(ctx *web.Context) (uid T, tenant string, resp web.Responser) {
+		var found bool
+		for _, v := range auth.Principals(ctx) {
+			if id, ok := toUID[T](v); ok {
+				uid, tenant, found = id, toTenant(v), true
+			}
+		}
+
+		if !found {
+			return uid, "", ctx.Problem(web.ProblemUnauthorized)
+		}
+		return uid, tenant, nil
+	}
+}
+
+func toTenant(v any) string {
+	if t, ok := v.(Tenanter); ok {
+		return t.GetTenant()
+	}
+	return ""
+}
+
+func toUID[T comparable](v any) (T, bool) {
+	switch vv := v.(type) {
+	case T:
+		return vv, true
+	case UIDer[T]:
+		return vv.GetUID(), true
+	default:
+		var zero T
+		return zero, false
+	}
+}
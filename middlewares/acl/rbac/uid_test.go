@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package rbac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/types"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+type user struct {
+	id int64
+}
+
+func (u *user) GetUID() int64 { return u.id }
+
+type tenantUser struct {
+	user
+	tenant string
+}
+
+func (u *tenantUser) GetTenant() string { return u.tenant }
+
+type slotAuth[T any] struct {
+	*auth.Slot[T]
+}
+
+func (a *slotAuth[T]) Middleware(next web.HandlerFunc, _, _, _ string) web.HandlerFunc { return next }
+
+func (a *slotAuth[T]) Logout(ctx *web.Context) error {
+	a.Delete(ctx)
+	return nil
+}
+
+func (a *slotAuth[T]) GetInfo(ctx *web.Context) (T, bool) { return a.Get(ctx) }
+
+func TestAuthUID(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	ids := &slotAuth[int64]{Slot: auth.NewSlot[int64]("ids")}
+	users := &slotAuth[*user]{Slot: auth.NewSlot[*user]("users")}
+
+	idUID := AuthUID[int64](ids)
+	userUID := AuthUID[int64](users)
+
+	ctx := s.NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", nil), types.NewContext())
+	uid, tenant, resp := idUID(ctx)
+	a.Zero(uid).Empty(tenant).NotNil(resp)
+	uid, tenant, resp = userUID(ctx)
+	a.Zero(uid).Empty(tenant).NotNil(resp)
+
+	ids.Set(ctx, 5)
+	users.Set(ctx, &user{id: 6})
+	uid, tenant, resp = idUID(ctx)
+	a.Nil(resp).Equal(uid, 5).Empty(tenant)
+	uid, tenant, resp = userUID(ctx)
+	a.Nil(resp).Equal(uid, 6).Empty(tenant)
+
+	tenants := &slotAuth[*tenantUser]{Slot: auth.NewSlot[*tenantUser]("tenants")}
+	tenants.Set(ctx, &tenantUser{user: user{id: 7}, tenant: "t1"})
+	uid, tenant, resp = AuthUID[int64](tenants)(ctx)
+	a.Nil(resp).Equal(uid, 7).Equal(tenant, "t1")
+
+	a.PanicString(func() {
+		AuthUID[int64](&slotAuth[string]{Slot: auth.NewSlot[string]("str")})
+	}, "U 必须是 T 类型或是实现了 UIDer[T] 接口")
+}
+
+func TestPrincipalUID(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	getUID := PrincipalUID[int64]()
+
+	ctx := s.NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", nil), types.NewContext())
+	uid, tenant, resp := getUID(ctx)
+	a.Zero(uid).Empty(tenant).NotNil(resp)
+
+	auth.NewSlot[int64]("ids").Set(ctx, 5)
+	uid, tenant, resp = getUID(ctx)
+	a.Nil(resp).Equal(uid, 5).Empty(tenant)
+
+	auth.NewSlot[*user]("users").Set(ctx, &user{id: 6})
+	auth.NewSlot[string]("str").Set(ctx, "str")
+	uid, tenant, resp = getUID(ctx)
+	a.Nil(resp).Equal(uid, 6).Empty(tenant)
+
+	auth.NewSlot[*tenantUser]("tenants").Set(ctx, &tenantUser{user: user{id: 7}, tenant: "t1"})
+	uid, tenant, resp = getUID(ctx)
+	a.Nil(resp).Equal(uid, 7).Equal(tenant, "t1")
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package acl
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+)
+
+// Resolver 根据可信代理获取客户端的真实 IP
+//
+// 仅在请求的直接来源是可信代理时，才会从报头中获取客户端的 IP，
+// 报头中的地址会从右往左依次检测，第一个不可信的地址即为客户端的 IP，
+// 以防止客户端通过伪造报头绕过访问控制。
+//
+// 零值表示不信任任何代理，始终以 [http.Request.RemoteAddr] 作为客户端的 IP。
+type Resolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// GenFunc 用于区分请求来源的函数
+//
+// 返回值一般作为缓存中键名的一部分，比如限流的配额、并发的数量等。
+type GenFunc = func(*web.Context) (string, error)
+
+// GenIP 以客户端 IP 区分请求的来源
+//
+// r 用于获取客户端的 IP，如果为空，表示不信任任何代理，直接采用连接的地址。
+func GenIP(r *Resolver) GenFunc {
+	if r == nil {
+		r = &Resolver{}
+	}
+
+	return func(ctx *web.Context) (string, error) {
+		ip, err := r.ClientIP(ctx)
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	}
+}
+
+// NewResolver 声明 [Resolver] 对象
+//
+// trusted 为可信代理的地址，可以是 IP 或是 CIDR，比如 10.0.0.0/8 或是 ::1；
+// headers 为获取客户端 IP 的报头，按顺序查找，仅采用第一个存在的报头。
+// 支持 [header.Forwarded]、[header.XForwardedFor] 和 [header.XRealIP]，
+// 为空时表示以上所有。
+//
+// NOTE: 如果代理不会覆盖或是追加某个报头，该报头的值可能由客户端伪造，
+// 此时应该在 headers 中仅指定代理实际使用的报头。
+func NewResolver(trusted []string, headers ...string) (*Resolver, error) {
+	r := &Resolver{trusted: make([]netip.Prefix, 0, len(trusted))}
+
+	for _, t := range trusted {
+		if strings.IndexByte(t, '/') < 0 {
+			addr, err := netip.ParseAddr(t)
+			if err != nil {
+				return nil, web.NewLocaleError("invalid ip %s", t)
+			}
+			addr = addr.Unmap()
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(t)
+		if err != nil {
+			return nil, web.NewLocaleError("invalid ip %s", t)
+		}
+		if p.Addr().Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		r.trusted = append(r.trusted, p.Masked())
+	}
+
+	supported := []string{header.Forwarded, header.XForwardedFor, header.XRealIP}
+	if len(headers) == 0 {
+		headers = supported
+	}
+	for _, h := range headers {
+		index := slices.IndexFunc(supported, func(v string) bool { return strings.EqualFold(v, h) })
+		if index < 0 {
+			return nil, web.NewLocaleError("unsupported header %s", h)
+		}
+		r.headers = append(r.headers, supported[index])
+	}
+
+	return r, nil
+}
+
+// ClientIP 获取 ctx 对应客户端的 IP
+func (r *Resolver) ClientIP(ctx *web.Context) (netip.Addr, error) {
+	return r.RequestIP(ctx.Request())
+}
+
+// RequestIP 获取 req 对应客户端的 IP
+func (r *Resolver) RequestIP(req *http.Request) (netip.Addr, error) {
+	ip, err := parseAddr(req.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !r.isTrusted(ip) {
+		return ip, nil
+	}
+
+	for _, h := range r.headers {
+		values := req.Header.Values(h)
+		if len(values) == 0 {
+			continue
+		}
+
+		var chain []string
+		switch h {
+		case header.Forwarded:
+			chain = parseForwarded(values)
+		case header.XForwardedFor:
+			chain = splitList(values)
+		default: // X-Real-IP
+			chain = []string{strings.TrimSpace(values[len(values)-1])}
+		}
+
+		for _, node := range slices.Backward(chain) {
+			addr, err := parseAddr(node)
+			if err != nil { // unknown 或是隐藏的地址，以最后一个可信代理为准。
+				return ip, nil
+			}
+
+			if ip = addr; !r.isTrusted(ip) {
+				return ip, nil
+			}
+		}
+		return ip, nil // 所有地址都是可信代理，取最左侧的地址。
+	}
+
+	return ip, nil
+}
+
+func (r *Resolver) isTrusted(ip netip.Addr) bool {
+	return slices.ContainsFunc(r.trusted, func(p netip.Prefix) bool { return p.Contains(ip) })
+}
+
+// 解析可能带端口的 IP 地址
+func parseAddr(ip string) (netip.Addr, error) {
+	if addr, err := netip.ParseAddr(strings.Trim(ip, "[]")); err == nil {
+		return addr.Unmap(), nil
+	}
+
+	addr, err := netip.ParseAddrPort(ip)
+	if err != nil {
+		return netip.Addr{}, web.NewLocaleError("invalid ip %s", ip)
+	}
+	return addr.Addr().Unmap(), nil
+}
+
+func splitList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		for item := range strings.SplitSeq(v, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list
+}
+
+// 从 Forwarded 报头中提取所有 for 的值
+//
+// 格式参考 RFC 7239，比如：
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+//
+// 没有 for 参数的元素以空值表示。
+func parseForwarded(values []string) []string {
+	elems := splitList(values)
+	list := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		var node string
+		for pair := range strings.SplitSeq(elem, ";") {
+			k, v, _ := strings.Cut(pair, "=")
+			if strings.EqualFold(strings.TrimSpace(k), "for") {
+				node = strings.Trim(strings.TrimSpace(v), `"`)
+				break
+			}
+		}
+		list = append(list, node)
+	}
+	return list
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package acl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/mux/v9/types"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func TestNewResolver(t *testing.T) {
+	a := assert.New(t, false)
+
+	r, err := NewResolver([]string{"10.1.2.3/8", "::1", "::ffff:172.16.0.0/108"})
+	a.NotError(err).NotNil(r).
+		Equal(r.trusted, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("::1/128"),
+			netip.MustParsePrefix("172.16.0.0/12"),
+		}).
+		Equal(r.headers, []string{header.Forwarded, header.XForwardedFor, header.XRealIP})
+
+	r, err = NewResolver(nil, "x-real-ip")
+	a.NotError(err).NotNil(r).Empty(r.trusted).Equal(r.headers, []string{header.XRealIP})
+
+	r, err = NewResolver([]string{"10.0.0.1/33"})
+	a.Error(err).Nil(r)
+
+	r, err = NewResolver([]string{"10.0.0"})
+	a.Error(err).Nil(r)
+
+	r, err = NewResolver(nil, "X-Client-IP")
+	a.Error(err).Nil(r)
+}
+
+func TestResolver_RequestIP(t *testing.T) {
+	a := assert.New(t, false)
+
+	newRequest := func(remote string, kv ...string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		for i := 0; i < len(kv); i += 2 {
+			req.Header.Add(kv[i], kv[i+1])
+		}
+		return req
+	}
+
+	test := func(r *Resolver, req *http.Request, want string) {
+		t.Helper()
+		ip, err := r.RequestIP(req)
+		a.NotError(err).Equal(ip, netip.MustParseAddr(want))
+	}
+
+	// 零值，不信任任何代理。
+	zero := &Resolver{}
+	test(zero, newRequest("1.1.1.1:8080", header.XForwardedFor, "2.2.2.2"), "1.1.1.1")
+	test(zero, newRequest("[::ffff:1.1.1.1]:8080"), "1.1.1.1")
+	test(zero, newRequest("[2001:db8::1]:8080"), "2001:db8::1")
+	_, err := zero.RequestIP(newRequest("pipe"))
+	a.Error(err)
+
+	r, err := NewResolver([]string{"10.0.0.0/8", "fc00::/7"})
+	a.NotError(err).NotNil(r)
+
+	// 不可信的来源，报头被忽略。
+	test(r, newRequest("1.1.1.1:8080", header.XForwardedFor, "2.2.2.2"), "1.1.1.1")
+
+	// 可信的来源，但是没有报头
+	test(r, newRequest("10.0.0.1:8080"), "10.0.0.1")
+
+	// X-Forwarded-For
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "2.2.2.2"), "2.2.2.2")
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "3.3.3.3, 2.2.2.2, 10.0.0.2"), "2.2.2.2") // 3.3.3.3 可能是伪造的
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "3.3.3.3", header.XForwardedFor, "2.2.2.2, 10.0.0.2"), "2.2.2.2")
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "10.0.0.3, 10.0.0.2"), "10.0.0.3")
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "unknown, 10.0.0.2"), "10.0.0.2")
+	test(r, newRequest("[fc00::1]:8080", header.XForwardedFor, "2001:db8::1"), "2001:db8::1")
+
+	// Forwarded
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, `for=2.2.2.2;proto=http;by=10.0.0.1`), "2.2.2.2")
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, `For="[2001:db8:cafe::17]:4711", for=10.0.0.2`), "2001:db8:cafe::17")
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, `for=3.3.3.3, for="2.2.2.2:80";proto=https`), "2.2.2.2")
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, `for=_hidden, for=10.0.0.2`), "10.0.0.2")
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, `proto=https`), "10.0.0.1")
+
+	// Forwarded 优先于 X-Forwarded-For
+	test(r, newRequest("10.0.0.1:8080", header.Forwarded, "for=2.2.2.2", header.XForwardedFor, "3.3.3.3"), "2.2.2.2")
+
+	// X-Real-IP
+	test(r, newRequest("10.0.0.1:8080", header.XRealIP, "2.2.2.2"), "2.2.2.2")
+
+	// 仅指定了 X-Real-IP
+	r, err = NewResolver([]string{"10.0.0.0/8"}, header.XRealIP)
+	a.NotError(err).NotNil(r)
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "3.3.3.3", header.XRealIP, "2.2.2.2"), "2.2.2.2")
+	test(r, newRequest("10.0.0.1:8080", header.XForwardedFor, "3.3.3.3"), "10.0.0.1")
+}
+
+func TestGenIP(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	r, err := NewResolver([]string{"10.0.0.0/8"})
+	a.NotError(err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:8080"
+	req.Header.Set(header.XForwardedFor, "1.1.1.1")
+	ctx := s.NewContext(httptest.NewRecorder(), req, types.NewContext())
+
+	ip, err := GenIP(r)(ctx)
+	a.NotError(err).Equal(ip, "1.1.1.1")
+
+	ip, err = GenIP(nil)(ctx)
+	a.NotError(err).Equal(ip, "10.0.0.1")
+}
@@ -16,7 +16,6 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/openapi"
 
-	"github.com/issue9/webuse/v7/internal/mauth"
 	"github.com/issue9/webuse/v7/middlewares/auth"
 )
 
@@ -29,7 +28,8 @@ type AuthFunc[T any] func(username, password []byte) (v T, ok bool)
 
 // basic 验证中间件
 type basic[T any] struct {
-	srv web.Server
+	srv  web.Server
+	slot *auth.Slot[T]
 
 	auth  AuthFunc[T]
 	realm string
@@ -63,7 +63,8 @@ func New[T any](srv web.Server, af AuthFunc[T], realm string, proxy bool) auth.A
 	}
 
 	return &basic[T]{
-		srv: srv,
+		srv:  srv,
+		slot: auth.NewSlot[T]("basic"),
 
 		auth:  af,
 		realm: auth.BasicToken(`realm="` + realm + `"`),
@@ -97,7 +98,7 @@ func (b *basic[T]) Middleware(next web.HandlerFunc, method, _, _ string) web.Han
 			return b.unauthorization(ctx)
 		}
 
-		mauth.Set(ctx, v)
+		b.slot.Set(ctx, v)
 		return next(ctx)
 	}
 }
@@ -109,7 +110,7 @@ func (b *basic[T]) unauthorization(ctx *web.Context) web.Responser {
 	return ctx.Problem(b.problemID)
 }
 
-func (b *basic[T]) GetInfo(ctx *web.Context) (T, bool) { return mauth.Get[T](ctx) }
+func (b *basic[T]) GetInfo(ctx *web.Context) (T, bool) { return b.slot.Get(ctx) }
 
 // SecurityScheme 声明支持 openapi 的 [openapi.SecurityScheme] 对象
 func SecurityScheme(id string, desc web.LocaleStringer) *openapi.SecurityScheme {
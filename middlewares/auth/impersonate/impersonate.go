@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package impersonate 以其它用户的身份访问
+//
+// 在任意的 [auth.Auth] 之上提供模拟其它用户的能力，比如客服人员以客户的身份进行调试：
+//
+//	imp := impersonate.New(s, session, time.Hour, id, load, allow, nil)
+//	router.Use(imp) // 代替 session 作为中间件
+//
+//	router.Post("/impersonate/{uid}", func(ctx *web.Context) web.Responser {
+//	    uid, resp := ctx.PathString("uid", web.ProblemBadRequest)
+//	    if resp != nil {
+//	        return resp
+//	    }
+//	    if err := imp.Start(ctx, uid); err != nil {
+//	        return ctx.Error(err, web.ProblemForbidden)
+//	    }
+//	    return web.NoContent()
+//	})
+//
+// 模拟期间，[Impersonate.GetInfo] 返回的是被模拟的用户，
+// 而真实的操作者可以通过 [Impersonate.Actor] 获取。
+package impersonate
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/auth"
+)
+
+var (
+	errNotAllowed = web.NewLocaleError("not allowed to impersonate other user")
+	errNotLogin   = web.NewLocaleError("not login")
+)
+
+type (
+	// IDFunc 返回用户的唯一 ID
+	IDFunc[T any] func(T) string
+
+	// LoadFunc 根据用户 ID 加载用户数据
+	LoadFunc[T any] func(id string) (T, error)
+
+	// AllowFunc 判断 actor 是否允许以 target 的身份进行访问
+	AllowFunc[T any] func(actor, target T) bool
+
+	// AuditFunc 记录模拟访问的请求
+	//
+	// 每一个处于模拟状态的请求都会调用此函数。
+	AuditFunc[T any] func(ctx *web.Context, actor, target T)
+
+	// Impersonate 模拟其它用户的身份
+	Impersonate[T any] struct {
+		auth  auth.Auth[T]
+		slot  *auth.Slot[T]
+		cache web.Cache
+		ttl   time.Duration
+		id    IDFunc[T]
+		load  LoadFunc[T]
+		allow AllowFunc[T]
+		audit AuditFunc[T]
+	}
+)
+
+// ErrNotAllowed 不允许模拟指定的用户
+func ErrNotAllowed() error { return errNotAllowed }
+
+// ErrNotLogin 未登录
+func ErrNotLogin() error { return errNotLogin }
+
+// New 声明 [Impersonate] 对象
+//
+// a 为实际的验证方式，[Impersonate] 的中间件会调用 a 的中间件；
+// ttl 为模拟状态的有效时长；
+// id 用于获取用户的唯一 ID，模拟状态以真实操作者的 ID 进行保存；
+// load 根据 ID 加载被模拟的用户数据，每次请求都会调用，如有需要，应该由调用方自行缓存数据；
+// allow 是否允许模拟指定的用户，每次请求都会重新检测，一旦返回 false 即结束模拟状态；
+// audit 记录模拟状态的请求，如果为空，则以 INFO 级别的日志输出；
+func New[T any](s web.Server, a auth.Auth[T], ttl time.Duration, id IDFunc[T], load LoadFunc[T], allow AllowFunc[T], audit AuditFunc[T]) *Impersonate[T] {
+	if a == nil {
+		panic("参数 a 不能为空")
+	}
+	if id == nil {
+		panic("参数 id 不能为空")
+	}
+	if load == nil {
+		panic("参数 load 不能为空")
+	}
+	if allow == nil {
+		panic("参数 allow 不能为空")
+	}
+
+	if audit == nil {
+		audit = func(ctx *web.Context, actor, target T) {
+			ctx.Logs().INFO().LocaleString(web.Phrase("user %s impersonated as %s access %s", id(actor), id(target), ctx.Request().URL.String()))
+		}
+	}
+
+	return &Impersonate[T]{
+		auth:  a,
+		slot:  auth.NewSlot[T]("impersonate"),
+		cache: web.NewCache(s.UniqueID(), s.Cache()),
+		ttl:   ttl,
+		id:    id,
+		load:  load,
+		allow: allow,
+		audit: audit,
+	}
+}
+
+func (i *Impersonate[T]) Middleware(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+	if method == http.MethodOptions {
+		return i.auth.Middleware(next, method, path, router)
+	}
+
+	return i.auth.Middleware(func(ctx *web.Context) web.Responser {
+		actor, found := i.auth.GetInfo(ctx)
+		if !found {
+			return next(ctx)
+		}
+
+		var targetID string
+		switch err := i.cache.Get(i.id(actor), &targetID); {
+		case errors.Is(err, cache.ErrCacheMiss()):
+			return next(ctx)
+		case err != nil:
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		target, err := i.load(targetID)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		if !i.allow(actor, target) { // 权限已被撤销，结束模拟状态。
+			if err := i.cache.Delete(i.id(actor)); err != nil {
+				return ctx.Error(err, web.ProblemInternalServerError)
+			}
+			return next(ctx)
+		}
+
+		i.slot.Set(ctx, target)
+		i.audit(ctx, actor, target)
+		return next(ctx)
+	}, method, path, router)
+}
+
+// Logout 退出登录
+//
+// 同时也会结束模拟状态。
+func (i *Impersonate[T]) Logout(ctx *web.Context) error {
+	return errors.Join(i.Stop(ctx), i.auth.Logout(ctx))
+}
+
+// GetInfo 获取当前的用户
+//
+// 如果处于模拟状态，返回的是被模拟的用户，否则与 [Impersonate.Actor] 相同。
+func (i *Impersonate[T]) GetInfo(ctx *web.Context) (T, bool) {
+	if v, found := i.slot.Get(ctx); found {
+		return v, true
+	}
+	return i.auth.GetInfo(ctx)
+}
+
+// Actor 返回真实的操作者
+func (i *Impersonate[T]) Actor(ctx *web.Context) (T, bool) { return i.auth.GetInfo(ctx) }
+
+// IsImpersonated 当前请求是否处于模拟状态
+func (i *Impersonate[T]) IsImpersonated(ctx *web.Context) bool {
+	_, found := i.slot.Get(ctx)
+	return found
+}
+
+// Start 开始以 targetID 指定的用户身份进行访问
+//
+// 从下一次请求开始生效，直到调用 [Impersonate.Stop] 或是超时。
+func (i *Impersonate[T]) Start(ctx *web.Context, targetID string) error {
+	actor, found := i.Actor(ctx)
+	if !found {
+		return ErrNotLogin()
+	}
+
+	target, err := i.load(targetID)
+	if err != nil {
+		return err
+	}
+
+	if i.id(actor) == targetID || !i.allow(actor, target) {
+		return ErrNotAllowed()
+	}
+
+	return i.cache.Set(i.id(actor), targetID, i.ttl)
+}
+
+// Stop 结束模拟状态
+func (i *Impersonate[T]) Stop(ctx *web.Context) error {
+	actor, found := i.Actor(ctx)
+	if !found {
+		return nil
+	}
+
+	i.slot.Delete(ctx)
+	return i.cache.Delete(i.id(actor))
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package impersonate
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/auth"
+	"github.com/issue9/webuse/v7/middlewares/auth/basic"
+)
+
+var _ auth.Auth[string] = &Impersonate[string]{}
+
+var users = map[string]string{
+	"admin": "admin",
+	"u1":    "u1",
+	"u2":    "u2",
+}
+
+func load(id string) (string, error) {
+	if u, found := users[id]; found {
+		return u, nil
+	}
+	return "", web.NewLocaleError("not found")
+}
+
+func TestImpersonate(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	b := basic.New(s, func(username, _ []byte) (string, bool) {
+		_, found := users[string(username)]
+		return string(username), found
+	}, "example.com", false)
+
+	audits := 0
+	allowed := true
+	imp := New(s, b, time.Minute,
+		func(u string) string { return u },
+		load,
+		func(actor, _ string) bool { return actor == "admin" && allowed },
+		func(ctx *web.Context, actor, target string) { audits++ },
+	)
+	a.NotNil(imp)
+
+	r := s.Routers().New("def", nil)
+	r.Use(imp)
+	r.Get("/info", func(ctx *web.Context) web.Responser {
+		u, found := imp.GetInfo(ctx)
+		a.True(found)
+		actor, found := imp.Actor(ctx)
+		a.True(found)
+
+		ctx.Header().Set("x-user", u)
+		ctx.Header().Set("x-actor", actor)
+		if imp.IsImpersonated(ctx) {
+			ctx.Header().Set("x-impersonated", "true")
+		}
+		return web.OK(nil)
+	})
+	r.Post("/impersonate/{id}", func(ctx *web.Context) web.Responser {
+		id, resp := ctx.PathString("id", web.ProblemBadRequest)
+		if resp != nil {
+			return resp
+		}
+		if err := imp.Start(ctx, id); err != nil {
+			return ctx.Error(err, web.ProblemForbidden)
+		}
+		return web.NoContent()
+	})
+	r.Delete("/impersonate", func(ctx *web.Context) web.Responser {
+		if err := imp.Stop(ctx); err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		return web.NoContent()
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	admin := auth.BasicToken("YWRtaW46cGFzcw==") // admin:pass
+	u1 := auth.BasicToken("dTE6cGFzcw==")        // u1:pass
+
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "admin").
+		Header("x-actor", "admin").
+		Header("x-impersonated", "")
+
+	// u1 没有权限
+	servertest.Post(a, "http://localhost:8080/impersonate/u2", nil).
+		Header(header.Authorization, u1).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 不存在的用户
+	servertest.Post(a, "http://localhost:8080/impersonate/not-exists", nil).
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Post(a, "http://localhost:8080/impersonate/u1", nil).
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "u1").
+		Header("x-actor", "admin").
+		Header("x-impersonated", "true")
+	a.Equal(audits, 1)
+
+	// 不影响 u1 自身的访问
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, u1).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "u1").
+		Header("x-actor", "u1").
+		Header("x-impersonated", "")
+	a.Equal(audits, 1)
+
+	servertest.Delete(a, "http://localhost:8080/impersonate").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "admin").
+		Header("x-actor", "admin").
+		Header("x-impersonated", "")
+	a.Equal(audits, 2) // DELETE 请求时依然处于模拟状态
+
+	// 撤销权限之后，模拟状态即结束。
+	servertest.Post(a, "http://localhost:8080/impersonate/u1", nil).
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusNoContent)
+	allowed = false
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "admin").
+		Header("x-impersonated", "")
+	allowed = true
+	servertest.Get(a, "http://localhost:8080/info").
+		Header(header.Authorization, admin).
+		Do(nil).
+		Status(http.StatusOK).
+		Header("x-user", "admin").
+		Header("x-impersonated", "")
+	a.Equal(audits, 2)
+
+	a.PanicString(func() {
+		New(s, b, time.Minute, func(u string) string { return u }, load, nil, nil)
+	}, "参数 allow 不能为空")
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/issue9/mux/v9/header"
 	"github.com/issue9/web"
 
-	"github.com/issue9/webuse/v7/internal/mauth"
 	"github.com/issue9/webuse/v7/middlewares/auth"
 )
 
@@ -28,6 +27,7 @@ type (
 		keyFunc       jwt.Keyfunc
 		claimsBuilder BuildClaimsFunc[T]
 		keys          []*key
+		slot          *auth.Slot[T]
 	}
 
 	BuildClaimsFunc[T Claims] func() T
@@ -42,6 +42,7 @@ func NewVerifier[T Claims](b Blocker[T], f BuildClaimsFunc[T]) *Verifier[T] {
 		blocker:       b,
 		claimsBuilder: f,
 		keys:          make([]*key, 0, 10),
+		slot:          auth.NewSlot[T]("jwt"),
 	}
 
 	j.keyFunc = func(t *jwt.Token) (any, error) {
@@ -113,7 +114,7 @@ func (j *Verifier[T]) Middleware(next web.HandlerFunc, method, _, _ string) web.
 			}
 		}
 
-		mauth.Set(ctx, claims)
+		j.slot.Set(ctx, claims)
 		return next(ctx)
 	}
 }
@@ -127,7 +128,7 @@ func (j *Verifier[T]) parseClaims(ctx *web.Context, token string) (T, web.Respon
 	return t.Claims.(T), nil
 }
 
-func (j *Verifier[T]) GetInfo(ctx *web.Context) (claims T, found bool) { return mauth.Get[T](ctx) }
+func (j *Verifier[T]) GetInfo(ctx *web.Context) (claims T, found bool) { return j.slot.Get(ctx) }
 
 func (j *Verifier[T]) addKey(id string, sign SigningMethod, keyData any) {
 	if slices.IndexFunc(j.keys, func(e *key) bool { return e.id == id }) >= 0 {
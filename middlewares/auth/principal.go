@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"iter"
+	"slices"
+
+	"github.com/issue9/web"
+)
+
+type contextType int
+
+const principalsKey contextType = 1
+
+// Slot 验证数据在 [web.Context] 中的存储位置
+//
+// 每个验证方式都应该拥有自己的 Slot，这样在多个验证中间件同时作用于一个请求时，
+// 各自的数据不会相互覆盖。
+type Slot[T any] struct {
+	name string
+}
+
+// NewSlot 声明 [Slot] 对象
+//
+// name 为该存储位置的名称，一般为验证方式的名称，比如 jwt、session 等，
+// 仅作为 [Principals] 等函数的返回值，不要求唯一。
+//
+// NOTE: 同一个 Slot 对象在同一个请求中只保存一个值，不同的 Slot 对象即使 name 相同也互不影响。
+func NewSlot[T any](name string) *Slot[T] { return &Slot[T]{name: name} }
+
+// Name 名称
+func (s *Slot[T]) Name() string { return s.name }
+
+// Set 将 v 写入 ctx
+//
+// 同时会将 v 作为当前请求的主体，之后调用 [Principal] 将返回此值，
+// 直到有其它 [Slot] 调用 Set 方法。
+func (s *Slot[T]) Set(ctx *web.Context, v T) {
+	ctx.SetVar(s, v)
+
+	ps := getPrincipals(ctx)
+	if index := slices.Index(ps, namedSlot(s)); index >= 0 {
+		ps = slices.Delete(ps, index, index+1)
+	}
+	ctx.SetVar(principalsKey, append(ps, s))
+}
+
+// Get 获取由 [Slot.Set] 写入的值
+func (s *Slot[T]) Get(ctx *web.Context) (val T, found bool) {
+	if v, found := ctx.GetVar(s); found {
+		return v.(T), true
+	}
+	return val, false
+}
+
+// Delete 删除由 [Slot.Set] 写入的值
+func (s *Slot[T]) Delete(ctx *web.Context) {
+	ctx.DelVar(s)
+
+	ps := getPrincipals(ctx)
+	if index := slices.Index(ps, namedSlot(s)); index >= 0 {
+		ctx.SetVar(principalsKey, slices.Delete(ps, index, index+1))
+	}
+}
+
+// 所有 Slot 实现的接口，方便 [Principals] 等函数获取数据。
+type namedSlot interface {
+	Name() string
+}
+
+func getPrincipals(ctx *web.Context) []namedSlot {
+	if v, found := ctx.GetVar(principalsKey); found {
+		return v.([]namedSlot)
+	}
+	return nil
+}
+
+// Principal 当前请求的主体
+//
+// 即最后一次调用 [Slot.Set] 写入的值，name 为对应 [Slot] 的名称。
+func Principal(ctx *web.Context) (name string, v any, found bool) {
+	ps := getPrincipals(ctx)
+	if len(ps) == 0 {
+		return "", nil, false
+	}
+
+	s := ps[len(ps)-1]
+	v, found = ctx.GetVar(s)
+	return s.Name(), v, found
+}
+
+// PrincipalAs 从后往前查找第一个类型为 T 的主体
+func PrincipalAs[T any](ctx *web.Context) (val T, found bool) {
+	ps := getPrincipals(ctx)
+	for _, s := range slices.Backward(ps) {
+		if v, ok := ctx.GetVar(s); ok {
+			if vv, ok := v.(T); ok {
+				return vv, true
+			}
+		}
+	}
+	return val, false
+}
+
+// Principals 按写入的顺序返回当前请求的所有主体
+//
+// 键名为 [Slot] 的名称，键值为对应的值。
+func Principals(ctx *web.Context) iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, s := range getPrincipals(ctx) {
+			if v, found := ctx.GetVar(s); found {
+				if !yield(s.Name(), v) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/types"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+func TestSlot(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	ctx := s.NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", nil), types.NewContext())
+
+	name, v, found := Principal(ctx)
+	a.False(found).Empty(name).Nil(v)
+
+	session := NewSlot[string]("session")
+	jwt := NewSlot[int]("jwt")
+	a.Equal(session.Name(), "session")
+
+	val, found := session.Get(ctx)
+	a.False(found).Empty(val)
+
+	session.Set(ctx, "u1")
+	jwt.Set(ctx, 5)
+
+	// 互不影响
+	val, found = session.Get(ctx)
+	a.True(found).Equal(val, "u1")
+	id, found := jwt.Get(ctx)
+	a.True(found).Equal(id, 5)
+
+	name, v, found = Principal(ctx)
+	a.True(found).Equal(name, "jwt").Equal(v, 5)
+
+	str, found := PrincipalAs[string](ctx)
+	a.True(found).Equal(str, "u1")
+
+	// 同名的 Slot 也互不影响
+	session2 := NewSlot[string]("session")
+	session2.Set(ctx, "u2")
+	val, found = session.Get(ctx)
+	a.True(found).Equal(val, "u1")
+	str, found = PrincipalAs[string](ctx)
+	a.True(found).Equal(str, "u2")
+
+	// 重新写入，会成为最新的主体
+	session.Set(ctx, "u3")
+	name, v, found = Principal(ctx)
+	a.True(found).Equal(name, "session").Equal(v, "u3")
+
+	names := make([]string, 0, 3)
+	for name := range Principals(ctx) {
+		names = append(names, name)
+	}
+	a.Equal(names, []string{"jwt", "session", "session"})
+
+	session.Delete(ctx)
+	_, found = session.Get(ctx)
+	a.False(found)
+	name, v, found = Principal(ctx)
+	a.True(found).Equal(name, "session").Equal(v, "u2")
+
+	session2.Delete(ctx)
+	jwt.Delete(ctx)
+	_, _, found = Principal(ctx)
+	a.False(found)
+	_, found = PrincipalAs[int](ctx)
+	a.False(found)
+}
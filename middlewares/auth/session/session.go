@@ -16,7 +16,7 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/openapi"
 
-	"github.com/issue9/webuse/v7/internal/mauth"
+	"github.com/issue9/webuse/v7/middlewares/auth"
 )
 
 var errSessionIDNotExists = web.NewLocaleError("session id not exists in context")
@@ -29,6 +29,7 @@ type contextType int
 type Session[T any] struct {
 	rands *rands.Rands[byte]
 	store Store[T]
+	slot  *auth.Slot[T]
 
 	// cookie 的相关设置
 	lifetime           int
@@ -48,6 +49,7 @@ func New[T any](s web.Server, store Store[T], lifetime int, name, path, domain s
 	return &Session[T]{
 		rands: r,
 		store: store,
+		slot:  auth.NewSlot[T]("session"),
 
 		lifetime: lifetime,
 		name:     name,
@@ -103,7 +105,7 @@ func (s *Session[T]) Middleware(next web.HandlerFunc, _, _, _ string) web.Handle
 			}
 		}
 
-		mauth.Set(ctx, v)
+		s.slot.Set(ctx, v)
 
 		return next(ctx)
 	}
@@ -131,7 +133,7 @@ func (s *Session[T]) GetSessionID(ctx *web.Context) (string, error) {
 
 // Save 保存 val
 func (s *Session[T]) Save(ctx *web.Context, val T) error {
-	mauth.Set(ctx, val)
+	s.slot.Set(ctx, val)
 	id, err := s.GetSessionID(ctx)
 	if err != nil {
 		return err
@@ -139,7 +141,7 @@ func (s *Session[T]) Save(ctx *web.Context, val T) error {
 	return s.store.Set(id, val)
 }
 
-func (s *Session[T]) GetInfo(ctx *web.Context) (T, bool) { return mauth.Get[T](ctx) }
+func (s *Session[T]) GetInfo(ctx *web.Context) (T, bool) { return s.slot.Get(ctx) }
 
 func (s *Session[T]) SecurityScheme(id string, desc web.LocaleStringer) *openapi.SecurityScheme {
 	return SecurityScheme(s, id, desc)
@@ -15,7 +15,6 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/openapi"
 
-	"github.com/issue9/webuse/v7/internal/mauth"
 	"github.com/issue9/webuse/v7/middlewares/auth"
 )
 
@@ -31,6 +30,7 @@ type Response struct {
 
 type Temporary[T any] struct {
 	cache                 web.Cache
+	slot                  *auth.Slot[T]
 	ttl                   time.Duration
 	expire                int
 	once                  bool
@@ -49,6 +49,7 @@ type Temporary[T any] struct {
 func New[T any](s web.Server, ttl time.Duration, once bool, query string, unauthProblemID, invalidTokenProblemID string) *Temporary[T] {
 	return &Temporary[T]{
 		cache:                 web.NewCache(s.UniqueID(), s.Cache()),
+		slot:                  auth.NewSlot[T]("temporary"),
 		ttl:                   ttl,
 		expire:                int(ttl.Seconds()),
 		once:                  once,
@@ -98,7 +99,7 @@ func (t *Temporary[T]) Middleware(next web.HandlerFunc, method, _, _ string) web
 		case err != nil:
 			return ctx.Error(err, t.invalidTokenProblemID)
 		default:
-			mauth.Set(ctx, v)
+			t.slot.Set(ctx, v)
 			ctx.SetVar(tokenContext, token)
 
 			if t.once {
@@ -122,7 +123,7 @@ func (t *Temporary[T]) Logout(ctx *web.Context) error {
 // QueryName 查询参数的名称
 func (t *Temporary[T]) QueryName() string { return t.query }
 
-func (t *Temporary[T]) GetInfo(ctx *web.Context) (T, bool) { return mauth.Get[T](ctx) }
+func (t *Temporary[T]) GetInfo(ctx *web.Context) (T, bool) { return t.slot.Get(ctx) }
 
 func (t *Temporary[T]) SecurityScheme(id string, desc web.LocaleStringer) *openapi.SecurityScheme {
 	return SecurityScheme(id, desc, t.QueryName())
@@ -15,13 +15,15 @@ import (
 	"github.com/issue9/web"
 	"github.com/issue9/web/openapi"
 
-	"github.com/issue9/webuse/v7/internal/mauth"
 	"github.com/issue9/webuse/v7/middlewares/auth"
 )
 
 type tokenType int
 
-const tokenContext tokenType = 0
+const (
+	tokenContext  tokenType = 0
+	accessContext tokenType = 1 // 刷新令牌关联的访问令牌
+)
 
 // Token 传统的令牌管理
 //
@@ -35,6 +37,7 @@ type Token[T UserData] struct {
 	s     web.Server
 	rands *rands.Rands[byte]
 	store Store[T]
+	slot  *auth.Slot[T]
 	br    BuildResponseFunc
 
 	accessExp, refreshExp       time.Duration
@@ -68,6 +71,7 @@ func New[T UserData](
 		s:     s,
 		rands: r,
 		store: store,
+		slot:  auth.NewSlot[T]("token"),
 		br:    br,
 
 		accessExp:             accessExp,
@@ -95,8 +99,9 @@ func (t *Token[T]) Middleware(next web.HandlerFunc, method, _, _ string) web.Han
 		case !found:
 			return ctx.Problem(web.ProblemUnauthorized)
 		default:
-			mauth.Set(ctx, v)
+			t.slot.Set(ctx, v.UserData)
 			ctx.SetVar(tokenContext, token)
+			ctx.SetVar(accessContext, v.Access)
 
 			if v.Access != "" { // 刷新令牌
 				// 删除令牌出错，不妨碍其它功能的继承执行，所以只记录日志，不退出。
@@ -117,13 +122,7 @@ func (t *Token[T]) Logout(ctx *web.Context) error {
 	return nil
 }
 
-func (t *Token[T]) GetInfo(ctx *web.Context) (T, bool) {
-	if v, found := mauth.Get[Item[T]](ctx); found {
-		return v.UserData, true
-	}
-	var zero T
-	return zero, false
-}
+func (t *Token[T]) GetInfo(ctx *web.Context) (T, bool) { return t.slot.Get(ctx) }
 
 // New 根据给定的参数 v 创建新的令牌
 //
@@ -148,15 +147,15 @@ func (t *Token[T]) New(ctx *web.Context, v T, status int, headers ...string) web
 
 // Refresh 刷新令牌
 func (t *Token[T]) Refresh(ctx *web.Context, status int, headers ...string) web.Responser {
-	v, found := mauth.Get[Item[T]](ctx)
+	v, found := t.slot.Get(ctx)
 	if !found {
 		panic("通过了令牌验证但是在 Context 找不到相关信息")
 	}
 
-	if v.Access == "" { // 不是刷新令牌
+	if access, _ := ctx.GetVar(accessContext); access == "" { // 不是刷新令牌
 		return ctx.Problem(t.invalidTokenProblemID)
 	}
-	return t.New(ctx, v.UserData, status, headers...)
+	return t.New(ctx, v, status, headers...)
 }
 
 // Delete 根据指定的用户数据
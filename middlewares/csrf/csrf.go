@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package csrf 防止跨站请求伪造的中间件
+//
+// 对于非安全的请求方法，会依次进行以下检测：
+//   - 根据 Sec-Fetch-Site、Origin 和 Referer 报头判断请求是否来自其它站点；
+//   - 提交的令牌是否与 [Store] 中保存的令牌相同；
+//
+// 令牌的保存方式由 [Store] 决定，目前提供了以下几种：
+//   - [SessionStore] 保存在 session 中的同步令牌模式；
+//   - [CookieStore] 无状态的双重提交 cookie 模式；
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+)
+
+const (
+	DefaultHeader = header.XCsrfToken // 默认的令牌报头
+	DefaultField  = "csrf_token"      // 默认的令牌表单字段
+)
+
+const tokenContext contextType = 1
+
+type contextType int
+
+var encoding = base64.RawURLEncoding
+
+// CSRF 防止跨站请求伪造的中间件
+type CSRF struct {
+	store   Store
+	header  string
+	field   string
+	origins []string
+	exempt  []string
+}
+
+// New 声明 [CSRF] 中间件
+//
+// origins 为除当前源之外，允许发起请求的其它源，当前源需要协议和域名都相同，格式为 scheme://host[:port]，比如 https://example.com。
+//
+// 令牌优先从报头中获取，报头中不存在时，才会从表单中获取。从表单中获取时会解析请求的内容，
+// 之后的处理函数只能通过 [http.Request.PostForm] 获取表单数据。
+func New(store Store, origins ...string) *CSRF {
+	return &CSRF{
+		store:   store,
+		header:  DefaultHeader,
+		field:   DefaultField,
+		origins: origins,
+		exempt:  make([]string, 0, 10),
+	}
+}
+
+// Names 指定令牌的报头和表单字段的名称
+//
+// 默认值分别为 [DefaultHeader] 和 [DefaultField]。
+func (c *CSRF) Names(header, field string) *CSRF {
+	c.header = header
+	c.field = field
+	return c
+}
+
+func buildID(method, path, router string) string { return router + method + path }
+
+// Exempt 返回一个脱离当前检测的中间件
+func (c *CSRF) Exempt() web.Middleware {
+	return web.MiddlewareFunc(func(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+		c.exempt = append(c.exempt, buildID(method, path, router))
+		return next
+	})
+}
+
+func (c *CSRF) Middleware(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+	if slices.Contains(c.exempt, buildID(method, path, router)) {
+		return next
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		token, err := c.store.Get(ctx)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		if !isSafeMethod(ctx.Request().Method) {
+			if !c.sameOrigin(ctx.Request()) || !c.validToken(ctx.Request(), token) {
+				return ctx.Problem(web.ProblemForbidden)
+			}
+		}
+
+		if token == "" {
+			if token, err = c.store.New(ctx); err != nil {
+				return ctx.Error(err, web.ProblemInternalServerError)
+			}
+		}
+		ctx.SetVar(tokenContext, &tokenInfo{token: token, field: c.field})
+
+		return next(ctx)
+	}
+}
+
+type tokenInfo struct {
+	token, field string
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// 请求是否来自当前站点或是 origins 中的源
+func (c *CSRF) sameOrigin(r *http.Request) bool {
+	switch r.Header.Get(header.SecFetchite) {
+	case "same-origin", "none": // none 表示由用户直接发起，比如在地址栏输入。
+		return true
+	}
+
+	origin := r.Header.Get(header.Origin)
+	if origin == "" || origin == "null" {
+		if origin = r.Header.Get(header.Referer); origin == "" {
+			// 无法判断来源，比如非浏览器的客户端，仅由令牌判断。
+			return r.Header.Get(header.SecFetchite) == ""
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Scheme, scheme(r)) && u.Host == r.Host {
+		return true
+	}
+	o := u.Scheme + "://" + u.Host
+	return slices.ContainsFunc(c.origins, func(v string) bool { return strings.EqualFold(v, o) })
+}
+
+// 请求的协议
+//
+// 在 TLS 由代理终止时，由代理通过 X-Forwarded-Proto 报头指定原始的协议。
+// 浏览器无法在跨站的请求中伪造该报头。
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if p, _, _ := strings.Cut(r.Header.Get(header.XForwardedProto), ","); p != "" {
+		return strings.ToLower(strings.TrimSpace(p))
+	}
+	return "http"
+}
+
+func (c *CSRF) validToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	submitted := r.Header.Get(c.header)
+	if submitted == "" {
+		if t, _, _ := mime.ParseMediaType(r.Header.Get(header.ContentType)); t == header.FormData || t == header.MultipartFormData {
+			submitted = r.PostFormValue(c.field)
+		}
+	}
+	return subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) == 1
+}
+
+func newToken() string {
+	bs := make([]byte, 32)
+	rand.Read(bs)
+	return encoding.EncodeToString(bs)
+}
+
+// Token 获取当前请求的令牌
+//
+// 可用于在模板中输出令牌，或是由接口返回给前端。
+func Token(ctx *web.Context) string {
+	if v, found := ctx.GetVar(tokenContext); found {
+		return v.(*tokenInfo).token
+	}
+	return ""
+}
+
+// Field 获取包含令牌的隐藏表单字段
+//
+// 可直接在 HTML 模板的表单中输出。
+func Field(ctx *web.Context) template.HTML {
+	v, found := ctx.GetVar(tokenContext)
+	if !found {
+		return ""
+	}
+
+	info := v.(*tokenInfo)
+	return template.HTML(`<input type="hidden" name="` + html.EscapeString(info.field) + `" value="` + html.EscapeString(info.token) + `" />`)
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+var _ web.Middleware = &CSRF{}
+
+func TestCSRF_sameOrigin(t *testing.T) {
+	a := assert.New(t, false)
+	c := New(nil, "https://admin.example.com")
+
+	newRequest := func(h map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "https://example.com/", nil)
+		for k, v := range h {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+
+	a.True(c.sameOrigin(newRequest(nil))).
+		True(c.sameOrigin(newRequest(map[string]string{header.SecFetchite: "same-origin"}))).
+		True(c.sameOrigin(newRequest(map[string]string{header.SecFetchite: "none"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.SecFetchite: "cross-site"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.SecFetchite: "same-site"})))
+
+	a.True(c.sameOrigin(newRequest(map[string]string{header.Origin: "https://example.com"}))).
+		True(c.sameOrigin(newRequest(map[string]string{header.Origin: "https://admin.example.com"}))).
+		True(c.sameOrigin(newRequest(map[string]string{header.SecFetchite: "same-site", header.Origin: "https://ADMIN.example.com"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.Origin: "http://admin.example.com"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.Origin: "https://evil.com"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.Origin: "::"})))
+
+	a.True(c.sameOrigin(newRequest(map[string]string{header.Origin: "null", header.Referer: "https://example.com/form"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.Referer: "https://evil.com/form"})))
+
+	// 协议不同
+	a.False(c.sameOrigin(newRequest(map[string]string{header.Origin: "http://example.com"}))).
+		False(c.sameOrigin(newRequest(map[string]string{header.Referer: "http://example.com/form"})))
+
+	// 由代理终止 TLS
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/", nil)
+	r.Header.Set(header.Origin, "https://example.com")
+	a.False(c.sameOrigin(r))
+	r.Header.Set(header.XForwardedProto, "https")
+	a.True(c.sameOrigin(r))
+	r.Header.Set(header.Origin, "http://example.com")
+	a.False(c.sameOrigin(r))
+}
+
+func TestCSRF_validToken(t *testing.T) {
+	a := assert.New(t, false)
+	c := New(nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	a.False(c.validToken(r, "")).
+		False(c.validToken(r, "token"))
+
+	r.Header.Set(DefaultHeader, "token")
+	a.True(c.validToken(r, "token")).
+		False(c.validToken(r, "token2"))
+
+	form := url.Values{DefaultField: {"token"}}
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set(header.ContentType, header.FormData+"; charset=utf-8")
+	a.True(c.validToken(r, "token"))
+
+	// 非表单的内容不会被解析
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set(header.ContentType, header.JSON)
+	a.False(c.validToken(r, "token"))
+
+	c.Names("X-Token", "token")
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("X-Token", "token")
+	a.True(c.validToken(r, "token"))
+}
+
+func TestCSRF_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	c := New(CookieStore([]byte("0123456789abcdef"), func(ctx *web.Context) (string, error) {
+		return ctx.Request().Header.Get("X-Session"), nil
+	}, "csrf", "/", "", false))
+
+	r := s.Routers().New("def", nil)
+	r.Use(c)
+	r.Get("/form", func(ctx *web.Context) web.Responser {
+		return web.OK(string(Field(ctx)))
+	})
+	r.Post("/form", func(ctx *web.Context) web.Responser {
+		return web.OK(Token(ctx))
+	})
+	r.Post("/webhook", func(ctx *web.Context) web.Responser {
+		return web.OK(Token(ctx))
+	}, c.Exempt())
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	resp := servertest.Get(a, "http://localhost:8080/form").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.Contains(string(body), `name=\"csrf_token\"`)
+		}).
+		Resp()
+	cookies := resp.Cookies()
+	a.Length(cookies, 1).Equal(cookies[0].Name, "csrf").False(cookies[0].HttpOnly)
+	token := cookies[0].Value
+	cookie := "csrf=" + token
+
+	// 没有令牌
+	servertest.Post(a, "http://localhost:8080/form", nil).Header(header.Cookie, cookie).Do(nil).
+		Status(http.StatusForbidden)
+
+	// 令牌错误
+	servertest.Post(a, "http://localhost:8080/form", nil).Header(header.Cookie, cookie).
+		Header(DefaultHeader, token+"x").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 来自其它站点
+	servertest.Post(a, "http://localhost:8080/form", nil).Header(header.Cookie, cookie).
+		Header(DefaultHeader, token).
+		Header(header.Origin, "https://evil.com").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	servertest.Post(a, "http://localhost:8080/form", nil).Header(header.Cookie, cookie).
+		Header(DefaultHeader, token).
+		Header(header.Accept, header.JSON).
+		Header(header.SecFetchite, "same-origin").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"` + token + `"`)
+
+	// 其它会话
+	servertest.Post(a, "http://localhost:8080/form", nil).Header(header.Cookie, cookie).
+		Header(DefaultHeader, token).
+		Header(header.SecFetchite, "same-origin").
+		Header("X-Session", "s2").
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 没有 cookie，即使提交了令牌也无效。
+	servertest.Post(a, "http://localhost:8080/form", nil).
+		Header(DefaultHeader, token).
+		Do(nil).
+		Status(http.StatusForbidden)
+
+	// 被豁免的路由
+	servertest.Post(a, "http://localhost:8080/webhook", nil).
+		Header(header.Origin, "https://evil.com").
+		Do(nil).
+		Status(http.StatusOK)
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/auth/session"
+)
+
+// Store 令牌的存储接口
+type Store interface {
+	// Get 获取当前请求关联的令牌
+	//
+	// 不存在时返回空字符串。
+	Get(*web.Context) (string, error)
+
+	// New 生成新的令牌并与当前请求关联
+	New(*web.Context) (string, error)
+}
+
+type sessionStore[T any] struct {
+	s   *session.Session[T]
+	get func(T) string
+	set func(T, string) T
+}
+
+type cookieStore struct {
+	secret             []byte
+	sid                func(*web.Context) (string, error)
+	name, path, domain string
+	secure             bool
+}
+
+// SessionStore 将令牌保存在 session 中的同步令牌模式
+//
+// get 和 set 用于从 session 的数据中读写令牌。
+// s 作为中间件需要先于 [CSRF] 执行，比如 Router.Use(csrf, session)。
+func SessionStore[T any](s *session.Session[T], get func(T) string, set func(T, string) T) Store {
+	return &sessionStore[T]{s: s, get: get, set: set}
+}
+
+func (s *sessionStore[T]) Get(ctx *web.Context) (string, error) {
+	v, found := s.s.GetInfo(ctx)
+	if !found {
+		return "", session.ErrSessionIDNotExists()
+	}
+	return s.get(v), nil
+}
+
+func (s *sessionStore[T]) New(ctx *web.Context) (string, error) {
+	v, found := s.s.GetInfo(ctx)
+	if !found {
+		return "", session.ErrSessionIDNotExists()
+	}
+
+	token := newToken()
+	return token, s.s.Save(ctx, s.set(v, token))
+}
+
+// CookieStore 无状态的双重提交 cookie 模式
+//
+// 令牌保存在客户端的 cookie 中，提交时需要将 cookie 中的令牌同时放在报头或是表单中。
+// 令牌由 secret 签名，且与 sid 返回的会话标识绑定，无法被伪造，也无法用于其它会话。
+// sid 一般返回 session ID 或是登录用户的 ID，会话标识改变之后，之前的令牌将失效并重新生成。
+// 其它参数为 cookie 的相关设置，该 cookie 需要被前端的 JS 读取，所以不能是 HttpOnly。
+func CookieStore(secret []byte, sid func(*web.Context) (string, error), name, path, domain string, secure bool) Store {
+	if len(secret) < 16 {
+		panic("参数 secret 的长度不能小于 16")
+	}
+	if sid == nil {
+		panic("参数 sid 不能为空")
+	}
+
+	return &cookieStore{
+		secret: secret,
+		sid:    sid,
+		name:   name,
+		path:   path,
+		domain: domain,
+		secure: secure,
+	}
+}
+
+func (s *cookieStore) sign(sid, data string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(sid))
+	h.Write([]byte{0})
+	h.Write([]byte(data))
+	return encoding.EncodeToString(h.Sum(nil))
+}
+
+func (s *cookieStore) Get(ctx *web.Context) (string, error) {
+	c, err := ctx.Request().Cookie(s.name)
+	if errors.Is(err, http.ErrNoCookie) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	sid, err := s.sid(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	data, sign, found := strings.Cut(c.Value, ".")
+	if !found || !hmac.Equal([]byte(sign), []byte(s.sign(sid, data))) { // 签名错误，视为不存在。
+		return "", nil
+	}
+	return c.Value, nil
+}
+
+func (s *cookieStore) New(ctx *web.Context) (string, error) {
+	sid, err := s.sid(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := newToken()
+	token += "." + s.sign(sid, token)
+
+	ctx.SetCookies(&http.Cookie{
+		Name:     s.name,
+		Value:    token,
+		Path:     s.path,
+		Domain:   s.domain,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package csrf
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+	"github.com/issue9/webuse/v7/middlewares/auth/session"
+)
+
+var (
+	_ Store = &sessionStore[int]{}
+	_ Store = &cookieStore{}
+)
+
+func TestCookieStore(t *testing.T) {
+	a := assert.New(t, false)
+	sid := func(*web.Context) (string, error) { return "sid", nil }
+
+	a.PanicString(func() {
+		CookieStore([]byte("123"), sid, "csrf", "/", "", false)
+	}, "参数 secret 的长度不能小于 16")
+
+	a.PanicString(func() {
+		CookieStore([]byte("0123456789abcdef"), nil, "csrf", "/", "", false)
+	}, "参数 sid 不能为空")
+
+	s1 := CookieStore([]byte("0123456789abcdef"), sid, "csrf", "/", "", false).(*cookieStore)
+	s2 := CookieStore([]byte("0123456789ABCDEF"), sid, "csrf", "/", "", false).(*cookieStore)
+	token := newToken()
+	a.NotEqual(s1.sign("sid", token), s2.sign("sid", token)).
+		NotEqual(s1.sign("sid", token), s1.sign("sid2", token))
+}
+
+type data struct {
+	Token string
+}
+
+func TestSessionStore(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+
+	sess := session.New(s, session.NewCacheStore[*data](s.Cache(), time.Minute), 60, "session_id", "/", "localhost", false, true)
+	c := New(SessionStore(sess, func(d *data) string { return d.Token }, func(d *data, token string) *data {
+		d.Token = token
+		return d
+	}))
+
+	r := s.Routers().New("def", nil)
+	r.Use(c, sess) // sess 需要先于 c 执行
+	r.Get("/token", func(ctx *web.Context) web.Responser {
+		return web.OK(Token(ctx))
+	})
+	r.Post("/token", func(ctx *web.Context) web.Responser {
+		return web.OK(Token(ctx))
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	var token string
+	resp := servertest.Get(a, "http://localhost:8080/token").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			token = string(body[1 : len(body)-1])
+		}).
+		Resp()
+	a.NotEmpty(token)
+	cookies := resp.Cookies()
+	a.Length(cookies, 1)
+	cookie := cookies[0].Name + "=" + cookies[0].Value
+
+	// 同一 session 的令牌不变
+	servertest.Get(a, "http://localhost:8080/token").Header(header.Accept, header.JSON).
+		Header(header.Cookie, cookie).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"` + token + `"`)
+
+	servertest.Post(a, "http://localhost:8080/token", nil).Header(header.Accept, header.JSON).
+		Header(header.Cookie, cookie).
+		Header(DefaultHeader, token).
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"` + token + `"`)
+
+	// 其它 session
+	servertest.Post(a, "http://localhost:8080/token", nil).
+		Header(DefaultHeader, token).
+		Do(nil).
+		Status(http.StatusForbidden)
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+)
+
+var unsafeMethods = []string{http.MethodPost, http.MethodPatch}
+
+// BufferBody 缓存请求的内容以便计算其指纹
+//
+// 中间件执行时请求的内容已经被 [web.Context] 引用，若由中间件读取，之后的处理函数将无法再次读取。
+// 所以需要在 [http.Server] 的层面提前缓存请求的内容，比如：
+//
+//	hs := &http.Server{Addr: ":8080"}
+//	s, err := server.NewHTTP("app", "1.0.0", &server.Options{HTTPServer: hs})
+//	hs.Handler = idempotency.BufferBody(hs.Handler, 1<<20)
+//
+// 只有带 [Header] 报头的 POST 和 PATCH 请求才会被缓存，
+// 缓存之后可通过 [http.Request.GetBody] 重复读取内容。
+// max 为允许的最大内容长度，超过此值将直接返回 413。
+//
+// [web.Context]: https://pkg.go.dev/github.com/issue9/web#Context
+func BufferBody(h http.Handler, max int64) http.Handler {
+	if max <= 0 {
+		panic("参数 max 必须大于 0")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.GetBody != nil || r.Header.Get(Header) == "" || !slices.Contains(unsafeMethods, r.Method) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, max))
+		if err != nil {
+			if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		h.ServeHTTP(w, r)
+	})
+}
+
+// 计算请求的指纹
+func fingerprint(r *http.Request) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n"))
+
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+
+		if _, err = io.Copy(h, body); err != nil {
+			return "", err
+		}
+	} else if r.ContentLength != 0 {
+		return "", errBodyNotBuffered
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package idempotency
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestBufferBody(t *testing.T) {
+	a := assert.New(t, false)
+
+	a.PanicString(func() { BufferBody(http.NotFoundHandler(), 0) }, "参数 max 必须大于 0")
+
+	var getBody bool
+	h := BufferBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		getBody = r.GetBody != nil
+		data, err := io.ReadAll(r.Body)
+		a.NotError(err)
+		w.Write(data)
+	}), 10)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("123"))
+	r.Header.Set(Header, "k1")
+	h.ServeHTTP(w, r)
+	a.True(getBody).Equal(w.Body.String(), "123")
+
+	// 没有 Idempotency-Key
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("123"))
+	h.ServeHTTP(w, r)
+	a.False(getBody).Equal(w.Body.String(), "123")
+
+	// 幂等的请求方法
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString("123"))
+	r.Header.Set(Header, "k1")
+	h.ServeHTTP(w, r)
+	a.False(getBody).Equal(w.Body.String(), "123")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("12345678901"))
+	r.Header.Set(Header, "k1")
+	h.ServeHTTP(w, r)
+	a.Equal(w.Code, http.StatusRequestEntityTooLarge)
+}
+
+func TestFingerprint(t *testing.T) {
+	a := assert.New(t, false)
+
+	newRequest := func(method, path, body string) *http.Request {
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewBufferString(body)), nil }
+		return r
+	}
+
+	fp1, err := fingerprint(newRequest(http.MethodPost, "/p", "123"))
+	a.NotError(err).NotEmpty(fp1)
+
+	fp2, err := fingerprint(newRequest(http.MethodPost, "/p", "123"))
+	a.NotError(err).Equal(fp1, fp2)
+
+	fp2, err = fingerprint(newRequest(http.MethodPost, "/p", "1234"))
+	a.NotError(err).NotEqual(fp1, fp2)
+
+	fp2, err = fingerprint(newRequest(http.MethodPatch, "/p", "123"))
+	a.NotError(err).NotEqual(fp1, fp2)
+
+	fp2, err = fingerprint(newRequest(http.MethodPost, "/p?id=1", "123"))
+	a.NotError(err).NotEqual(fp1, fp2)
+
+	// 未缓存的内容
+	fp2, err = fingerprint(httptest.NewRequest(http.MethodPost, "/p", bytes.NewBufferString("123")))
+	a.Equal(err, errBodyNotBuffered).Empty(fp2)
+
+	fp2, err = fingerprint(httptest.NewRequest(http.MethodPost, "/p", nil))
+	a.NotError(err).NotEmpty(fp2)
+}
@@ -0,0 +1,315 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package idempotency 实现 Idempotency-Key 报头的中间件
+//
+// 对于带 [Header] 报头的 POST 和 PATCH 请求：
+//   - 首次请求正常执行，其状态码、报头和内容会以 [Header] 的值作为键名保存在缓存中，
+//     报头不包括 Set-Cookie、逐跳报头以及限流等与每次请求相关的报头；
+//   - 同一键名的请求正在执行时，再次请求将返回 409；
+//   - 之后的请求直接返回缓存的内容，并带上 [ReplayedHeader] 报头；
+//   - 如果同一键名的请求内容不同，则返回 422；
+//
+// 请求内容的指纹需要 [BufferBody] 的配合才能计算。
+// 默认所有用户共享同一键名空间，一般需要通过 [Idempotency.Scope] 按用户区分。
+//
+// 规范：https://datatracker.ietf.org/doc/draft-ietf-httpapi-idempotency-key-header/
+package idempotency
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/issue9/cache"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+
+	"github.com/issue9/webuse/v7/middlewares/acl"
+)
+
+const (
+	Header         = "Idempotency-Key"     // 请求中的幂等键名报头
+	ReplayedHeader = "Idempotent-Replayed" // 表示当前内容为缓存的输出
+)
+
+const maxKeyLength = 255
+
+var (
+	errBodyNotBuffered = web.NewLocaleError("the request body is not buffered")
+	errKeyTooLong      = web.NewLocaleError("the length of %s must not exceed %d", Header, maxKeyLength)
+	errMissingKey      = web.NewLocaleError("missing %s header", Header)
+)
+
+// Idempotency 实现 Idempotency-Key 的中间件
+type Idempotency struct {
+	c        web.Cache
+	ttl      time.Duration
+	timeout  time.Duration
+	required bool
+	gen      acl.GenFunc
+}
+
+// 缓存的输出内容
+type response struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+// New 声明 [Idempotency] 中间件
+//
+// c 为保存输出内容的缓存，可以通过 [cache.Prefix] 指定前缀；
+// ttl 为输出内容的缓存时间；
+// timeout 为请求的最长执行时间，在此时间内同一键名的请求会被拒绝，超过此时间则视为请求已经终止；
+func New(c web.Cache, ttl, timeout time.Duration) *Idempotency {
+	if ttl <= 0 {
+		panic("参数 ttl 必须大于 0")
+	}
+	if timeout <= 0 {
+		panic("参数 timeout 必须大于 0")
+	}
+
+	return &Idempotency{
+		c:       c,
+		ttl:     ttl,
+		timeout: timeout,
+	}
+}
+
+// Required 未指定 [Header] 报头的请求将返回 400
+//
+// 默认情况下，未指定 [Header] 报头的请求会被正常执行。
+func (i *Idempotency) Required() *Idempotency {
+	i.required = true
+	return i
+}
+
+// Scope 按用户区分键名
+//
+// gen 的返回值将作为键名的前缀，一般为当前登录用户的 ID。
+// 默认情况下，所有用户共享同一键名空间，任何人只要知道了键名，就可以获取他人请求的输出内容，
+// 所以除非键名本身不可预测且只在同一用户内使用，否则都应该指定 gen。
+func (i *Idempotency) Scope(gen acl.GenFunc) *Idempotency {
+	i.gen = gen
+	return i
+}
+
+func (i *Idempotency) Middleware(next web.HandlerFunc, method, _, _ string) web.HandlerFunc {
+	if !slices.Contains(unsafeMethods, method) {
+		return next
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		key := ctx.Request().Header.Get(Header)
+		switch {
+		case key == "" && i.required:
+			return ctx.Error(errMissingKey, web.ProblemBadRequest)
+		case key == "":
+			return next(ctx)
+		case len(key) > maxKeyLength:
+			return ctx.Error(errKeyTooLong, web.ProblemBadRequest)
+		}
+
+		if i.gen != nil {
+			prefix, err := i.gen(ctx)
+			if err != nil {
+				return ctx.Error(err, web.ProblemInternalServerError)
+			}
+			key = prefix + "_" + key
+		}
+
+		fp, err := fingerprint(ctx.Request())
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+
+		if resp := i.replay(ctx, key, fp); resp != nil {
+			return resp
+		}
+
+		unlock, err := i.lock(key)
+		if err != nil {
+			return ctx.Error(err, web.ProblemInternalServerError)
+		}
+		if unlock == nil {
+			return ctx.Problem(web.ProblemConflict)
+		}
+		defer unlock()
+
+		// 获得锁之前，其它请求可能已经完成。
+		if resp := i.replay(ctx, key, fp); resp != nil {
+			return resp
+		}
+
+		raw(ctx)
+		var rec *recorder
+		ctx.Wrap(func(w http.ResponseWriter) http.ResponseWriter {
+			rec = &recorder{ResponseWriter: w}
+			return rec
+		})
+
+		if resp := next(ctx); resp != nil {
+			resp.Apply(ctx)
+		}
+
+		if rec.status > 0 && rec.status < http.StatusInternalServerError { // 服务端的错误允许客户端重试
+			r := &response{
+				Fingerprint: fp,
+				Status:      rec.status,
+				Header:      storedHeader(ctx.Header()),
+				Body:        rec.body,
+			}
+			if err := i.c.Set(key, r, i.ttl); err != nil {
+				ctx.Logs().ERROR().Error(err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// 如果存在 key 的缓存，则返回对应的输出。
+func (i *Idempotency) replay(ctx *web.Context, key, fp string) web.Responser {
+	r := &response{}
+	switch err := i.c.Get(key, r); {
+	case errors.Is(err, cache.ErrCacheMiss()):
+		return nil
+	case err != nil:
+		return ctx.Error(err, web.ProblemInternalServerError)
+	case r.Fingerprint != fp:
+		return ctx.Problem(web.ProblemUnprocessableEntity)
+	}
+
+	return web.ResponserFunc(func(ctx *web.Context) {
+		raw(ctx)
+
+		h := ctx.Header()
+		for k, v := range r.Header {
+			h[k] = v
+		}
+		h.Set(ReplayedHeader, "true")
+
+		ctx.WriteHeader(r.Status)
+		if _, err := ctx.Write(r.Body); err != nil {
+			ctx.Logs().ERROR().Error(err)
+		}
+	})
+}
+
+// 不需要保存的报头
+//
+// 包括逐跳报头以及与当前请求或用户相关的报头，这些报头可能由外层的中间件设置，
+// 重放时应该由当时的请求重新生成，而不是输出首次请求的值。
+var skippedHeaders = []string{
+	header.Connection,
+	header.KeepAlive,
+	header.ProxyAuthenticate,
+	header.ProxyConnection,
+	header.TE,
+	header.Trailer,
+	header.TransferEncoding,
+	header.Upgrade,
+
+	header.ContentLength,
+	header.ContentEncoding,
+	header.Date,
+	header.SetCookie,
+	header.XRequestID,
+	header.ContentSecurityPolicy,
+	header.ContentSecurityPolicyReportOnly,
+	header.RetryAfter,
+	header.XRateLimitLimit,
+	header.XRateLimitRemaining,
+	header.XRateLimitReset,
+	"RateLimit",
+	"RateLimit-Policy",
+}
+
+func storedHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range skippedHeaders {
+		h.Del(k)
+	}
+	return h
+}
+
+// 缓存的内容需要原样输出，所以不能压缩或是转换字符集。
+func raw(ctx *web.Context) {
+	if ctx.Encoding() != "" {
+		ctx.SetEncoding("")
+		ctx.Header().Del(header.ContentEncoding)
+	}
+	ctx.SetCharset("utf-8")
+}
+
+// 锁定 key，如果已经被锁定，返回 nil, nil。
+//
+// 获得锁之后会保存一个唯一的令牌，释放时只有令牌未变且未超过 timeout 才会删除锁，
+// 防止执行时间超过 timeout 的请求删除了之后其它请求获得的锁。
+func (i *Idempotency) lock(key string) (unlock func(), err error) {
+	key += "_lock"
+	owner := key + "_owner"
+
+	start := time.Now()
+	_, setter, _, err := i.c.Counter(key, i.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := setter(1)
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss()): // 锁恰好被释放
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case n != 1:
+		return nil, nil
+	}
+
+	token := rand.Text()
+	if err := i.c.Set(owner, token, i.timeout); err != nil {
+		_ = i.c.Delete(key)
+		return nil, err
+	}
+
+	return func() {
+		if time.Since(start) >= i.timeout { // 锁已经过期，可能已经被其它请求获得。
+			return
+		}
+
+		var v string
+		if err := i.c.Get(owner, &v); err != nil || v != token {
+			return
+		}
+		// 先删除令牌，此时锁依然存在，其它请求无法在两者之间获得锁并写入新的令牌。
+		// 即使出错，也会在 timeout 之后自动释放。
+		_ = i.c.Delete(owner)
+		_ = i.c.Delete(key)
+	}, nil
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body = append(r.body, b...)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package idempotency
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/logs/v7"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+	"golang.org/x/text/language"
+)
+
+var _ web.Middleware = &Idempotency{}
+
+func newServer(a *assert.Assertion) web.Server {
+	hs := &http.Server{Addr: ":8080"}
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		Language:   language.SimplifiedChinese,
+		HTTPServer: hs,
+		Codec:      web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, json.ProblemMimetype, true, true),
+		Logs:       logs.New(logs.NewTermHandler(os.Stderr, nil)),
+	})
+	a.NotError(err).NotNil(s)
+	hs.Handler = BufferBody(hs.Handler, 1024)
+
+	return s
+}
+
+func TestNew(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+
+	a.PanicString(func() { New(s.Cache(), 0, time.Second) }, "参数 ttl 必须大于 0")
+	a.PanicString(func() { New(s.Cache(), time.Second, 0) }, "参数 timeout 必须大于 0")
+}
+
+func TestIdempotency_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+	i := New(s.Cache(), time.Minute, time.Second)
+
+	var count atomic.Int64
+	release := make(chan struct{})
+	r := s.Routers().New("def", nil)
+	r.Use(i)
+	r.Post("/payments", func(ctx *web.Context) web.Responser {
+		v := map[string]int{}
+		if resp := ctx.Read(true, &v, web.ProblemUnprocessableEntity); resp != nil {
+			return resp
+		}
+		count.Add(1)
+		ctx.Header().Set("X-Count", "1")
+		ctx.Header().Set(header.SetCookie, "sid=1")
+		return web.Created(v, "/payments/1")
+	})
+	r.Post("/slow", func(ctx *web.Context) web.Responser {
+		<-release
+		return web.NoContent()
+	})
+	r.Put("/payments", func(ctx *web.Context) web.Responser {
+		count.Add(1)
+		return web.NoContent()
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	const url = "http://localhost:8080/payments"
+
+	servertest.Post(a, url, []byte(`{"amount":5}`)).Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Header(Header, "k1").
+		Do(nil).
+		Status(http.StatusCreated).
+		Header("X-Count", "1").
+		StringBody(`{"amount":5}`)
+	a.Equal(count.Load(), 1)
+
+	// 重放
+	resp := servertest.Post(a, url, []byte(`{"amount":5}`)).Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Header(header.AcceptEncoding, "gzip").
+		Header(Header, "k1").
+		Do(nil).
+		Status(http.StatusCreated).
+		Header(ReplayedHeader, "true").
+		Header(header.Location, "/payments/1").
+		Header("X-Count", "1").
+		Header(header.SetCookie, "").
+		StringBody(`{"amount":5}`).
+		Resp()
+	a.Equal(count.Load(), 1).Empty(resp.Header.Get(header.ContentEncoding))
+
+	// 内容不同
+	servertest.Post(a, url, []byte(`{"amount":6}`)).Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Header(Header, "k1").
+		Do(nil).
+		Status(http.StatusUnprocessableEntity)
+	a.Equal(count.Load(), 1)
+
+	// 不同的键名
+	servertest.Post(a, url, []byte(`{"amount":5}`)).Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Header(Header, "k2").
+		Do(nil).
+		Status(http.StatusCreated)
+	a.Equal(count.Load(), 2)
+
+	// 没有键名
+	servertest.Post(a, url, []byte(`{"amount":5}`)).Header(header.ContentType, header.JSON).Header(header.Accept, header.JSON).
+		Do(nil).
+		Status(http.StatusCreated)
+	a.Equal(count.Load(), 3)
+
+	servertest.Post(a, url, nil).Header(Header, strings.Repeat("k", maxKeyLength+1)).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	// 超过 BufferBody 的限制
+	servertest.Post(a, url, []byte(strings.Repeat("1", 1025))).Header(header.ContentType, header.JSON).
+		Header(Header, "k3").
+		Do(nil).
+		Status(http.StatusRequestEntityTooLarge)
+
+	// 幂等的请求方法不受影响
+	servertest.NewRequest(a, http.MethodPut, url).Header(Header, "k1").Do(nil).Status(http.StatusNoContent)
+	servertest.NewRequest(a, http.MethodPut, url).Header(Header, "k1").Do(nil).Status(http.StatusNoContent)
+	a.Equal(count.Load(), 5)
+
+	// 并发的请求
+	done := make(chan struct{})
+	go func() {
+		servertest.Post(a, "http://localhost:8080/slow", nil).Header(Header, "slow").Do(nil).Status(http.StatusNoContent)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	servertest.Post(a, "http://localhost:8080/slow", nil).Header(Header, "slow").Do(nil).Status(http.StatusConflict)
+	close(release)
+	<-done
+	servertest.Post(a, "http://localhost:8080/slow", nil).Header(Header, "slow").Do(nil).
+		Status(http.StatusNoContent).
+		Header(ReplayedHeader, "true")
+}
+
+func TestIdempotency_Required(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+	i := New(s.Cache(), time.Minute, time.Second).Required().Scope(func(ctx *web.Context) (string, error) {
+		return ctx.Request().Header.Get("X-User"), nil
+	})
+
+	r := s.Routers().New("def", nil)
+	r.Use(i)
+	r.Post("/payments", func(ctx *web.Context) web.Responser {
+		return web.OK(ctx.Request().Header.Get("X-User"))
+	})
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	const url = "http://localhost:8080/payments"
+
+	servertest.Post(a, url, nil).Do(nil).Status(http.StatusBadRequest)
+
+	servertest.Post(a, url, nil).Header(header.Accept, header.JSON).Header(Header, "k1").Header("X-User", "u1").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"u1"`)
+
+	// 不同用户的键名互不影响
+	servertest.Post(a, url, nil).Header(header.Accept, header.JSON).Header(Header, "k1").Header("X-User", "u2").
+		Do(nil).
+		Status(http.StatusOK).
+		StringBody(`"u2"`)
+}
+
+func TestIdempotency_lock(t *testing.T) {
+	a := assert.New(t, false)
+	s := newServer(a)
+	i := New(s.Cache(), time.Minute, time.Minute)
+
+	unlock1, err := i.lock("k")
+	a.NotError(err).NotNil(unlock1)
+	unlock, err := i.lock("k")
+	a.NotError(err).Nil(unlock)
+
+	// 锁过期之后被其它请求获得
+	a.NotError(s.Cache().Delete("k_lock"))
+	unlock2, err := i.lock("k")
+	a.NotError(err).NotNil(unlock2)
+
+	// 不会释放其它请求的锁
+	unlock1()
+	unlock, err = i.lock("k")
+	a.NotError(err).Nil(unlock)
+
+	unlock2()
+	unlock, err = i.lock("k")
+	a.NotError(err).NotNil(unlock)
+	unlock()
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"slices"
+	"strings"
+)
+
+// 常用的 CSP 指令
+const (
+	DefaultSrc     = "default-src"
+	ScriptSrc      = "script-src"
+	StyleSrc       = "style-src"
+	ImgSrc         = "img-src"
+	FontSrc        = "font-src"
+	ConnectSrc     = "connect-src"
+	MediaSrc       = "media-src"
+	ObjectSrc      = "object-src"
+	FrameSrc       = "frame-src"
+	WorkerSrc      = "worker-src"
+	ManifestSrc    = "manifest-src"
+	BaseURI        = "base-uri"
+	FormAction     = "form-action"
+	FrameAncestors = "frame-ancestors"
+)
+
+// 常用的 CSP 源
+const (
+	Self          = "'self'"
+	None          = "'none'"
+	UnsafeInline  = "'unsafe-inline'"
+	UnsafeEval    = "'unsafe-eval'"
+	StrictDynamic = "'strict-dynamic'"
+	Data          = "data:"
+	Blob          = "blob:"
+)
+
+// nonce 的占位符，由中间件替换为当前请求的 nonce。
+const placeholder = "__csp_nonce__"
+
+const reportEndpoint = "csp-endpoint"
+
+// CSP 内容安全策略的构建工具
+type CSP struct {
+	directives []*directive
+	report     string
+	reportOnly bool
+}
+
+type directive struct {
+	name    string
+	sources []string
+	nonce   bool
+}
+
+// NewCSP 声明 [CSP] 对象
+func NewCSP() *CSP { return &CSP{directives: make([]*directive, 0, 10)} }
+
+func (csp *CSP) get(name string) *directive {
+	if index := slices.IndexFunc(csp.directives, func(d *directive) bool { return d.name == name }); index >= 0 {
+		return csp.directives[index]
+	}
+
+	d := &directive{name: name}
+	csp.directives = append(csp.directives, d)
+	return d
+}
+
+// Add 向指令 name 添加源
+//
+// sources 可以为空，比如 upgrade-insecure-requests 等不需要源的指令。
+func (csp *CSP) Add(name string, sources ...string) *CSP {
+	if name == "" {
+		panic("参数 name 不能为空")
+	}
+
+	d := csp.get(name)
+	for _, s := range sources {
+		if !slices.Contains(d.sources, s) {
+			d.sources = append(d.sources, s)
+		}
+	}
+	return csp
+}
+
+// Nonce 为指令 names 添加每个请求都不相同的 nonce 源
+//
+// 页面中的内联脚本或样式需要带上与之相同的 nonce 属性才能执行，
+// 可通过 [Nonce] 获取。
+func (csp *CSP) Nonce(names ...string) *CSP {
+	if len(names) == 0 {
+		panic("参数 names 不能为空")
+	}
+
+	for _, name := range names {
+		csp.get(name).nonce = true
+	}
+	return csp
+}
+
+// Report 指定违规报告的提交地址
+//
+// 同时会输出 report-uri 和 report-to 两个指令，url 可以由 [ReportHandler] 处理。
+func (csp *CSP) Report(url string) *CSP {
+	csp.report = url
+	return csp
+}
+
+// ReportOnly 仅报告违规行为而不阻止
+//
+// 将采用 Content-Security-Policy-Report-Only 报头代替 Content-Security-Policy。
+func (csp *CSP) ReportOnly() *CSP {
+	csp.reportOnly = true
+	return csp
+}
+
+// 生成策略内容，nonce 以 placeholder 代替。
+func (csp *CSP) build() (policy string, nonce bool) {
+	b := &strings.Builder{}
+	for _, d := range csp.directives {
+		if b.Len() > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(d.name)
+
+		for _, s := range d.sources {
+			b.WriteByte(' ')
+			b.WriteString(s)
+		}
+
+		if d.nonce {
+			b.WriteString(" 'nonce-" + placeholder + "'")
+			nonce = true
+		}
+	}
+
+	if csp.report != "" {
+		if b.Len() > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString("report-uri " + csp.report + "; report-to " + reportEndpoint)
+	}
+
+	return b.String(), nonce
+}
+
+// String 返回策略内容
+//
+// 其中的 nonce 以占位符表示，由中间件在每个请求中替换为实际的值。
+func (csp *CSP) String() string {
+	p, _ := csp.build()
+	return p
+}
+
+func newNonce() string {
+	bs := make([]byte, 16)
+	rand.Read(bs)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
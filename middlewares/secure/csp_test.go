@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package secure
+
+import (
+	"testing"
+
+	"github.com/issue9/assert/v4"
+)
+
+func TestCSP(t *testing.T) {
+	a := assert.New(t, false)
+
+	csp := NewCSP()
+	a.Empty(csp.String())
+
+	csp.Add(DefaultSrc, Self).Add(ScriptSrc, Self, "https://cdn.example.com").Add(ScriptSrc, Self)
+	a.Equal(csp.String(), "default-src 'self'; script-src 'self' https://cdn.example.com")
+
+	csp.Nonce(ScriptSrc, StyleSrc).Add("upgrade-insecure-requests")
+	p, nonce := csp.build()
+	a.True(nonce).
+		Equal(p, "default-src 'self'; script-src 'self' https://cdn.example.com 'nonce-"+placeholder+"'; style-src 'nonce-"+placeholder+"'; upgrade-insecure-requests")
+
+	csp = NewCSP().Add(DefaultSrc, None).Report("/csp")
+	a.Equal(csp.String(), "default-src 'none'; report-uri /csp; report-to "+reportEndpoint)
+
+	a.PanicString(func() { NewCSP().Add("") }, "参数 name 不能为空")
+	a.PanicString(func() { NewCSP().Nonce() }, "参数 names 不能为空")
+
+	n1, n2 := newNonce(), newNonce()
+	a.NotEqual(n1, n2).Length(n1, 22)
+}
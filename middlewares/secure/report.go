@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package secure
+
+import (
+	ej "encoding/json"
+	"io"
+	"mime"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+)
+
+const maxReportSize = 64 * 1024
+
+// 违规报告的媒体类型
+const (
+	ReportMimetype  = "application/csp-report"   // report-uri 提交的内容类型
+	ReportsMimetype = "application/reports+json" // report-to 提交的内容类型
+)
+
+// Report 违规报告的内容
+type Report struct {
+	DocumentURL        string `json:"documentURL"`
+	Referrer           string `json:"referrer,omitempty"`
+	BlockedURL         string `json:"blockedURL,omitempty"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	OriginalPolicy     string `json:"originalPolicy"`
+	Disposition        string `json:"disposition,omitempty"` // enforce 或是 report
+	SourceFile         string `json:"sourceFile,omitempty"`
+	LineNumber         int    `json:"lineNumber,omitempty"`
+	ColumnNumber       int    `json:"columnNumber,omitempty"`
+	StatusCode         int    `json:"statusCode,omitempty"`
+	Sample             string `json:"sample,omitempty"`
+}
+
+// report-uri 的提交格式
+type legacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		StatusCode         int    `json:"status-code"`
+		ScriptSample       string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// report-to 的提交格式
+type reportItem struct {
+	Type string  `json:"type"`
+	Body *Report `json:"body"`
+}
+
+func (l *legacyReport) report() *Report {
+	r := &Report{
+		DocumentURL:        l.Report.DocumentURI,
+		Referrer:           l.Report.Referrer,
+		BlockedURL:         l.Report.BlockedURI,
+		EffectiveDirective: l.Report.EffectiveDirective,
+		OriginalPolicy:     l.Report.OriginalPolicy,
+		Disposition:        l.Report.Disposition,
+		SourceFile:         l.Report.SourceFile,
+		LineNumber:         l.Report.LineNumber,
+		ColumnNumber:       l.Report.ColumnNumber,
+		StatusCode:         l.Report.StatusCode,
+		Sample:             l.Report.ScriptSample,
+	}
+	if r.EffectiveDirective == "" {
+		r.EffectiveDirective = l.Report.ViolatedDirective
+	}
+	return r
+}
+
+// AddMimetypes 向 c 添加违规报告的媒体类型
+//
+// 服务端会拒绝未在 [web.Codec] 中注册的请求类型，
+// 只有添加了这些媒体类型，[ReportHandler] 才能正常接收报告。
+func AddMimetypes(c *web.Codec) *web.Codec {
+	return c.AddMimetype(ReportMimetype, json.Marshal, json.Unmarshal, "", false, true).
+		AddMimetype(ReportsMimetype, json.Marshal, json.Unmarshal, "", false, true)
+}
+
+// ReportHandler 收集违规报告的处理函数
+//
+// 可同时处理 report-uri 和 report-to 两种方式提交的报告，
+// 需要将该处理函数的地址传递给 [CSP.Report]，且需要 [AddMimetypes] 添加对应的媒体类型。
+//
+// f 用于处理报告，如果为空，则报告将被写入日志。
+func ReportHandler(f func(*web.Context, *Report)) web.HandlerFunc {
+	if f == nil {
+		f = func(ctx *web.Context, r *Report) {
+			ctx.Logs().WARN().LocaleString(web.Phrase("csp violation: %s blocked %s on %s", r.EffectiveDirective, r.BlockedURL, r.DocumentURL))
+		}
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		reports, err := parseReports(ctx.Request().Header.Get(header.ContentType), io.LimitReader(ctx.RequestBody(), maxReportSize))
+		if err != nil {
+			return ctx.Error(err, web.ProblemBadRequest)
+		}
+		if reports == nil {
+			return ctx.Problem(web.ProblemUnsupportedMediaType)
+		}
+
+		for _, r := range reports {
+			f(ctx, r)
+		}
+		return web.NoContent()
+	}
+}
+
+// 解析报告内容，如果 ct 不是可处理的类型，返回 nil, nil。
+func parseReports(ct string, r io.Reader) ([]*Report, error) {
+	t, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case ReportMimetype:
+		l := &legacyReport{}
+		if err := ej.NewDecoder(r).Decode(l); err != nil {
+			return nil, err
+		}
+		return []*Report{l.report()}, nil
+	case ReportsMimetype:
+		items := make([]*reportItem, 0, 5)
+		if err := ej.NewDecoder(r).Decode(&items); err != nil {
+			return nil, err
+		}
+
+		reports := make([]*Report, 0, len(items))
+		for _, item := range items {
+			if item.Type == "csp-violation" && item.Body != nil {
+				reports = append(reports, item.Body)
+			}
+		}
+		return reports, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package secure
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/logs/v7"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/mimetype/json"
+	"github.com/issue9/web/server"
+	"github.com/issue9/web/server/servertest"
+	"golang.org/x/text/language"
+)
+
+const (
+	legacyBody = `{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"inline","violated-directive":"script-src","original-policy":"script-src 'self'"}}`
+	reportBody = `[{"type":"csp-violation","body":{"documentURL":"https://example.com/","blockedURL":"eval","effectiveDirective":"script-src","originalPolicy":"script-src 'self'"}},{"type":"deprecation","body":{}}]`
+)
+
+func TestParseReports(t *testing.T) {
+	a := assert.New(t, false)
+
+	reports, err := parseReports(ReportMimetype, strings.NewReader(legacyBody))
+	a.NotError(err).Length(reports, 1).
+		Equal(reports[0], &Report{DocumentURL: "https://example.com/", BlockedURL: "inline", EffectiveDirective: "script-src", OriginalPolicy: "script-src 'self'"})
+
+	reports, err = parseReports(ReportsMimetype+"; charset=utf-8", strings.NewReader(reportBody))
+	a.NotError(err).Length(reports, 1).
+		Equal(reports[0], &Report{DocumentURL: "https://example.com/", BlockedURL: "eval", EffectiveDirective: "script-src", OriginalPolicy: "script-src 'self'"})
+
+	reports, err = parseReports(header.JSON, strings.NewReader(reportBody))
+	a.NotError(err).Nil(reports)
+
+	reports, err = parseReports(ReportMimetype, strings.NewReader("{"))
+	a.Error(err).Nil(reports)
+}
+
+func TestReportHandler(t *testing.T) {
+	a := assert.New(t, false)
+	s, err := server.NewHTTP("test", "1.0.0", &server.Options{
+		Language:   language.SimplifiedChinese,
+		HTTPServer: &http.Server{Addr: ":8080"},
+		Codec:      AddMimetypes(web.NewCodec().AddMimetype(json.Mimetype, json.Marshal, json.Unmarshal, json.ProblemMimetype, true, true)),
+		Logs:       logs.New(logs.NewTermHandler(os.Stderr, nil)),
+	})
+	a.NotError(err).NotNil(s)
+
+	reports := make([]*Report, 0, 2)
+	r := s.Routers().New("def", nil)
+	r.Post("/csp", ReportHandler(func(_ *web.Context, r *Report) { reports = append(reports, r) }))
+	r.Post("/log", ReportHandler(nil))
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	servertest.Post(a, "http://localhost:8080/csp", []byte(legacyBody)).
+		Header(header.ContentType, ReportMimetype).
+		Do(nil).
+		Status(http.StatusNoContent)
+
+	servertest.Post(a, "http://localhost:8080/csp", []byte(reportBody)).
+		Header(header.ContentType, ReportsMimetype).
+		Do(nil).
+		Status(http.StatusNoContent)
+	a.Length(reports, 2).
+		Equal(reports[0].BlockedURL, "inline").
+		Equal(reports[1].BlockedURL, "eval")
+
+	servertest.Post(a, "http://localhost:8080/csp", []byte("{")).
+		Header(header.ContentType, ReportMimetype).
+		Do(nil).
+		Status(http.StatusBadRequest)
+
+	servertest.Post(a, "http://localhost:8080/log", []byte(legacyBody)).
+		Header(header.ContentType, ReportMimetype).
+		Do(nil).
+		Status(http.StatusNoContent)
+}
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+// Package secure 输出与安全相关的报头
+//
+// 包含以下报头：
+//   - Strict-Transport-Security
+//   - X-Content-Type-Options
+//   - X-Frame-Options
+//   - Referrer-Policy
+//   - Permissions-Policy
+//   - Content-Security-Policy
+//
+// 如果 [CSP] 中包含了 nonce，那么每个请求都会生成一个新的 nonce，
+// 可通过 [Nonce] 获取并传递给 HTML 模板。
+package secure
+
+import (
+	"html/template"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+)
+
+const nonceContext contextType = 1
+
+type contextType int
+
+// Config 安全报头的配置项
+//
+// 各字段为零值时表示不输出对应的报头。
+type Config struct {
+	HSTS              *HSTS
+	NoSniff           bool                // 输出 X-Content-Type-Options: nosniff
+	FrameOptions      string              // X-Frame-Options 的值，DENY 或是 SAMEORIGIN
+	ReferrerPolicy    string              // Referrer-Policy 的值，比如 strict-origin-when-cross-origin
+	PermissionsPolicy map[string][]string // Permissions-Policy 的值，键名为功能名称，键值为允许的源，为空表示禁用该功能。
+	CSP               *CSP
+}
+
+// HSTS Strict-Transport-Security 报头的配置
+type HSTS struct {
+	MaxAge            time.Duration
+	IncludeSubDomains bool
+	Preload           bool
+}
+
+// Secure 输出安全报头的中间件
+type Secure struct {
+	p      *policy
+	routes map[string]*policy
+}
+
+type policy struct {
+	headers   [][2]string
+	cspHeader string
+	csp       string
+	nonce     bool
+}
+
+// DefaultConfig 返回一个较为严格的默认配置
+//
+// 其中的 CSP 只允许加载同源的资源，内联脚本需要带上 nonce。
+func DefaultConfig() *Config {
+	return &Config{
+		HSTS:           &HSTS{MaxAge: 365 * 24 * time.Hour, IncludeSubDomains: true},
+		NoSniff:        true,
+		FrameOptions:   "DENY",
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+		CSP: NewCSP().Add(DefaultSrc, Self).
+			Add(ScriptSrc, Self).Nonce(ScriptSrc).
+			Add(ObjectSrc, None).
+			Add(BaseURI, Self).
+			Add(FrameAncestors, None),
+	}
+}
+
+// New 声明 [Secure] 中间件
+//
+// c 为空时采用 [DefaultConfig] 的返回值。
+func New(c *Config) *Secure {
+	if c == nil {
+		c = DefaultConfig()
+	}
+
+	return &Secure{
+		p:      c.build(),
+		routes: make(map[string]*policy, 10),
+	}
+}
+
+func (c *Config) build() *policy {
+	p := &policy{headers: make([][2]string, 0, 6)}
+
+	if c.HSTS != nil && c.HSTS.MaxAge > 0 {
+		v := "max-age=" + strconv.FormatInt(int64(c.HSTS.MaxAge.Seconds()), 10)
+		if c.HSTS.IncludeSubDomains {
+			v += "; includeSubDomains"
+		}
+		if c.HSTS.Preload {
+			v += "; preload"
+		}
+		p.headers = append(p.headers, [2]string{header.StrictTransportSecurity, v})
+	}
+
+	if c.NoSniff {
+		p.headers = append(p.headers, [2]string{header.XContentTypeOptions, "nosniff"})
+	}
+
+	if c.FrameOptions != "" {
+		p.headers = append(p.headers, [2]string{header.XFrameOptions, c.FrameOptions})
+	}
+
+	if c.ReferrerPolicy != "" {
+		p.headers = append(p.headers, [2]string{header.ReferrerPolicy, c.ReferrerPolicy})
+	}
+
+	if len(c.PermissionsPolicy) > 0 {
+		p.headers = append(p.headers, [2]string{header.PermissionsPolicy, buildPermissionsPolicy(c.PermissionsPolicy)})
+	}
+
+	if c.CSP != nil {
+		if p.csp, p.nonce = c.CSP.build(); p.csp != "" {
+			p.cspHeader = header.ContentSecurityPolicy
+			if c.CSP.reportOnly {
+				p.cspHeader = header.ContentSecurityPolicyReportOnly
+			}
+		}
+
+		if c.CSP.report != "" {
+			p.headers = append(p.headers, [2]string{header.ReportingEndpoints, reportEndpoint + `="` + c.CSP.report + `"`})
+		}
+	}
+
+	return p
+}
+
+func buildPermissionsPolicy(pp map[string][]string) string {
+	b := &strings.Builder{}
+	for _, k := range slices.Sorted(maps.Keys(pp)) {
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k + "=(")
+		for i, v := range pp[k] {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			switch v {
+			case "self", "*", "src":
+				b.WriteString(v)
+			default:
+				b.WriteString(strconv.Quote(v))
+			}
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func buildID(method, path, router string) string { return router + method + path }
+
+// Route 返回一个为单个路由指定配置的中间件
+//
+// c 会完全代替 [New] 中指定的配置，为空表示该路由不输出任何安全报头。
+func (s *Secure) Route(c *Config) web.Middleware {
+	var p *policy
+	if c != nil {
+		p = c.build()
+	}
+
+	return web.MiddlewareFunc(func(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+		s.routes[buildID(method, path, router)] = p
+		return next
+	})
+}
+
+func (s *Secure) Middleware(next web.HandlerFunc, method, path, router string) web.HandlerFunc {
+	p := s.p
+	if rp, found := s.routes[buildID(method, path, router)]; found {
+		p = rp
+	}
+	if p == nil {
+		return next
+	}
+
+	return func(ctx *web.Context) web.Responser {
+		h := ctx.Header()
+		for _, v := range p.headers {
+			h.Set(v[0], v[1])
+		}
+
+		if p.cspHeader != "" {
+			csp := p.csp
+			if p.nonce {
+				nonce := newNonce()
+				ctx.SetVar(nonceContext, nonce)
+				csp = strings.ReplaceAll(csp, placeholder, nonce)
+			}
+			h.Set(p.cspHeader, csp)
+		}
+
+		return next(ctx)
+	}
+}
+
+// Nonce 获取当前请求的 nonce
+//
+// 如果当前请求的 CSP 未包含 nonce，则返回空值。
+func Nonce(ctx *web.Context) string {
+	if v, found := ctx.GetVar(nonceContext); found {
+		return v.(string)
+	}
+	return ""
+}
+
+// NonceAttr 获取当前请求的 nonce 属性
+//
+// 可传递给 HTML 模板，直接在 script 和 style 标签中输出。
+func NonceAttr(ctx *web.Context) template.HTMLAttr {
+	if nonce := Nonce(ctx); nonce != "" {
+		return template.HTMLAttr(`nonce="` + nonce + `"`)
+	}
+	return ""
+}
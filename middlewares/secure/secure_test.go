@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2026 caixw
+//
+// SPDX-License-Identifier: MIT
+
+package secure
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert/v4"
+	"github.com/issue9/mux/v9/header"
+	"github.com/issue9/web"
+	"github.com/issue9/web/server/servertest"
+
+	"github.com/issue9/webuse/v7/internal/testserver"
+)
+
+var _ web.Middleware = &Secure{}
+
+func TestConfig_build(t *testing.T) {
+	a := assert.New(t, false)
+
+	p := (&Config{}).build()
+	a.Empty(p.headers).Empty(p.cspHeader)
+
+	p = (&Config{
+		HSTS:              &HSTS{MaxAge: time.Hour, IncludeSubDomains: true, Preload: true},
+		NoSniff:           true,
+		FrameOptions:      "SAMEORIGIN",
+		ReferrerPolicy:    "no-referrer",
+		PermissionsPolicy: map[string][]string{"geolocation": {"self", "https://example.com"}, "camera": nil},
+		CSP:               NewCSP().Add(DefaultSrc, Self).Report("/csp").ReportOnly(),
+	}).build()
+	a.Equal(p.headers, [][2]string{
+		{header.StrictTransportSecurity, "max-age=3600; includeSubDomains; preload"},
+		{header.XContentTypeOptions, "nosniff"},
+		{header.XFrameOptions, "SAMEORIGIN"},
+		{header.ReferrerPolicy, "no-referrer"},
+		{header.PermissionsPolicy, `camera=(), geolocation=(self "https://example.com")`},
+		{header.ReportingEndpoints, `csp-endpoint="/csp"`},
+	}).
+		Equal(p.cspHeader, header.ContentSecurityPolicyReportOnly).
+		False(p.nonce)
+}
+
+func TestSecure_Middleware(t *testing.T) {
+	a := assert.New(t, false)
+	s := testserver.New(a)
+	sec := New(nil)
+
+	r := s.Routers().New("def", nil)
+	r.Use(sec)
+	r.Get("/nonce", func(ctx *web.Context) web.Responser {
+		return web.OK(Nonce(ctx))
+	})
+	r.Get("/none", func(ctx *web.Context) web.Responser {
+		return web.OK(Nonce(ctx))
+	}, sec.Route(nil))
+	r.Get("/route", func(ctx *web.Context) web.Responser {
+		return web.OK(string(NonceAttr(ctx)))
+	}, sec.Route(&Config{NoSniff: true}))
+
+	defer servertest.Run(a, s)()
+	defer s.Close(0)
+
+	var nonce string
+	resp := servertest.Get(a, "http://localhost:8080/nonce").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		Header(header.XContentTypeOptions, "nosniff").
+		Header(header.XFrameOptions, "DENY").
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			nonce = string(body[1 : len(body)-1])
+		}).
+		Resp()
+	a.NotEmpty(nonce).
+		True(strings.Contains(resp.Header.Get(header.ContentSecurityPolicy), "'nonce-"+nonce+"'")).
+		NotEmpty(resp.Header.Get(header.StrictTransportSecurity))
+
+	// 每次请求的 nonce 都不相同
+	servertest.Get(a, "http://localhost:8080/nonce").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		BodyFunc(func(a *assert.Assertion, body []byte) {
+			a.NotEqual(string(body[1:len(body)-1]), nonce)
+		})
+
+	resp = servertest.Get(a, "http://localhost:8080/none").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		StringBody(`""`).
+		Resp()
+	a.Empty(resp.Header.Get(header.XContentTypeOptions)).
+		Empty(resp.Header.Get(header.ContentSecurityPolicy))
+
+	resp = servertest.Get(a, "http://localhost:8080/route").Header(header.Accept, header.JSON).Do(nil).
+		Status(http.StatusOK).
+		StringBody(`""`).
+		Header(header.XContentTypeOptions, "nosniff").
+		Resp()
+	a.Empty(resp.Header.Get(header.XFrameOptions)).
+		Empty(resp.Header.Get(header.ContentSecurityPolicy))
+}
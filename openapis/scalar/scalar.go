@@ -25,9 +25,20 @@ const CDNAssets = "https://cdn.jsdelivr.net/npm/@scalar/api-reference"
 
 // Install 安装模板
 //
-// NOTE: 此操作会同时安装 json 和 yaml 两个模板函数
+// NOTE: 此操作会同时安装 json、yaml 和 nonce 三个模板函数，
+// 其中 nonce 始终返回空值，如果启用了带 nonce 的 CSP，需要改用 [Marshal]。
 func Install(s web.Server) { html.Install(s, openapifuncs.Funcs, nil, "*.html", tpl) }
 
+// Marshal 包装 HTML 的编码函数 m，由其直接输出 scalar 的文档页面
+//
+// 页面中内联脚本的 nonce 属性为当前请求的 [secure.Nonce]，
+// 适用于启用了带 nonce 的 CSP 的场景，其它对象依然交由 m 处理。比如：
+//
+//	web.NewCodec().AddMimetype(html.Mimetype, scalar.Marshal(html.Marshal), html.Unmarshal, "", false, true)
+//
+// [secure.Nonce]: https://pkg.go.dev/github.com/issue9/webuse/v7/middlewares/secure#Nonce
+func Marshal(m web.MarshalFunc) web.MarshalFunc { return openapifuncs.Marshal(m, "scalar", tpl) }
+
 // WithHTML 指定 [scalar] 的 HTML 模板
 //
 // 可用来代替 [openapi.WithHTML]